@@ -21,10 +21,12 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
@@ -32,14 +34,26 @@ import (
 
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/constants"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/driver"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/dtk"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/entrypoint"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/version"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers"
 )
 
 const stderrOutput = "stderr"
 
+// dumpConfigArg prints the effective configuration and exits instead of running a container mode
+const dumpConfigArg = "dumpconfig"
+
+// listInventoryArg prints the driver packages cached in the inventory as a table, or as JSON
+// with a "json" second argument, and exits
+const listInventoryArg = "listinventory"
+
+// diagnosticsArg collects a support bundle of the host state into DIAGNOSTICS_DIR and exits
+const diagnosticsArg = "diagnostics"
+
 type ctxData struct {
 	//nolint:containedctx
 	Ctx    context.Context
@@ -74,14 +88,46 @@ func main() {
 		os.Exit(1)
 	}
 
+	flag.Parse()
+	if flag.Arg(0) == dumpConfigArg {
+		data, err := cfg.DumpJSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to dump configuration: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if flag.Arg(0) == listInventoryArg {
+		// Logs would be mixed with the listing on stdout
+		entries, err := entrypoint.ListInventory(logr.Discard(), cfg)
+		if err == nil {
+			err = printInventory(os.Stdout, entries, flag.Arg(1) == "json")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to list driver inventory: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log := getLogger(cfg)
 	log.Info("entrypoint", "version", version.GetVersionString())
 
 	log.Info(fmt.Sprintf("Container full version: %s-%s", cfg.NvidiaNicDriverVer, cfg.NvidiaNicContainerVer))
 
+	if flag.Arg(0) == diagnosticsArg {
+		if err := entrypoint.CollectDiagnostics(log, cfg); err != nil {
+			log.Error(err, "failed to collect diagnostics")
+			os.Exit(1)
+		}
+		return
+	}
+
 	if log.V(1).Enabled() {
 		//nolint:errchkjson
-		data, _ := json.MarshalIndent(cfg, "", "  ")
+		data, _ := cfg.DumpJSON()
 		log.V(1).Info("driver container config: \n" + string(data))
 	}
 	containerMode, err := getContainerMode()
@@ -97,7 +143,7 @@ func main() {
 		ctx = logr.NewContext(ctx, log)
 		setupSignalHandler(getSignalChannel(), []ctxData{{Ctx: ctx, Cancel: cancel}})
 
-		if err := dtk.RunBuild(ctx, log, cfg, cmd.New()); err != nil {
+		if err := dtk.RunBuild(ctx, log, cfg, cmd.NewFromConfig(cfg, wrappers.NewOS())); err != nil {
 			log.Error(err, "DTK Build failed")
 			cancel()
 			os.Exit(1)
@@ -112,6 +158,29 @@ func main() {
 	}
 }
 
+// printInventory writes the inventory entries to w as a table or as JSON
+func printInventory(w io.Writer, entries []driver.InventoryEntry, asJSON bool) error {
+	if asJSON {
+		if entries == nil {
+			entries = []driver.InventoryEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KERNEL\tDRIVER\tARCH\tCHECKSUM\tPACKAGES\tINCOMPLETE\tBUILD INFO")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%t\t%s\n", e.KernelVersion, e.DriverVersion, e.Arch,
+			e.Checksum, e.PackageCount, e.Incomplete, e.BuildInfo)
+	}
+	return tw.Flush()
+}
+
 func getContainerMode() (string, error) {
 	flag.Parse()
 	containerMode := flag.Arg(0)
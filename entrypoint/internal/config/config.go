@@ -18,9 +18,15 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
+	"gopkg.in/yaml.v3"
 
 	"github.com/Mellanox/doca-driver-build/entrypoint/pkg/mofedmodules"
 )
@@ -32,7 +38,52 @@ type Config struct {
 	CreateIfnamesUdev             bool   `env:"CREATE_IFNAMES_UDEV"`
 	EnableNfsRdma                 bool   `env:"ENABLE_NFSRDMA"`
 	RestoreDriverOnPodTermination bool   `env:"RESTORE_DRIVER_ON_POD_TERMINATION" envDefault:"false"`
-	UbuntuProToken                string `env:"UBUNTU_PRO_TOKEN"`
+	SkipNetconfigOnDPU            bool   `env:"SKIP_NETCONFIG_ON_DPU"`
+	UbuntuProToken                string `env:"UBUNTU_PRO_TOKEN"                  secret:"true"`
+	// EnableNvmeRdma builds and checks the NVMe over RDMA modules, it defaults to EnableNfsRdma
+	EnableNvmeRdma bool `env:"ENABLE_NVME_RDMA"`
+	// ReconcileInterval is how often the loaded driver is re-checked after start and reloaded
+	// when it drifted from the candidate driver; zero checks only once at start
+	ReconcileInterval time.Duration `env:"RECONCILE_INTERVAL"`
+	// LoadRetries is how many times a failed driver load is retried before giving up,
+	// LoadRetryBackoff is the delay before the first retry, it doubles on each retry
+	LoadRetries      int           `env:"LOAD_RETRIES"`
+	LoadRetryBackoff time.Duration `env:"LOAD_RETRY_BACKOFF" envDefault:"10s"`
+	// SelectiveReload (experimental) reloads only the drifted driver modules with modprobe instead of
+	// restarting openibd when they aren't used by other modules; any failure falls back to the restart
+	SelectiveReload bool `env:"SELECTIVE_RELOAD"`
+	// VerifyReload re-checks the srcversion of the loaded modules after the driver restart and fails the
+	// load when they still don't match the candidate driver, i.e. the restart silently kept the old modules
+	VerifyReload bool `env:"VERIFY_RELOAD" envDefault:"true"`
+	// KubeEventsEnabled posts Kubernetes Events on the node for driver build, load and unload, using the
+	// in-cluster config of the pod; NodeName is set from the downward API (spec.nodeName)
+	KubeEventsEnabled bool   `env:"KUBE_EVENTS_ENABLED"`
+	NodeName          string `env:"NODE_NAME"`
+	// UbuntuRenameIfup renames /sbin/ifup on Ubuntu without /etc/network/interfaces, so that
+	// mlnx_interface_mgr.sh doesn't run it; the rename is reverted in Clear
+	UbuntuRenameIfup bool `env:"UBUNTU_RENAME_IFUP" envDefault:"true"`
+	// Fabric is the link type of the Mellanox devices of the node (auto, eth, ib or mixed), used to
+	// select the modules loaded around the driver restart; auto detects it from sysfs
+	Fabric string `env:"FABRIC" envDefault:"auto"`
+	// AllowArchMismatch permits running the container on a host with a different CPU architecture
+	// (e.g. under emulation) instead of failing in PreStart
+	AllowArchMismatch bool `env:"ALLOW_ARCH_MISMATCH"`
+	// CustomCACertsDir holds extra CA certificates (e.g. from a mounted secret) that are
+	// copied into the OS trust store every time CA certificates are refreshed.
+	CustomCACertsDir string `env:"CUSTOM_CA_CERTS_DIR"`
+
+	// NetconfigInclude and NetconfigExclude select the netdevs managed by netconfig by name or
+	// PCI address, glob patterns are supported. Empty include means all, exclude always wins.
+	NetconfigInclude []string `env:"NETCONFIG_INCLUDE" envSeparator:" "`
+	NetconfigExclude []string `env:"NETCONFIG_EXCLUDE" envSeparator:" "`
+	// NetconfigDiscoveryConcurrency is the number of netdevs inspected in parallel during Save
+	NetconfigDiscoveryConcurrency int `env:"NETCONFIG_DISCOVERY_CONCURRENCY" envDefault:"8"`
+	// RequireMellanoxDevices fails the network config save when mlx5_core is loaded but no Mellanox
+	// device is found, by default this is only logged
+	RequireMellanoxDevices bool `env:"REQUIRE_MELLANOX_DEVICES"`
+	// NetconfigExportPath is a file the saved SRIOV configuration is written to as JSON after each Save,
+	// for external tooling. It is never read back.
+	NetconfigExportPath string `env:"NETCONFIG_EXPORT_PATH"`
 
 	// driver manager advanced settings
 	DriverReadyPath        string `env:"DRIVER_READY_PATH"         envDefault:"/run/mellanox/drivers/.driver-ready"`
@@ -40,6 +91,29 @@ type Config struct {
 	LockFilePath           string `env:"LOCK_FILE_PATH"            envDefault:"/run/mellanox/drivers/.lock"`
 	MlxDriversMount        string `env:"MLX_DRIVERS_MOUNT"         envDefault:"/run/mellanox/drivers"`
 	SharedKernelHeadersDir string `env:"SHARED_KERNEL_HEADERS_DIR" envDefault:"/usr/src/"`
+	// SysMountPropagation is the propagation /sys is switched to before the kernel headers are
+	// bind mounted (private, slave or shared), skip leaves /sys untouched
+	SysMountPropagation string `env:"SYS_MOUNT_PROPAGATION" envDefault:"private"`
+	// SysfsRoot and ProcRoot allow reading sysfs/procfs from a non-standard mount point
+	// (e.g. the host sysfs bind-mounted elsewhere in the container, or a fake tree in tests).
+	SysfsRoot string `env:"SYSFS_ROOT" envDefault:"/sys"`
+	ProcRoot  string `env:"PROC_ROOT"  envDefault:"/proc"`
+	// HostRoot is where the host root filesystem is mounted, the driver restart resolves the
+	// host inbox modules under it with modprobe -d (e.g. / when the entrypoint is chrooted into the host)
+	HostRoot string `env:"HOST_ROOT" envDefault:"/host"`
+	// OpenibdScriptPath is the openibd script restarting the driver, it also gets the storage
+	// modules injected into its unload list
+	OpenibdScriptPath string `env:"OPENIBD_SCRIPT_PATH" envDefault:"/etc/init.d/openibd"`
+	// StrictHostModules fails the driver restart when the host modules aren't mounted under HostRoot,
+	// by default a warning is logged and the restart goes on without the host inbox modules
+	StrictHostModules bool `env:"STRICT_HOST_MODULES"`
+	// CheckSecureBootLoad fails Load when the kernel log reports a signature verification failure
+	// of a driver module, e.g. on Secure Boot nodes where the unsigned driver modules are rejected
+	CheckSecureBootLoad bool `env:"CHECK_SECURE_BOOT_LOAD"`
+	// UseNsenterForHostCommands runs the commands targeting the host (the openibd restart and the
+	// modprobe of the host inbox modules) in the host namespaces with HostCommandPrefix
+	UseNsenterForHostCommands bool   `env:"USE_NSENTER_FOR_HOST_COMMANDS"`
+	HostCommandPrefix         string `env:"HOST_COMMAND_PREFIX" envDefault:"nsenter -t 1 -m -n --"`
 
 	NvidiaNicDriverVer    string `env:"NVIDIA_NIC_DRIVER_VER,required,notEmpty"`
 	NvidiaNicDriverPath   string `env:"NVIDIA_NIC_DRIVER_PATH"`
@@ -52,17 +126,120 @@ type Config struct {
 	DtkOcpDoneCompileFlag         string `env:"DTK_OCP_DONE_COMPILE_FLAG"`
 	AppendDriverBuildFlags        string `env:"APPEND_DRIVER_BUILD_FLAGS"`
 	NvidiaNicDriversInventoryPath string `env:"NVIDIA_NIC_DRIVERS_INVENTORY_PATH"`
+	// LocalKernelPackagesDir holds pre-staged kernel headers/devel packages (.deb or .rpm) that are
+	// installed instead of fetching them from repos, for air-gapped clusters
+	LocalKernelPackagesDir string `env:"LOCAL_KERNEL_PACKAGES_DIR"`
+	// KeepEnabledRepos leaves the RHEL/OpenShift repos enabled for the build enabled on Clear
+	KeepEnabledRepos bool `env:"KEEP_ENABLED_REPOS"`
+	// ExtraBuildPackages are installed with the OS package manager after the standard prerequisites,
+	// for custom kernels that need additional build dependencies (e.g. dwarves)
+	ExtraBuildPackages []string `env:"EXTRA_BUILD_PACKAGES" envSeparator:" "`
+	// BuildNiceness and BuildIoniceClass lower the CPU/IO priority of the install.pl build with
+	// nice -n / ionice -c so it doesn't starve host workloads, 0 leaves the priority unchanged
+	BuildNiceness    int `env:"BUILD_NICENESS"`
+	BuildIoniceClass int `env:"BUILD_IONICE_CLASS"`
+	// BuildJobs is the number of parallel make jobs of the install.pl build, passed as MAKEFLAGS=-j<N>;
+	// 0 uses the number of CPUs
+	BuildJobs int `env:"BUILD_JOBS"`
+	// BuildLogDir is where the logs written by install.pl are copied after a build, for post-mortem
+	BuildLogDir string `env:"BUILD_LOG_DIR"`
+	// ReadOnlyInventoryPaths are shared driver caches (e.g. mounted from a registry) that are
+	// checked for matching packages before building into NvidiaNicDriversInventoryPath.
+	ReadOnlyInventoryPaths []string `env:"READ_ONLY_INVENTORY_PATHS" envSeparator:":"`
+	// ChecksumIncludeGlobs and ChecksumExcludeGlobs select by file name the inventory files covered by
+	// the inventory checksum, so that logs or metadata written to the inventory don't invalidate it.
+	// Empty include means all files, exclude always wins.
+	ChecksumIncludeGlobs []string `env:"CHECKSUM_INCLUDE_GLOBS" envDefault:"*.deb *.rpm" envSeparator:" "`
+	ChecksumExcludeGlobs []string `env:"CHECKSUM_EXCLUDE_GLOBS" envSeparator:" "`
+	// InventoryArchSubdir stores the driver packages under an <arch> subdir of the inventory
+	// version dir, so that one inventory volume can be shared by nodes of different architectures
+	InventoryArchSubdir bool `env:"INVENTORY_ARCH_SUBDIR"`
+	// InventoryMaxKernels is the number of kernel versions kept in the driver inventory, the least
+	// recently built ones are pruned after a build; zero keeps only the running kernel on cleanup
+	InventoryMaxKernels int `env:"INVENTORY_MAX_KERNELS"`
+	// PinnedDriverVer installs the packages of this version from the driver inventory, e.g. to roll back
+	// to a previous build, instead of building NvidiaNicDriverVer
+	PinnedDriverVer string `env:"PINNED_DRIVER_VER"`
+	// ForceRebuild ignores the driver inventory caches and always builds the driver
+	ForceRebuild bool `env:"FORCE_REBUILD"`
+	// AutoDetectDriverVersion keys the driver inventory by the version read from the built packages
+	// instead of NvidiaNicDriverVer, a mismatch between the two is reported
+	AutoDetectDriverVersion bool `env:"AUTO_DETECT_DRIVER_VERSION"`
+	// CopyHostResolvConf replaces /etc/resolv.conf with the host one during Build, for clusters where
+	// only the host can resolve the package mirrors; the original file is restored on Clear
+	CopyHostResolvConf bool `env:"COPY_HOST_RESOLV_CONF"`
+	// CopyHostZyppRepos copies the zypper repos and credentials of the host before installing the kernel
+	// devel package of a non-default SLES kernel flavor (e.g. azure, 64kb), which is only in the host repos
+	CopyHostZyppRepos bool `env:"COPY_HOST_ZYPP_REPOS"`
+	// OfflineBuild builds the driver without network access: package repos are not set up or
+	// refreshed and the kernel headers and build tools must already be installed in the image
+	OfflineBuild bool `env:"OFFLINE_BUILD"`
+	// PkgManagerLockWait is how long apt-get, dnf and zypper wait for a package manager lock held
+	// by another process (e.g. unattended-upgrades on the host) before failing; zero fails immediately
+	PkgManagerLockWait time.Duration `env:"PKG_MANAGER_LOCK_WAIT"`
+	// KernelHeaderPackageTemplate is a text/template of the kernel headers package name installed for the
+	// build, with .KernelVersion, .Arch and .Flavor, for derivative distros that rename the package
+	KernelHeaderPackageTemplate string `env:"KERNEL_HEADER_PACKAGE_TEMPLATE"`
+	// MinBuildDiskBytes is the free space required on the driver sources and inventory filesystems
+	// before compiling the driver, zero disables the check
+	MinBuildDiskBytes uint64 `env:"MIN_BUILD_DISK_BYTES" envDefault:"5368709120"`
+	// TargetKernelVersion is the kernel Build builds, installs and keys the inventory for instead of the
+	// running one, e.g. to pre-stage the packages for the kernel the node reboots into. Load ignores it.
+	TargetKernelVersion string `env:"TARGET_KERNEL_VERSION"`
+	// ExportPackagesTarball is a path where a gzipped tarball of the built packages and a build-info
+	// file is written after a build from source, e.g. for a CI job to publish the packages
+	ExportPackagesTarball string `env:"EXPORT_PACKAGES_TARBALL"`
+
+	// SkipReloadOnVersionMatch skips the driver reload in precompiled mode when the running driver
+	// version already equals NvidiaNicDriverVer, even if the module srcversions differ
+	SkipReloadOnVersionMatch bool `env:"SKIP_RELOAD_ON_VERSION_MATCH"`
+	// PostLoadHook is a shell command run after the driver is loaded, with LOADED_DRIVER_VERSION
+	// and KERNEL_VERSION set in its environment. Its failure only fails Load with PostLoadHookRequired.
+	PostLoadHook         string `env:"POST_LOAD_HOOK"`
+	PostLoadHookRequired bool   `env:"POST_LOAD_HOOK_REQUIRED"`
+	// DiagnosticsDir is where the diagnostics argument writes the support bundle of the host state
+	DiagnosticsDir string `env:"DIAGNOSTICS_DIR" envDefault:"/tmp/doca-driver-diagnostics"`
+	// Mlx5CoreMinSize is the smallest plausible size in bytes of the loaded mlx5_core, a smaller one
+	// (e.g. a stub or dummy module) is reported after Load; zero disables the check
+	Mlx5CoreMinSize int `env:"MLX5_CORE_MIN_SIZE"`
 
-	OfedBlacklistModulesFile string   `env:"OFED_BLACKLIST_MODULES_FILE" envDefault:"/host/etc/modprobe.d/blacklist-ofed-modules.conf"`
-	OfedBlacklistModules     []string `env:"OFED_BLACKLIST_MODULES"      envDefault:"mlx5_core:mlx5_ib:ib_umad:ib_uverbs:ib_ipoib:rdma_cm:rdma_ucm:ib_core:ib_cm" envSeparator:":"`
-	Mlx5AuxiliaryModules     []string `env:"MLX5_AUXILIARY_MODULES"      envSeparator:" "`
+	// PersistBlacklist keeps the blacklist file on the host after Load so a host reboot
+	// doesn't load the inbox driver before the container runs; it is removed on Unload instead.
+	PersistBlacklist bool `env:"PERSIST_BLACKLIST"`
+	// BlacklistMergeExisting merges the generated entries into an existing (e.g. operator-managed)
+	// blacklist file instead of overwriting it; only the entries added by the driver are removed later.
+	BlacklistMergeExisting bool `env:"BLACKLIST_MERGE_EXISTING"`
+	// StrictBlacklistValidation fails the blacklist generation on an invalid module name instead of skipping it
+	StrictBlacklistValidation bool     `env:"STRICT_BLACKLIST_VALIDATION"`
+	OfedBlacklistModulesFile  string   `env:"OFED_BLACKLIST_MODULES_FILE" envDefault:"/host/etc/modprobe.d/blacklist-ofed-modules.conf"`
+	OfedBlacklistModules      []string `env:"OFED_BLACKLIST_MODULES"      envDefault:"mlx5_core:mlx5_ib:ib_umad:ib_uverbs:ib_ipoib:rdma_cm:rdma_ucm:ib_core:ib_cm" envSeparator:":"`
+	Mlx5AuxiliaryModules      []string `env:"MLX5_AUXILIARY_MODULES"      envSeparator:" "`
+	// LoadVdpa loads mlx5_vdpa after the driver restart when it is one of the Mlx5AuxiliaryModules,
+	// RequireVdpa fails the restart when it can't be found or loaded instead of skipping it
+	LoadVdpa    bool `env:"LOAD_VDPA"    envDefault:"true"`
+	RequireVdpa bool `env:"REQUIRE_VDPA"`
 	// StorageModules defaults to mofedmodules.DefaultStorageModules when unset; see GetConfig.
 	StorageModules []string `env:"STORAGE_MODULES" envSeparator:" "`
 	// ThirdPartyRDMAModules defaults to mofedmodules.DefaultThirdPartyRDMAModules when unset; see GetConfig.
 	ThirdPartyRDMAModules []string `env:"THIRD_PARTY_RDMA_MODULES" envSeparator:" "`
+	// PostRestartModules are loaded in order after the driver restart, e.g. ib_umad rdma_ucm
+	PostRestartModules []string `env:"POST_RESTART_MODULES" envSeparator:" "`
+	// ModuleOptions are the modprobe options of modules, e.g. mlx5_core:prof_sel=2 num_of_groups=4;
+	// they are written to /etc/modprobe.d/mlnx-options.conf by Load and removed by Unload/Clear
+	ModuleOptions map[string]string `env:"MODULE_OPTIONS" envSeparator:";"`
+	// CommandEnv is merged into the environment of every command run by the container,
+	// e.g. LC_ALL:C;MAKEFLAGS:-j8
+	CommandEnv map[string]string `env:"COMMAND_ENV" envSeparator:";"`
 
 	// DKMS settings
 	UseDKMS bool `env:"USE_DKMS" envDefault:"false"`
+	// SkipDepmodStub skips creating the kernel modules directory and the empty modules.order and
+	// modules.builtin files before the driver packages are installed
+	SkipDepmodStub bool `env:"SKIP_DEPMOD_STUB"`
+	// EnableKMP builds KMP (SLES) and kmod (RedHat) packages instead of passing --disable-kmp to install.pl
+	EnableKMP bool `env:"ENABLE_KMP"`
+	// WithMlnxTools passes --with-mlnx-tools to install.pl to also build the mlnx-tools userspace package
+	WithMlnxTools bool `env:"WITH_MLNX_TOOLS" envDefault:"true"`
 	// UnloadThirdPartyRdmaModules enables blacklisting and unloading of all known
 	// third-party RDMA kernel modules (from rdma-core) before OFED driver reload.
 	// When true, modules from ThirdPartyRDMAModules are:
@@ -72,21 +249,96 @@ type Config struct {
 	// Example: UNLOAD_THIRD_PARTY_RDMA_MODULES=true
 	UnloadThirdPartyRdmaModules bool `env:"UNLOAD_THIRD_PARTY_RDMA_MODULES"`
 
+	// AuditLogFile, when set, gets a JSON line appended for every command the container executes.
+	// CommandAllowList, when set, restricts the commands that may be executed to these binaries.
+	AuditLogFile     string   `env:"AUDIT_LOG_FILE"`
+	CommandAllowList []string `env:"COMMAND_ALLOW_LIST" envSeparator:" "`
+	// MaxCommandOutputBytes caps the stdout and the stderr kept for each command, the rest is dropped
+	// with a "[truncated N bytes]" marker so a verbose command can't exhaust the memory; zero disables it
+	MaxCommandOutputBytes int `env:"MAX_COMMAND_OUTPUT_BYTES"`
+
+	// ForceGCCVersion is the gcc major version to set up when it can't be detected from /proc/version
+	// (e.g. clang-built kernels), 0 keeps the default of skipping gcc setup
+	ForceGCCVersion int `env:"FORCE_GCC_VERSION"`
+
 	// debug settings
 	EntrypointDebug     bool   `env:"ENTRYPOINT_DEBUG"`
 	DebugLogFile        string `env:"DEBUG_LOG_FILE"          envDefault:"/tmp/entrypoint_debug_cmds.log"`
 	DebugSleepSecOnExit int    `env:"DEBUG_SLEEP_SEC_ON_EXIT" envDefault:"300"`
 	BindDelaySec        int    `env:"BIND_DELAY_SEC"          envDefault:"4"`
+	// CommandHeartbeatInterval controls how often progress is logged while a long command
+	// (e.g. openibd restart) runs; zero disables it.
+	CommandHeartbeatInterval time.Duration `env:"COMMAND_HEARTBEAT_INTERVAL" envDefault:"10s"`
+
+	// sriov_numvfs write retries (with doubling backoff) and how long to wait for the VF count to settle
+	SriovNumVfsWriteRetries     int `env:"SRIOV_NUMVFS_WRITE_RETRIES"      envDefault:"5"`
+	SriovNumVfsRetryDelayMs     int `env:"SRIOV_NUMVFS_RETRY_DELAY_MS"     envDefault:"500"`
+	SriovNumVfsSettleTimeoutSec int `env:"SRIOV_NUMVFS_SETTLE_TIMEOUT_SEC" envDefault:"10"`
+	// NetlinkWaitTimeout is how long the network config restore waits for a link to be registered
+	// after the driver reload before failing with "Link not found"
+	NetlinkWaitTimeout time.Duration `env:"NETLINK_WAIT_TIMEOUT" envDefault:"5s"`
+	// NetconfigRestoreTimeout bounds the whole network config restore, on timeout the restore fails
+	// naming the device it was on instead of hanging. Zero disables the deadline
+	NetconfigRestoreTimeout time.Duration `env:"NETCONFIG_RESTORE_TIMEOUT"`
+	// NetconfigPollInterval is the interval of the sriov_numvfs, VF netdev and link readiness polling
+	NetconfigPollInterval time.Duration `env:"NETCONFIG_POLL_INTERVAL" envDefault:"200ms"`
+	// StrictNetconfigRestore fails the network config restore when some devices or VFs couldn't be
+	// restored, by default the failures are only logged
+	StrictNetconfigRestore bool `env:"STRICT_NETCONFIG_RESTORE"`
+	// PreferIPCommand sets the VF GUIDs and admin MACs, enslaves the LAG members and renames the representors
+	// with the ip command instead of netlink, for debugging
+	PreferIPCommand bool `env:"PREFER_IP_COMMAND"`
 }
 
 var DefaultMlx5AuxiliaryModules = []string{"mlx5_vdpa", "mlx5_fwctl", "mlx5_dpll"}
 
+// redactedValue replaces the value of the secret fields in Redacted
+const redactedValue = "<redacted>"
+
+// Redacted returns a copy of the config with the non-empty fields tagged secret:"true" masked.
+func (c Config) Redacted() Config {
+	v := reflect.ValueOf(&c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("secret") != "true" {
+			continue
+		}
+		if field := v.Field(i); field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(redactedValue)
+		}
+	}
+	return c
+}
+
+// DumpJSON returns the redacted config as indented JSON.
+func (c Config) DumpJSON() ([]byte, error) {
+	return json.MarshalIndent(c.Redacted(), "", "  ")
+}
+
+// configFileEnv is the environment variable with the path of an optional YAML (or JSON) config file.
+// The file maps the environment variable names of Config to values, e.g. "ENABLE_NFSRDMA: true".
+const configFileEnv = "CONFIG_FILE"
+
 // GetConfig parses environment variables and returns a Config struct.
+// Values from the file in CONFIG_FILE, if set, are used for variables missing from the environment.
 // When module-list environment variables are unset, the corresponding slices
-// are populated from the canonical defaults.
+// are populated from the canonical defaults, and an unset ENABLE_NVME_RDMA follows ENABLE_NFSRDMA.
 func GetConfig() (Config, error) {
+	environment := env.ToMap(os.Environ())
+	if path := environment[configFileEnv]; path != "" {
+		fileValues, err := readConfigFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		for key, value := range fileValues {
+			if _, set := environment[key]; !set {
+				environment[key] = value
+			}
+		}
+	}
+
 	var cfg Config
-	if err := env.Parse(&cfg); err != nil {
+	if err := env.ParseWithOptions(&cfg, env.Options{Environment: environment}); err != nil {
 		return Config{}, err
 	}
 	if len(cfg.StorageModules) == 0 {
@@ -95,8 +347,67 @@ func GetConfig() (Config, error) {
 	if len(cfg.ThirdPartyRDMAModules) == 0 {
 		cfg.ThirdPartyRDMAModules = append(cfg.ThirdPartyRDMAModules, mofedmodules.DefaultThirdPartyRDMAModules...)
 	}
-	if _, configured := os.LookupEnv("MLX5_AUXILIARY_MODULES"); !configured && len(cfg.Mlx5AuxiliaryModules) == 0 {
+	if _, configured := environment["MLX5_AUXILIARY_MODULES"]; !configured && len(cfg.Mlx5AuxiliaryModules) == 0 {
 		cfg.Mlx5AuxiliaryModules = append(cfg.Mlx5AuxiliaryModules, DefaultMlx5AuxiliaryModules...)
 	}
+	if _, configured := environment["ENABLE_NVME_RDMA"]; !configured {
+		cfg.EnableNvmeRdma = cfg.EnableNfsRdma
+	}
 	return cfg, nil
 }
+
+// readConfigFile reads the config file at path and returns its values in the string form
+// expected for the corresponding environment variables. Lists are joined with the envSeparator of the field.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	separators := envSeparators()
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		separator, known := separators[key]
+		if !known {
+			return nil, fmt.Errorf("unknown key %q in config file %s", key, path)
+		}
+		switch v := value.(type) {
+		case nil:
+			// "KEY:" without value, leave the default
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, separator)
+		case map[string]interface{}:
+			return nil, fmt.Errorf("invalid value for %q in config file %s: expected a scalar or a list", key, path)
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// envSeparators returns the list separator of every environment variable of Config
+func envSeparators() map[string]string {
+	t := reflect.TypeOf(Config{})
+	separators := make(map[string]string, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if name == "" {
+			continue
+		}
+		separator := field.Tag.Get("envSeparator")
+		if separator == "" {
+			separator = ","
+		}
+		separators[name] = separator
+	}
+	return separators
+}
@@ -17,7 +17,9 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -35,6 +37,16 @@ var _ = Describe("Config", func() {
 		os.Unsetenv("THIRD_PARTY_RDMA_MODULES")
 		os.Unsetenv("STORAGE_MODULES")
 		os.Unsetenv("MLX5_AUXILIARY_MODULES")
+		os.Unsetenv("CONFIG_FILE")
+		os.Unsetenv("ENABLE_NFSRDMA")
+		os.Unsetenv("ENABLE_NVME_RDMA")
+		os.Unsetenv("SYSFS_ROOT")
+		os.Unsetenv("PROC_ROOT")
+		os.Unsetenv("HOST_ROOT")
+		os.Unsetenv("OPENIBD_SCRIPT_PATH")
+		os.Unsetenv("UBUNTU_PRO_TOKEN")
+		os.Unsetenv("MODULE_OPTIONS")
+		os.Unsetenv("WITH_MLNX_TOOLS")
 	})
 
 	Context("UnloadThirdPartyRdmaModules", func() {
@@ -63,6 +75,24 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("WithMlnxTools", func() {
+		It("should default to true when WITH_MLNX_TOOLS is not set", func() {
+			os.Unsetenv("WITH_MLNX_TOOLS")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.WithMlnxTools).To(BeTrue())
+		})
+
+		It("should be false when set to \"false\"", func() {
+			os.Setenv("WITH_MLNX_TOOLS", "false")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.WithMlnxTools).To(BeFalse())
+		})
+	})
+
 	Context("StorageModules", func() {
 		It("should include ib_iser and ib_srp in the default list", func() {
 			os.Unsetenv("STORAGE_MODULES")
@@ -126,4 +156,175 @@ var _ = Describe("Config", func() {
 			Expect(cfg.Mlx5AuxiliaryModules).To(BeEmpty())
 		})
 	})
+
+	Context("EnableNvmeRdma", func() {
+		It("should follow ENABLE_NFSRDMA when ENABLE_NVME_RDMA is not set", func() {
+			os.Setenv("ENABLE_NFSRDMA", "true")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.EnableNfsRdma).To(BeTrue())
+			Expect(cfg.EnableNvmeRdma).To(BeTrue())
+		})
+
+		It("should be set independently from ENABLE_NFSRDMA", func() {
+			os.Setenv("ENABLE_NFSRDMA", "true")
+			os.Setenv("ENABLE_NVME_RDMA", "false")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.EnableNfsRdma).To(BeTrue())
+			Expect(cfg.EnableNvmeRdma).To(BeFalse())
+		})
+	})
+
+	Context("SysfsRoot and ProcRoot", func() {
+		It("should default to /sys and /proc", func() {
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.SysfsRoot).To(Equal("/sys"))
+			Expect(cfg.ProcRoot).To(Equal("/proc"))
+		})
+
+		It("should honor overrides", func() {
+			os.Setenv("SYSFS_ROOT", "/host/sys")
+			os.Setenv("PROC_ROOT", "/host/proc")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.SysfsRoot).To(Equal("/host/sys"))
+			Expect(cfg.ProcRoot).To(Equal("/host/proc"))
+		})
+	})
+
+	Context("HostRoot and OpenibdScriptPath", func() {
+		It("should default to /host and /etc/init.d/openibd", func() {
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.HostRoot).To(Equal("/host"))
+			Expect(cfg.OpenibdScriptPath).To(Equal("/etc/init.d/openibd"))
+		})
+
+		It("should honor overrides", func() {
+			os.Setenv("HOST_ROOT", "/")
+			os.Setenv("OPENIBD_SCRIPT_PATH", "/usr/sbin/openibd")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.HostRoot).To(Equal("/"))
+			Expect(cfg.OpenibdScriptPath).To(Equal("/usr/sbin/openibd"))
+		})
+	})
+
+	Context("ModuleOptions", func() {
+		It("should parse the options of every module", func() {
+			os.Setenv("MODULE_OPTIONS", "mlx5_core:prof_sel=2 num_of_groups=4;ib_ipoib:ipoib_enhanced=0")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.ModuleOptions).To(Equal(map[string]string{
+				"mlx5_core": "prof_sel=2 num_of_groups=4",
+				"ib_ipoib":  "ipoib_enhanced=0",
+			}))
+		})
+	})
+
+	Context("CONFIG_FILE", func() {
+		var configFile string
+
+		BeforeEach(func() {
+			configFile = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			os.Setenv("CONFIG_FILE", configFile)
+		})
+
+		It("should populate values from the config file", func() {
+			os.Unsetenv("NVIDIA_NIC_DRIVER_VER")
+			Expect(os.WriteFile(configFile, []byte(`
+NVIDIA_NIC_DRIVER_VER: 25.04-0.6.1.0
+ENABLE_NFSRDMA: true
+BIND_DELAY_SEC: 10
+THIRD_PARTY_RDMA_MODULES: [foo_re, bar_rdma]
+READ_ONLY_INVENTORY_PATHS:
+  - /shared/a
+  - /shared/b
+`), 0o644)).To(Succeed())
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.NvidiaNicDriverVer).To(Equal("25.04-0.6.1.0"))
+			Expect(cfg.EnableNfsRdma).To(BeTrue())
+			Expect(cfg.BindDelaySec).To(Equal(10))
+			Expect(cfg.ThirdPartyRDMAModules).To(Equal([]string{"foo_re", "bar_rdma"}))
+			Expect(cfg.ReadOnlyInventoryPaths).To(Equal([]string{"/shared/a", "/shared/b"}))
+			// Defaults still apply to the values not in the file
+			Expect(cfg.SysfsRoot).To(Equal("/sys"))
+		})
+
+		It("should accept a JSON config file", func() {
+			Expect(os.WriteFile(configFile, []byte(`{"ENABLE_NFSRDMA": true, "STORAGE_MODULES": ["ib_iser"]}`), 0o644)).To(Succeed())
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.EnableNfsRdma).To(BeTrue())
+			Expect(cfg.StorageModules).To(Equal([]string{"ib_iser"}))
+		})
+
+		It("should let environment variables override the config file", func() {
+			Expect(os.WriteFile(configFile, []byte("NVIDIA_NIC_DRIVER_VER: 24.10-1.1.4.0\nENABLE_NFSRDMA: true\n"), 0o644)).To(Succeed())
+			os.Setenv("ENABLE_NFSRDMA", "false")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.NvidiaNicDriverVer).To(Equal("25.04-0.6.0.0"))
+			Expect(cfg.EnableNfsRdma).To(BeFalse())
+		})
+
+		It("should return a clear error for malformed YAML", func() {
+			Expect(os.WriteFile(configFile, []byte("ENABLE_NFSRDMA: [true\n"), 0o644)).To(Succeed())
+
+			_, err := GetConfig()
+			Expect(err).To(MatchError(ContainSubstring("failed to parse config file " + configFile)))
+		})
+
+		It("should reject unknown keys", func() {
+			Expect(os.WriteFile(configFile, []byte("ENABLE_NFS_RDMA: true\n"), 0o644)).To(Succeed())
+
+			_, err := GetConfig()
+			Expect(err).To(MatchError(ContainSubstring(`unknown key "ENABLE_NFS_RDMA"`)))
+		})
+
+		It("should fail when the config file doesn't exist", func() {
+			_, err := GetConfig()
+			Expect(err).To(MatchError(ContainSubstring("failed to read config file")))
+		})
+	})
+
+	Context("DumpJSON", func() {
+		It("should mask secrets and include the other fields", func() {
+			os.Setenv("UBUNTU_PRO_TOKEN", "C1234567890abcdef")
+			os.Setenv("ENABLE_NFSRDMA", "true")
+
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			data, err := cfg.DumpJSON()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring("C1234567890abcdef"))
+
+			var dumped map[string]interface{}
+			Expect(json.Unmarshal(data, &dumped)).To(Succeed())
+			Expect(dumped).To(HaveKeyWithValue("UbuntuProToken", "<redacted>"))
+			Expect(dumped).To(HaveKeyWithValue("NvidiaNicDriverVer", "25.04-0.6.0.0"))
+			Expect(dumped).To(HaveKeyWithValue("EnableNfsRdma", true))
+			Expect(dumped).To(HaveKeyWithValue("BindDelaySec", BeNumerically("==", 4)))
+
+			// The config itself is left untouched
+			Expect(cfg.UbuntuProToken).To(Equal("C1234567890abcdef"))
+		})
+
+		It("should leave an unset secret empty", func() {
+			cfg, err := GetConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Redacted().UbuntuProToken).To(BeEmpty())
+		})
+	})
 })
@@ -29,12 +29,33 @@ const (
 	OSTypeRedHat    = "redhat"
 	OSTypeOpenShift = "openshift"
 
+	// /sys mount propagation modes, see config.Config.SysMountPropagation
+	SysMountPropagationPrivate = "private"
+	SysMountPropagationSlave   = "slave"
+	SysMountPropagationShared  = "shared"
+	SysMountPropagationSkip    = "skip"
+
+	// Fabric types, see config.Config.Fabric
+	FabricAuto  = "auto"
+	FabricEth   = "eth"
+	FabricIB    = "ib"
+	FabricMixed = "mixed"
+
 	// Default versions
 	DefaultRHELVersion      = "8.4"
 	DefaultOpenShiftVersion = "4.9"
 
 	InvalidGUID = "00:00:00:00:00:00:00:00"
 
+	// Default pseudo-filesystem roots, used when the configured roots are empty
+	DefaultSysfsRoot = "/sys"
+	DefaultProcRoot  = "/proc"
+
+	// Default host paths, used when the configured paths are empty
+	DefaultHostRoot          = "/host"
+	DefaultOpenibdScriptPath = "/etc/init.d/openibd"
+	DefaultHostCommandPrefix = "nsenter -t 1 -m -n --"
+
 	// DTK constants
 	DtkOcpBuildScriptPath    = "/root/dtk_nic_driver_build.sh"
 	DtkStartCompileFlag      = "dtk_start_compile"
@@ -0,0 +1,150 @@
+/*
+ Copyright 2026, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// diagnosticsCommand is a command whose output is stored in a file of the diagnostics bundle
+type diagnosticsCommand struct {
+	file    string
+	command string
+	args    []string
+}
+
+// diagnosticsCommands are the host state commands collected by CollectDiagnostics
+var diagnosticsCommands = []diagnosticsCommand{
+	{file: "uname.txt", command: "uname", args: []string{"-a"}},
+	{file: "lsmod.txt", command: "lsmod"},
+	{file: "modinfo-mlx5_core.txt", command: "modinfo", args: []string{moduleMlx5Core}},
+	{file: "dmesg-mlx5.txt", command: "sh", args: []string{"-c", "dmesg | grep mlx5"}},
+}
+
+// CollectDiagnostics writes the host state relevant for support into files under outDir.
+// Every item is collected on a best-effort basis, only a failure to create outDir is returned.
+func (d *driverMgr) CollectDiagnostics(ctx context.Context, outDir string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if err := d.os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create diagnostics dir %s: %w", outDir, err)
+	}
+	log.Info("Collecting diagnostics", "dir", outDir)
+
+	for _, c := range diagnosticsCommands {
+		d.collectDiagnosticsCommand(ctx, outDir, c)
+	}
+	for _, netdev := range d.mellanoxNetdevs(ctx) {
+		d.collectDiagnosticsCommand(ctx, outDir, diagnosticsCommand{
+			file: "ethtool-" + netdev + ".txt", command: "ethtool", args: []string{"-i", netdev},
+		})
+	}
+
+	d.collectDiagnosticsFile(ctx, outDir, "proc-version.txt", d.procPath("version"))
+	d.collectDiagnosticsFile(ctx, outDir, "os-release.txt", filepath.Join(d.hostRoot(), "etc", "os-release"))
+	d.collectDiagnosticsFile(ctx, outDir, "blacklist.conf", d.cfg.OfedBlacklistModulesFile)
+	d.writeDiagnosticsFile(ctx, outDir, "inventory.txt", d.inventoryState(ctx))
+
+	log.Info("Diagnostics collected", "dir", outDir)
+	return nil
+}
+
+// collectDiagnosticsCommand runs the command and stores its output, a failure is recorded in the file
+func (d *driverMgr) collectDiagnosticsCommand(ctx context.Context, outDir string, c diagnosticsCommand) {
+	stdout, stderr, err := d.cmd.RunCommand(ctx, c.command, c.args...)
+	content := stdout + stderr
+	if err != nil {
+		logr.FromContextOrDiscard(ctx).V(1).Info("Diagnostics command failed",
+			"command", c.command, "args", c.args, "error", err)
+		// Non-fatal error, continue
+		content += fmt.Sprintf("\nerror: %v\n", err)
+	}
+	d.writeDiagnosticsFile(ctx, outDir, c.file, content)
+}
+
+// collectDiagnosticsFile copies the file into the bundle, a missing file is recorded instead
+func (d *driverMgr) collectDiagnosticsFile(ctx context.Context, outDir, name, path string) {
+	data, err := d.os.ReadFile(path)
+	if err != nil {
+		logr.FromContextOrDiscard(ctx).V(1).Info("Failed to read diagnostics file", "path", path, "error", err)
+		// Non-fatal error, continue
+		d.writeDiagnosticsFile(ctx, outDir, name, fmt.Sprintf("error: %v\n", err))
+		return
+	}
+	d.writeDiagnosticsFile(ctx, outDir, name, string(data))
+}
+
+func (d *driverMgr) writeDiagnosticsFile(ctx context.Context, outDir, name, content string) {
+	path := filepath.Join(outDir, name)
+	if err := d.os.WriteFile(path, []byte(content), 0o644); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "Failed to write diagnostics file", "path", path)
+		// Non-fatal error, continue
+	}
+}
+
+// mellanoxNetdevs returns the names of the netdevs of Mellanox devices
+func (d *driverMgr) mellanoxNetdevs(ctx context.Context) []string {
+	entries, err := d.os.ReadDir(d.sysfsPath("class", "net"))
+	if err != nil {
+		logr.FromContextOrDiscard(ctx).V(1).Info("Failed to list network devices", "error", err)
+		return nil
+	}
+
+	var netdevs []string
+	for _, entry := range entries {
+		vendor, err := d.os.ReadFile(d.sysfsPath("class", "net", entry.Name(), "device", "vendor"))
+		if err != nil || strings.TrimSpace(string(vendor)) != "0x15b3" {
+			continue
+		}
+		netdevs = append(netdevs, entry.Name())
+	}
+	return netdevs
+}
+
+// inventoryState describes the driver inventory content for the running kernel
+func (d *driverMgr) inventoryState(ctx context.Context) string {
+	if d.cfg.NvidiaNicDriversInventoryPath == "" {
+		return "inventory is disabled\n"
+	}
+
+	kernelVersion, err := d.host.GetKernelVersion(ctx)
+	if err != nil {
+		return fmt.Sprintf("error: failed to get kernel version: %v\n", err)
+	}
+
+	var b strings.Builder
+	kernelDir := filepath.Join(d.cfg.NvidiaNicDriversInventoryPath, kernelVersion)
+	fmt.Fprintf(&b, "inventory: %s\n", kernelDir)
+	entries, err := d.os.ReadDir(kernelDir)
+	if err != nil {
+		fmt.Fprintf(&b, "error: %v\n", err)
+		return b.String()
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			fmt.Fprintf(&b, "%s/\n", entry.Name())
+			continue
+		}
+		fmt.Fprintf(&b, "%s\n", entry.Name())
+	}
+	return b.String()
+}
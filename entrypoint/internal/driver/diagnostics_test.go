@@ -0,0 +1,130 @@
+/*
+ Copyright 2026, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/constants"
+	cmdMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd/mocks"
+	hostMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/host/mocks"
+	wrappersMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers/mocks"
+)
+
+var _ = Describe("Driver diagnostics", func() {
+	const outDir = "/tmp/diag"
+
+	var (
+		dm       *driverMgr
+		cmdMock  *cmdMockPkg.Interface
+		hostMock *hostMockPkg.Interface
+		osMock   *wrappersMockPkg.OSWrapper
+		ctx      context.Context
+		cfg      config.Config
+		written  map[string]string
+	)
+
+	BeforeEach(func() {
+		cmdMock = cmdMockPkg.NewInterface(GinkgoT())
+		hostMock = hostMockPkg.NewInterface(GinkgoT())
+		osMock = wrappersMockPkg.NewOSWrapper(GinkgoT())
+		ctx = context.Background()
+		cfg = config.Config{
+			OfedBlacklistModulesFile:      "/host/etc/modprobe.d/blacklist-ofed-modules.conf",
+			NvidiaNicDriversInventoryPath: "/mnt/drivers-inventory",
+		}
+		written = map[string]string{}
+	})
+
+	// expectWrites records the files written into the out dir
+	expectWrites := func() {
+		osMock.EXPECT().WriteFile(mock.Anything, mock.Anything, os.FileMode(0o644)).
+			RunAndReturn(func(name string, data []byte, _ os.FileMode) error {
+				written[name] = string(data)
+				return nil
+			})
+	}
+
+	It("should write the output of every diagnostics command and file into the out dir", func() {
+		dm = New(constants.DriverContainerModeSources, cfg, cmdMock, hostMock, osMock).(*driverMgr)
+
+		osMock.EXPECT().MkdirAll(outDir, os.FileMode(0o755)).Return(nil)
+		expectWrites()
+		cmdMock.EXPECT().RunCommand(ctx, "uname", "-a").Return("Linux node 5.15.0\n", "", nil)
+		cmdMock.EXPECT().RunCommand(ctx, "lsmod").Return("mlx5_core 1 0\n", "", nil)
+		cmdMock.EXPECT().RunCommand(ctx, "modinfo", "mlx5_core").Return("version: 25.01\n", "", nil)
+		cmdMock.EXPECT().RunCommand(ctx, "sh", "-c", "dmesg | grep mlx5").Return("", "", errors.New("exit status 1"))
+		osMock.EXPECT().ReadDir("/sys/class/net").Return([]os.DirEntry{
+			mockDirEntry{name: "eth0"}, mockDirEntry{name: "lo"},
+		}, nil)
+		osMock.EXPECT().ReadFile("/sys/class/net/eth0/device/vendor").Return([]byte("0x15b3\n"), nil)
+		osMock.EXPECT().ReadFile("/sys/class/net/lo/device/vendor").Return(nil, errors.New("not found"))
+		cmdMock.EXPECT().RunCommand(ctx, "ethtool", "-i", "eth0").Return("driver: mlx5_core\n", "", nil)
+		osMock.EXPECT().ReadFile("/proc/version").Return([]byte("Linux version 5.15.0\n"), nil)
+		osMock.EXPECT().ReadFile("/host/etc/os-release").Return([]byte("ID=ubuntu\n"), nil)
+		osMock.EXPECT().ReadFile(cfg.OfedBlacklistModulesFile).Return(nil, errors.New("no such file"))
+		hostMock.EXPECT().GetKernelVersion(ctx).Return("5.15.0", nil)
+		osMock.EXPECT().ReadDir("/mnt/drivers-inventory/5.15.0").Return([]os.DirEntry{
+			mockDirEntry{name: "25.01", isDir: true}, mockDirEntry{name: "25.01.checksum"},
+		}, nil)
+
+		Expect(dm.CollectDiagnostics(ctx, outDir)).To(Succeed())
+		Expect(written).To(HaveLen(9))
+		Expect(written).To(HaveKeyWithValue(outDir+"/uname.txt", "Linux node 5.15.0\n"))
+		Expect(written).To(HaveKeyWithValue(outDir+"/lsmod.txt", "mlx5_core 1 0\n"))
+		Expect(written).To(HaveKeyWithValue(outDir+"/modinfo-mlx5_core.txt", "version: 25.01\n"))
+		Expect(written).To(HaveKeyWithValue(outDir+"/dmesg-mlx5.txt", ContainSubstring("error: exit status 1")))
+		Expect(written).To(HaveKeyWithValue(outDir+"/ethtool-eth0.txt", "driver: mlx5_core\n"))
+		Expect(written).To(HaveKeyWithValue(outDir+"/proc-version.txt", "Linux version 5.15.0\n"))
+		Expect(written).To(HaveKeyWithValue(outDir+"/os-release.txt", "ID=ubuntu\n"))
+		Expect(written).To(HaveKeyWithValue(outDir+"/blacklist.conf", ContainSubstring("no such file")))
+		Expect(written).To(HaveKeyWithValue(outDir+"/inventory.txt",
+			"inventory: /mnt/drivers-inventory/5.15.0\n25.01/\n25.01.checksum\n"))
+	})
+
+	It("should continue when a diagnostics file can't be written", func() {
+		cfg.NvidiaNicDriversInventoryPath = ""
+		dm = New(constants.DriverContainerModeSources, cfg, cmdMock, hostMock, osMock).(*driverMgr)
+
+		osMock.EXPECT().MkdirAll(outDir, os.FileMode(0o755)).Return(nil)
+		osMock.EXPECT().WriteFile(mock.Anything, mock.Anything, os.FileMode(0o644)).Return(errors.New("read-only file system"))
+		cmdMock.EXPECT().RunCommand(ctx, mock.Anything, mock.Anything).Return("", "", nil)
+		cmdMock.EXPECT().RunCommand(ctx, mock.Anything, mock.Anything, mock.Anything).Return("", "", nil)
+		cmdMock.EXPECT().RunCommand(ctx, "lsmod").Return("", "", nil)
+		osMock.EXPECT().ReadDir("/sys/class/net").Return(nil, errors.New("not found"))
+		osMock.EXPECT().ReadFile(mock.Anything).Return([]byte(""), nil)
+
+		Expect(dm.CollectDiagnostics(ctx, outDir)).To(Succeed())
+		osMock.AssertNumberOfCalls(GinkgoT(), "WriteFile", 8)
+	})
+
+	It("should fail when the out dir can't be created", func() {
+		dm = New(constants.DriverContainerModeSources, cfg, cmdMock, hostMock, osMock).(*driverMgr)
+
+		osMock.EXPECT().MkdirAll(outDir, os.FileMode(0o755)).Return(errors.New("permission denied"))
+
+		err := dm.CollectDiagnostics(ctx, outDir)
+		Expect(err).To(MatchError(ContainSubstring("failed to create diagnostics dir /tmp/diag")))
+	})
+})
@@ -17,16 +17,27 @@
 package driver
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/kballard/go-shellquote"
 
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/constants"
@@ -48,10 +59,56 @@ const (
 	moduleIBCore   = "ib_core"
 	moduleMlx5Core = "mlx5_core"
 	moduleMlx5IB   = "mlx5_ib"
+	moduleMlx5Vdpa = "mlx5_vdpa"
+
+	// writeProbeFileName is written and removed to check write access to a directory
+	writeProbeFileName = ".write-probe"
+	// number of install.pl output lines logged when the build fails
+	buildOutputTailLines = 50
+
+	// ifup is renamed on Ubuntu hosts without /etc/network/interfaces, see ubuntuSyncNetworkConfigurationTools
+	ifupPath       = "/sbin/ifup"
+	ifupBackupPath = ifupPath + ".bk"
+
+	// resolvConfPath is replaced by the host resolv.conf during Build with CopyHostResolvConf
+	resolvConfPath = "/etc/resolv.conf"
+
+	// markers around the blacklist entries added in BlacklistMergeExisting mode
+	blacklistManagedBlockBegin = "# BEGIN doca-driver-build managed blacklist"
+	blacklistManagedBlockEnd   = "# END doca-driver-build managed blacklist"
+
+	// moduleOptionsFile holds the ModuleOptions, openibd loads the modules with them
+	moduleOptionsFile = "/etc/modprobe.d/mlnx-options.conf"
+
+	// buildInfoFileName is the name of the file describing the build in the ExportPackagesTarball
+	buildInfoFileName = "build-info"
+
+	// default KernelHeaderPackageTemplate of each OS
+	ubuntuKernelHeaderPackageTemplate = "linux-headers-{{.KernelVersion}}"
+	slesKernelHeaderPackageTemplate   = "kernel-{{.Flavor}}-devel={{.KernelVersion}}"
+	redHatKernelHeaderPackageTemplate = "kernel-{{if .Flavor}}{{.Flavor}}-{{end}}devel-{{.KernelVersion}}"
 )
 
+// goArchToUname maps Go architecture names to the machine names reported by `uname -m`
+var goArchToUname = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
 var kernelModuleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
 
+// pkgManagerLockRetryInterval is the delay between dnf/zypper attempts while the package
+// manager is locked by another process, see runPackageManager
+var pkgManagerLockRetryInterval = 5 * time.Second
+
+// Sources of the driver packages installed by Build, see Interface.BuildSource
+const (
+	BuildSourceCache = "cache"
+	BuildSourceFresh = "fresh"
+)
+
 // New creates a new instance of the driver manager
 func New(containerMode string, cfg config.Config,
 	c cmd.Interface, h host.Interface, osWrapper wrappers.OSWrapper,
@@ -72,14 +129,28 @@ type Interface interface {
 	PreStart(ctx context.Context) error
 	// Build installs required dependencies and build the driver
 	Build(ctx context.Context) error
+	// BuildSource returns BuildSourceCache when the last Build reused packages from the inventory,
+	// BuildSourceFresh when it built them, empty before Build decided
+	BuildSource() string
 	// Load the new driver version. Returns a boolean indicating whether the driver was loaded successfully.
 	// The function will return false if the system already has the same driver version loaded.
 	Load(ctx context.Context) (bool, error)
+	// Drifted reports whether the host kernel changed or the loaded modules drifted from the candidate
+	// ones since the driver was loaded, i.e. whether Reconcile would reload the driver.
+	Drifted(ctx context.Context) (bool, error)
+	// Reconcile rebuilds the driver for a changed host kernel and reloads it the same way Load does,
+	// it is called after Drifted reported a drift. Returns true if the driver was reloaded.
+	Reconcile(ctx context.Context) (bool, error)
 	// Unload the driver and replace it with the inbox driver. Returns a boolean indicating whether the driver was unloaded successfully.
 	// The function will return false if the system already runs with inbox driver.
 	Unload(ctx context.Context) (bool, error)
 	// Clear cleanups the system by removing unended leftovers.
 	Clear(ctx context.Context) error
+	// CollectDiagnostics writes the host state relevant for support (kernel, loaded modules,
+	// netdevs, blacklist and inventory) into files under outDir.
+	CollectDiagnostics(ctx context.Context, outDir string) error
+	// ListInventory returns the driver packages cached in the inventory, per kernel and driver version
+	ListInventory(ctx context.Context) ([]InventoryEntry, error)
 }
 
 type driverMgr struct {
@@ -88,6 +159,31 @@ type driverMgr struct {
 	newDriverLoaded bool
 
 	driverBuildIncomplete bool
+	// buildSource records whether Build reused the inventory packages or built them, see BuildSource
+	buildSource string
+	// enabledRepos are the dnf repos enabled by this run, they are disabled again in Clear
+	enabledRepos []string
+	// fabric caches the result of getFabric
+	fabric string
+	// ifupRenamed is set when /sbin/ifup was renamed by ubuntuSyncNetworkConfigurationTools,
+	// it is renamed back in Clear
+	ifupRenamed bool
+	// resolvConfReplaced is set when /etc/resolv.conf was replaced by the host one with CopyHostResolvConf,
+	// resolvConfOriginal (nil if there was no file) is written back in Clear
+	resolvConfReplaced bool
+	resolvConfOriginal []byte
+	// detectedDriverVer is the driver version read from the built packages with AutoDetectDriverVersion
+	detectedDriverVer string
+	// installedDrivers holds the <kernel>/<driver version> pairs installed by this process,
+	// a repeated installDriver call for one of them is a no-op
+	installedDrivers map[string]bool
+	// loadedKernelVer is the kernel the driver was last loaded for with ReconcileInterval,
+	// Reconcile rebuilds and reloads the driver when the host kernel differs from it
+	loadedKernelVer string
+	// arch caches the node architecture used for the InventoryArchSubdir layout
+	arch string
+	// sourceFingerprint caches the driver sources hash, see currentSourceFingerprint
+	sourceFingerprint string
 
 	cmd  cmd.Interface
 	host host.Interface
@@ -98,6 +194,12 @@ type driverMgr struct {
 func (d *driverMgr) PreStart(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
 
+	// Fail fast instead of failing deep inside the build when running on a host with another arch
+	if err := d.checkArchitecture(ctx); err != nil {
+		log.Error(err, "host architecture check failed")
+		return err
+	}
+
 	// When DKMS is enabled, dkms and the OFED package post-install scriptlets invoke
 	// `systemctl`, which is noisy in this non-systemd container. Install a no-op stub on
 	// PATH before the build/install (Build) and load (Load) steps so those calls succeed
@@ -107,7 +209,7 @@ func (d *driverMgr) PreStart(ctx context.Context) error {
 	}
 
 	// Update CA certificates at the very beginning
-	if err := d.updateCACertificates(ctx); err != nil {
+	if err := d.RefreshCACertificates(ctx); err != nil {
 		log.V(1).Info("Failed to update CA certificates", "error", err)
 		// Non-fatal error, continue
 	}
@@ -122,11 +224,15 @@ func (d *driverMgr) PreStart(ctx context.Context) error {
 	case constants.DriverContainerModeSources:
 		log.Info("Executing driver sources container")
 		if d.cfg.NvidiaNicDriverPath == "" {
-			err := fmt.Errorf("NVIDIA_NIC_DRIVER_PATH environment variable must be set")
+			err := ErrMissingDriverPath
 			log.Error(err, "missing required environment variable")
 			return err
 		}
 		log.V(1).Info("Drivers source", "path", d.cfg.NvidiaNicDriverPath)
+		if err := d.checkHostMounts(ctx); err != nil {
+			log.Error(err, "host mounts check failed")
+			return err
+		}
 		if err := d.prepareGCC(ctx); err != nil {
 			return err
 		}
@@ -149,9 +255,71 @@ func (d *driverMgr) PreStart(ctx context.Context) error {
 		}
 	case constants.DriverContainerModePrecompiled:
 		log.Info("Executing precompiled driver container")
+		if err := d.checkHostMounts(ctx); err != nil {
+			log.Error(err, "host mounts check failed")
+			return err
+		}
 		return nil
 	default:
-		return fmt.Errorf("unknown containerMode")
+		return fmt.Errorf("%w: %s", ErrUnknownContainerMode, d.containerMode)
+	}
+	return nil
+}
+
+// hostMount is a host path the container depends on and why
+type hostMount struct {
+	path   string
+	reason string
+}
+
+// requiredHostMounts returns the host paths needed by the container mode for the OS type and kernel
+func (d *driverMgr) requiredHostMounts(ctx context.Context, osType, kernelVersion string) []hostMount {
+	mounts := []hostMount{
+		{d.sysfsPath(), "host sysfs, used to load the driver and configure the devices"},
+		{"/lib/modules", "host kernel modules, where the driver modules are installed"},
+	}
+	if d.containerMode != constants.DriverContainerModeSources {
+		return mounts
+	}
+
+	// Headers of RT and 64k kernels come from repos that are only configured on the host
+	switch osType {
+	case constants.OSTypeUbuntu:
+		if strings.Contains(kernelVersion, "realtime") {
+			mounts = append(mounts, hostMount{"/host/etc/apt", "host APT configuration, needed to install realtime kernel headers"})
+		}
+	case constants.OSTypeRedHat, constants.OSTypeOpenShift:
+		kernelType, _, _, _ := d.analyzeKernelType(ctx, kernelVersion, &host.RedhatVersionInfo{})
+		if kernelType == kernelTypeRT || kernelType == kernelType64k {
+			mounts = append(mounts, hostMount{"/host/etc/yum.repos.d", "host yum repos, needed to install " + kernelType + " kernel packages"})
+		}
+	}
+	return mounts
+}
+
+// checkHostMounts verifies the required host mounts exist and returns a single error listing all the missing ones
+func (d *driverMgr) checkHostMounts(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	osType, err := d.host.GetOSType(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get OS type: %w", err)
+	}
+	kernelVersion, err := d.host.GetKernelVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get kernel version: %w", err)
+	}
+
+	var missing []string
+	for _, mount := range d.requiredHostMounts(ctx, osType, kernelVersion) {
+		if _, err := d.os.Stat(mount.path); err != nil {
+			log.V(1).Info("Required host mount is not accessible", "path", mount.path, "error", err)
+			missing = append(missing, fmt.Sprintf("%s (%s)", mount.path, mount.reason))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s; add them as hostPath volumes of the driver container",
+			ErrMissingHostMounts, strings.Join(missing, ", "))
 	}
 	return nil
 }
@@ -167,7 +335,7 @@ func (d *driverMgr) Build(ctx context.Context) error {
 	}
 
 	// Get kernel version
-	kernelVersion, err := d.host.GetKernelVersion(ctx)
+	kernelVersion, err := d.buildKernelVersion(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get kernel version: %w", err)
 	}
@@ -178,25 +346,72 @@ func (d *driverMgr) Build(ctx context.Context) error {
 		return fmt.Errorf("failed to get OS type: %w", err)
 	}
 
+	progress := newBuildProgress(d.cfg.DtkOcpDriverBuild)
+
 	// For DTK builds the DTK sidecar handles compilation, so kernel headers are not
 	// needed in this container and package repos may not be reachable from it.
 	// For non-DTK builds, prerequisites must be installed before the cache check
 	// because DKMS still needs kernel headers even when driver packages are cached.
 	if !d.cfg.DtkOcpDriverBuild {
-		log.V(1).Info("About to install prerequisites", "os", osType, "kernel", kernelVersion)
-		if err := d.installPrerequisitesForOS(ctx, osType, kernelVersion); err != nil {
-			return fmt.Errorf("failed to install prerequisites: %w", err)
+		progress.phase(ctx, phasePrerequisites)
+		if d.cfg.CopyHostResolvConf {
+			if err := d.copyHostResolvConf(ctx); err != nil {
+				log.Error(err, "Failed to copy host resolv.conf")
+				// Non-fatal error, continue
+			}
+		}
+		// Custom CA certs may have been mounted after PreStart, refresh the trust store
+		// before the package manager goes to the network.
+		if err := d.RefreshCACertificates(ctx); err != nil {
+			log.V(1).Info("Failed to refresh CA certificates", "error", err)
+			// Non-fatal error, continue
+		}
+		if d.cfg.OfflineBuild {
+			log.V(1).Info("Offline build, verifying prerequisites", "os", osType, "kernel", kernelVersion)
+			if err := d.verifyOfflinePrerequisites(ctx, osType, kernelVersion); err != nil {
+				return fmt.Errorf("failed to verify prerequisites: %w", err)
+			}
+		} else {
+			log.V(1).Info("About to install prerequisites", "os", osType, "kernel", kernelVersion)
+			if err := d.installPrerequisitesForOS(ctx, osType, kernelVersion); err != nil {
+				if d.cfg.TargetKernelVersion != "" {
+					return fmt.Errorf("failed to install prerequisites, the headers of the target kernel %s may not be installable: %w",
+						kernelVersion, err)
+				}
+				return fmt.Errorf("failed to install prerequisites: %w", err)
+			}
+			if err := d.verifyKernelHeaders(ctx, osType, kernelVersion); err != nil {
+				return err
+			}
+			if err := d.installExtraBuildPackages(ctx, osType); err != nil {
+				return fmt.Errorf("failed to install extra build packages: %w", err)
+			}
+		}
+	}
+
+	// The installed OFED version is only informational, skip the extra command unless debug logging is on
+	if log.V(1).Enabled() {
+		ofedVersion, err := d.host.GetInstalledOfedVersion(ctx)
+		if err != nil {
+			log.V(1).Info("Failed to get installed OFED version", "error", err)
+			// Non-fatal error, continue
+		} else if ofedVersion != "" {
+			log.V(1).Info("Installed OFED version", "version", ofedVersion, "driverVersion", d.driverVersion())
 		}
 	}
 
 	// Check driver inventory and validate checksums
+	progress.phase(ctx, phaseCheckingInventory)
 	shouldBuild, inventoryPath, err := d.checkDriverInventory(ctx, kernelVersion)
 	if err != nil {
 		return fmt.Errorf("failed to check driver inventory: %w", err)
 	}
 
 	if !shouldBuild {
-		log.Info("Skipping driver build, reusing previously built packages", "kernel", kernelVersion)
+		progress.skipBuild()
+		d.buildSource = BuildSourceCache
+		log.Info("Skipping driver build, reusing previously built packages", "kernel", kernelVersion,
+			"build_source", d.buildSource)
 	} else {
 		// Mark build as incomplete at the start
 		d.driverBuildIncomplete = true
@@ -209,9 +424,15 @@ func (d *driverMgr) Build(ctx context.Context) error {
 		}
 
 		// Check if DTK OCP driver build is enabled
+		progress.phase(ctx, phaseCompiling)
+		if !d.cfg.DtkOcpDriverBuild {
+			if err := d.checkBuildDiskSpace(ctx, inventoryPath); err != nil {
+				return err
+			}
+		}
 		if d.cfg.DtkOcpDriverBuild {
 			if err := d.buildDriverDTK(ctx, kernelVersion, inventoryPath); err != nil {
-				return err
+				return fmt.Errorf("%w with DTK: %w", ErrBuildFailed, err)
 			}
 		} else {
 			// Create inventory directory
@@ -225,10 +446,21 @@ func (d *driverMgr) Build(ctx context.Context) error {
 			}
 
 			// Copy build artifacts to inventory
+			progress.phase(ctx, phaseCopyingArtifacts)
 			if err := d.copyBuildArtifacts(ctx, d.cfg.NvidiaNicDriverPath, inventoryPath, osType); err != nil {
 				return fmt.Errorf("failed to copy build artifacts: %w", err)
 			}
 
+			if d.cfg.AutoDetectDriverVersion {
+				inventoryPath = d.applyDetectedDriverVersion(ctx, inventoryPath, kernelVersion, osType)
+			}
+
+			if d.cfg.ExportPackagesTarball != "" {
+				if err := d.exportPackagesTarball(ctx, inventoryPath, kernelVersion, osType); err != nil {
+					return fmt.Errorf("failed to export driver packages: %w", err)
+				}
+			}
+
 			// Fix source link if needed
 			if err := d.fixSourceLink(ctx, kernelVersion); err != nil {
 				log.V(1).Info("Failed to fix source link", "error", err)
@@ -245,11 +477,21 @@ func (d *driverMgr) Build(ctx context.Context) error {
 
 		// Mark build as complete after successful build
 		d.driverBuildIncomplete = false
+		d.buildSource = BuildSourceFresh
+
+		if d.cfg.NvidiaNicDriversInventoryPath != "" && d.cfg.InventoryMaxKernels > 0 {
+			if err := d.pruneInventoryKernels(ctx, kernelVersion); err != nil {
+				log.V(1).Info("Failed to prune driver inventory", "error", err)
+				// Non-fatal error, continue
+			}
+		}
 
-		log.Info("Driver build completed successfully", "kernel", kernelVersion, "inventory", inventoryPath)
+		log.Info("Driver build completed successfully", "kernel", kernelVersion, "inventory", inventoryPath,
+			"build_source", d.buildSource)
 	}
 
 	// Install the driver packages (always install, whether from cache or fresh build)
+	progress.phase(ctx, phaseInstalling)
 	if err := d.installDriver(ctx, inventoryPath, kernelVersion, osType); err != nil {
 		return fmt.Errorf("failed to install driver: %w", err)
 	}
@@ -261,30 +503,123 @@ func (d *driverMgr) Build(ctx context.Context) error {
 		}
 	}
 
+	progress.done(ctx)
+	return nil
+}
+
+// checkBuildDiskSpace fails when the driver sources or the inventory filesystem has less than
+// MinBuildDiskBytes free, a build running out of space fails with confusing errors midway
+func (d *driverMgr) checkBuildDiskSpace(ctx context.Context, inventoryPath string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if d.cfg.MinBuildDiskBytes == 0 {
+		return nil
+	}
+	// The inventory directory was just wiped, check its parent which holds the other kernels
+	for _, path := range []string{d.cfg.NvidiaNicDriverPath, filepath.Dir(inventoryPath)} {
+		free, err := d.host.GetFreeDiskSpace(ctx, path)
+		if err != nil {
+			log.V(1).Info("Failed to get free disk space, skipping the check", "path", path, "error", err)
+			// Non-fatal error, continue
+			continue
+		}
+		if free < d.cfg.MinBuildDiskBytes {
+			return fmt.Errorf("%w: %s has %d bytes available, %d bytes required (MIN_BUILD_DISK_BYTES)",
+				ErrInsufficientDiskSpace, path, free, d.cfg.MinBuildDiskBytes)
+		}
+		log.V(1).Info("Enough free disk space for the build", "path", path, "free", free,
+			"required", d.cfg.MinBuildDiskBytes)
+	}
 	return nil
 }
 
+// buildKernelVersion returns the kernel Build is for: TargetKernelVersion when set, the running kernel otherwise
+func (d *driverMgr) buildKernelVersion(ctx context.Context) (string, error) {
+	if d.cfg.TargetKernelVersion != "" {
+		logr.FromContextOrDiscard(ctx).Info("Building for the target kernel instead of the running one, "+
+			"Load still uses the running kernel", "kernel", d.cfg.TargetKernelVersion)
+		return d.cfg.TargetKernelVersion, nil
+	}
+	return d.host.GetKernelVersion(ctx)
+}
+
+// BuildSource is the default implementation of the driver.Interface.
+func (d *driverMgr) BuildSource() string {
+	return d.buildSource
+}
+
+// Build phases reported by buildProgress
+const (
+	phasePrerequisites     = "prerequisites"
+	phaseCheckingInventory = "checking inventory"
+	phaseCompiling         = "compiling"
+	phaseCopyingArtifacts  = "copying artifacts"
+	phaseInstalling        = "installing"
+)
+
+// buildProgress logs the Build phases with a [step/total] counter, so the long compile
+// phase can be told apart from a hang
+type buildProgress struct {
+	step  int
+	total int
+	// buildSteps are the phases skipped when the inventory already holds the packages
+	buildSteps int
+	start      time.Time
+}
+
+// newBuildProgress returns the progress of a Build which compiles the driver. With dtk the
+// prerequisites aren't installed and the DTK sidecar copies the artifacts.
+func newBuildProgress(dtk bool) *buildProgress {
+	if dtk {
+		// checking inventory, compiling and installing
+		return &buildProgress{total: 3, buildSteps: 1, start: time.Now()}
+	}
+	return &buildProgress{total: 5, buildSteps: 2, start: time.Now()}
+}
+
+// phase logs the start of the next phase
+func (p *buildProgress) phase(ctx context.Context, name string) {
+	p.step++
+	logr.FromContextOrDiscard(ctx).Info(fmt.Sprintf("[%d/%d] Build phase: %s", p.step, p.total, name))
+}
+
+// skipBuild drops the compile phases from the total on an inventory cache hit
+func (p *buildProgress) skipBuild() {
+	p.total -= p.buildSteps
+}
+
+// done logs the number of phases run and the total duration
+func (p *buildProgress) done(ctx context.Context) {
+	logr.FromContextOrDiscard(ctx).Info(fmt.Sprintf("[%d/%d] Build finished", p.step, p.total),
+		"elapsed", time.Since(p.start).Round(time.Second).String())
+}
+
 // Load is the default implementation of the driver.Interface.
 func (d *driverMgr) Load(ctx context.Context) (bool, error) {
+	return d.load(ctx, false)
+}
+
+// load loads the candidate driver, Reconcile sets drifted to reload the driver without checking
+// the loaded modules again after Drifted
+func (d *driverMgr) load(ctx context.Context, drifted bool) (bool, error) {
 	if err := d.generateOfedModulesBlacklist(ctx); err != nil {
 		return false, err
 	}
-	defer func() {
-		if err := d.removeOfedModulesBlacklist(ctx); err != nil {
-			log := logr.FromContextOrDiscard(ctx)
-			log.Error(err, "Failed to remove OFED modules blacklist during cleanup")
-		}
-	}()
+	// With PersistBlacklist the file stays on the host until Unload restores the inbox driver
+	if !d.cfg.PersistBlacklist {
+		defer func() {
+			if err := d.removeOfedModulesBlacklist(ctx); err != nil {
+				log := logr.FromContextOrDiscard(ctx)
+				log.Error(err, "Failed to remove OFED modules blacklist during cleanup")
+			}
+		}()
+	}
 
 	log := logr.FromContextOrDiscard(ctx)
 	log.V(1).Info("Loading driver modules")
 
-	// Define modules to check
-	modulesToCheck := []string{moduleMlx5Core, moduleMlx5IB, moduleIBCore}
-
-	// Add NFS RDMA modules if enabled
-	if d.cfg.EnableNfsRdma {
-		modulesToCheck = append(modulesToCheck, "nvme_rdma", "rpcrdma")
+	if err := d.writeModuleOptions(ctx); err != nil {
+		return false, err
 	}
 
 	// Setup DKMS if enabled. Must run before restartDriver so that
@@ -300,40 +635,55 @@ func (d *driverMgr) Load(ctx context.Context) (bool, error) {
 		}
 	}
 
+	// Only the kernel log lines printed after this mark are checked by checkSecureBootLoad
+	kernelLogMark := d.kernelLogMark(ctx)
+
 	// Check if loaded kernel modules match expected versions
-	modulesMatch, err := d.checkLoadedKmodSrcverVsModinfo(ctx, modulesToCheck)
-	if err != nil {
-		return false, fmt.Errorf("failed to check module versions: %w", err)
+	modulesMatch := false
+	if !drifted {
+		var err error
+		if modulesMatch, err = d.loadedModulesMatch(ctx); err != nil {
+			return false, err
+		}
 	}
 
 	if !modulesMatch {
-		log.V(1).Info("Module versions don't match, restarting driver")
-
-		// Restart driver
-		if err := d.restartDriver(ctx); err != nil {
-			return false, fmt.Errorf("failed to restart driver: %w", err)
-		}
-
-		// Mark that a new driver was loaded
-		d.newDriverLoaded = true
-
-		// Load NFS RDMA modules if enabled
-		if d.cfg.EnableNfsRdma {
-			if err := d.loadNfsRdma(ctx); err != nil {
-				log.V(1).Info("Failed to load NFS RDMA modules", "error", err)
-				// Non-fatal error, continue
+		if d.cfg.SelectiveReload && d.reloadDriftedModules(ctx) {
+			d.newDriverLoaded = true
+			if err := d.postReload(ctx); err != nil {
+				return false, err
+			}
+		} else {
+			log.V(1).Info("Module versions don't match, restarting driver")
+			if err := d.reloadDriver(ctx); err != nil {
+				return false, err
 			}
 		}
 	} else {
 		log.V(1).Info("Loaded and candidate drivers are identical, skipping reload")
 	}
 
+	// The srcversion check passes against stale loaded modules when the new ones were rejected
+	if err := d.checkSecureBootLoad(ctx, kernelLogMark); err != nil {
+		return false, err
+	}
+
 	// Print loaded driver version
 	if err := d.printLoadedDriverVersion(ctx); err != nil {
 		log.V(1).Info("Failed to print driver version", "error", err)
 		// Non-fatal error, continue
 	}
 
+	d.checkMlx5CoreSize(ctx)
+
+	if err := d.runPostLoadHook(ctx); err != nil {
+		if d.cfg.PostLoadHookRequired {
+			return false, fmt.Errorf("post-load hook failed: %w", err)
+		}
+		log.Info("[WARN] post-load hook failed", "error", err)
+		// Non-fatal error, continue
+	}
+
 	// Mount rootfs for shared kernel headers
 	if err := d.mountRootfs(ctx); err != nil {
 		return false, fmt.Errorf("failed to mount rootfs: %w", err)
@@ -345,14 +695,215 @@ func (d *driverMgr) Load(ctx context.Context) (bool, error) {
 		// Non-fatal error, continue
 	}
 
+	// Remember the kernel the driver was loaded for, Reconcile compares the host kernel with it
+	if d.cfg.ReconcileInterval > 0 {
+		kernelVersion, err := d.host.GetKernelVersion(ctx)
+		if err != nil {
+			log.V(1).Info("Failed to get kernel version", "error", err)
+			// Non-fatal error, continue
+		}
+		d.loadedKernelVer = kernelVersion
+	}
+
 	log.Info("Driver loaded successfully")
 	return true, nil
 }
 
+// Drifted is the default implementation of the driver.Interface.
+func (d *driverMgr) Drifted(ctx context.Context) (bool, error) {
+	kernelVersion, err := d.host.GetKernelVersion(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get kernel version: %w", err)
+	}
+	if d.loadedKernelVer != "" && d.loadedKernelVer != kernelVersion {
+		return true, nil
+	}
+	modulesMatch, err := d.loadedModulesMatch(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !modulesMatch, nil
+}
+
+// Reconcile is the default implementation of the driver.Interface.
+func (d *driverMgr) Reconcile(ctx context.Context) (bool, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	kernelVersion, err := d.host.GetKernelVersion(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get kernel version: %w", err)
+	}
+	if d.loadedKernelVer != "" && d.loadedKernelVer != kernelVersion {
+		log.Info("Host kernel changed since the driver was loaded", "loaded", d.loadedKernelVer, "current", kernelVersion)
+		if d.containerMode == constants.DriverContainerModeSources {
+			if err := d.Build(ctx); err != nil {
+				return false, fmt.Errorf("failed to rebuild driver for kernel %s: %w", kernelVersion, err)
+			}
+		} else {
+			// The loaded driver is kept and loadedKernelVer isn't updated, the drift is reported on every reconcile
+			return false, fmt.Errorf("%w: loaded for %s, running %s", ErrPrecompiledKernelChanged,
+				d.loadedKernelVer, kernelVersion)
+		}
+	}
+
+	log.Info("Loaded driver drifted from the candidate driver, reloading")
+	return d.load(ctx, true)
+}
+
+// loadedModulesMatch reports whether the loaded driver modules are the candidate ones
+func (d *driverMgr) loadedModulesMatch(ctx context.Context) (bool, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	modulesMatch, err := d.checkLoadedKmodSrcverVsModinfo(ctx, d.driverModules())
+	if err != nil {
+		return false, fmt.Errorf("failed to check module versions: %w", err)
+	}
+
+	if !modulesMatch && d.runningDriverVersionMatches(ctx) {
+		log.Info("Running driver version matches the precompiled driver, skipping reload",
+			"version", d.cfg.NvidiaNicDriverVer)
+		modulesMatch = true
+	}
+
+	return modulesMatch, nil
+}
+
+// coreDriverModules returns the driver modules loaded by the openibd restart
+func coreDriverModules() []string {
+	return []string{moduleMlx5Core, moduleMlx5IB, moduleIBCore}
+}
+
+// driverModules returns the driver modules checked after the install and before a reload,
+// the NVMe and NFS RDMA modules are included when they are built
+func (d *driverMgr) driverModules() []string {
+	modules := coreDriverModules()
+	if d.cfg.EnableNvmeRdma {
+		modules = append(modules, "nvme_rdma")
+	}
+	if d.cfg.EnableNfsRdma {
+		modules = append(modules, "rpcrdma")
+	}
+	return modules
+}
+
+// reloadDriftedModules reloads only the drifted driver modules with modprobe, for SelectiveReload.
+// It returns false when the full driver restart is still needed: a driver module isn't loaded,
+// a drifted module is used by other modules or it can't be unloaded (e.g. it is busy).
+func (d *driverMgr) reloadDriftedModules(ctx context.Context) bool {
+	log := logr.FromContextOrDiscard(ctx)
+
+	loadedModules, err := d.host.LsMod(ctx)
+	if err != nil {
+		log.V(1).Info("Failed to get loaded modules, falling back to driver restart", "error", err)
+		return false
+	}
+
+	var drifted []string
+	for _, module := range d.driverModules() {
+		loaded, exists := loadedModules[module]
+		if !exists {
+			log.V(1).Info("Module not loaded, falling back to driver restart", "module", module)
+			return false
+		}
+		if d.moduleSrcversionMatches(ctx, module) {
+			continue
+		}
+		if len(loaded.UsedBy) > 0 {
+			log.V(1).Info("Drifted module is used by other modules, falling back to driver restart",
+				"module", module, "usedBy", loaded.UsedBy)
+			return false
+		}
+		drifted = append(drifted, module)
+	}
+	if len(drifted) == 0 {
+		return false
+	}
+
+	for _, module := range drifted {
+		if _, stderr, err := d.cmd.RunCommand(ctx, "modprobe", "-r", module); err != nil {
+			log.Info("[WARN] Failed to unload drifted module, falling back to driver restart",
+				"module", module, "error", err, "stderr", stderr)
+			return false
+		}
+		if _, stderr, err := d.cmd.RunCommand(ctx, "modprobe", module); err != nil {
+			log.Info("[WARN] Failed to load drifted module, falling back to driver restart",
+				"module", module, "error", err, "stderr", stderr)
+			return false
+		}
+	}
+
+	log.Info("Reloaded drifted driver modules without driver restart", "modules", drifted)
+	return true
+}
+
+// reloadDriver restarts the driver to load the candidate modules
+func (d *driverMgr) reloadDriver(ctx context.Context) error {
+	// Restart driver
+	if err := d.restartDriver(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrRestartFailed, err)
+	}
+
+	// Mark that a new driver was loaded
+	d.newDriverLoaded = true
+
+	return d.postReload(ctx)
+}
+
+// postReload loads the optional modules after the driver restart or the selective reload of the
+// drifted modules, and verifies the reloaded modules with VerifyReload
+func (d *driverMgr) postReload(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	// Only the core modules and the optional modules that were loaded are verified
+	loaded := coreDriverModules()
+
+	// Load NFS RDMA modules if enabled
+	if d.cfg.EnableNfsRdma {
+		if err := d.loadNfsRdma(ctx); err != nil {
+			log.V(1).Info("Failed to load NFS RDMA modules", "error", err)
+			// Non-fatal error, continue
+		} else {
+			loaded = append(loaded, "rpcrdma")
+		}
+	}
+
+	d.loadPostRestartModules(ctx)
+
+	if d.cfg.VerifyReload {
+		if err := d.verifyReload(ctx, loaded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyReload checks that the given loaded modules match the candidate driver after the driver restart,
+// openibd may succeed while the old modules stay loaded (e.g. a module was in use)
+func (d *driverMgr) verifyReload(ctx context.Context, modules []string) error {
+	modulesMatch, err := d.checkLoadedKmodSrcverVsModinfo(ctx, modules)
+	if err != nil {
+		return fmt.Errorf("failed to verify the reloaded modules: %w", err)
+	}
+	if !modulesMatch {
+		return ErrReloadNotApplied
+	}
+	logr.FromContextOrDiscard(ctx).V(1).Info("Loaded modules match the candidate driver after the restart")
+	return nil
+}
+
 // Unload is the default implementation of the driver.Interface.
 func (d *driverMgr) Unload(ctx context.Context) (bool, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
+	// A persistent blacklist would prevent the inbox driver from being restored
+	if d.cfg.PersistBlacklist {
+		if err := d.removeOfedModulesBlacklist(ctx); err != nil {
+			return false, err
+		}
+	}
+	d.removeModuleOptions(ctx)
+
 	if d.newDriverLoaded {
 		// Check if mlnxofedctl exists
 		if _, err := d.os.Stat("/usr/sbin/mlnxofedctl"); err == nil {
@@ -405,6 +956,13 @@ func (d *driverMgr) Clear(ctx context.Context) error {
 		log.Error(err, "Failed to unmount rootfs")
 	}
 
+	d.restoreEnabledRepos(ctx)
+	d.restoreIfup(ctx)
+	d.restoreResolvConf(ctx)
+	d.removeModuleOptions(ctx)
+
+	// The persistent blacklist is kept, it is only removed by Unload when the inbox driver is restored
+
 	// Remove driver packages temporary directory if not reused or build incomplete
 	isReusable := d.cfg.NvidiaNicDriversInventoryPath != ""
 	shouldCleanup := !isReusable || d.driverBuildIncomplete
@@ -424,7 +982,7 @@ func (d *driverMgr) Clear(ctx context.Context) error {
 			return nil // Non-fatal, skip cleanup
 		}
 
-		if inventoryPath != "" {
+		if inventoryPath != "" && !d.isReadOnlyInventoryPath(inventoryPath) {
 			log.Info("Removing driver packages temporary directory", "path", inventoryPath)
 			if err := d.os.RemoveAll(inventoryPath); err != nil {
 				log.Error(err, "Failed to remove driver inventory")
@@ -435,45 +993,61 @@ func (d *driverMgr) Clear(ctx context.Context) error {
 	return nil
 }
 
-// mountRootfs mounts the shared kernel headers directory for the Mellanox OFED driver container
-func (d *driverMgr) mountRootfs(ctx context.Context) error {
+// setSysMountPropagation makes /sys runbindable and then applies the SysMountPropagation mode,
+// private by default. Nothing is changed with skip, for hosts where /sys is already set up.
+func (d *driverMgr) setSysMountPropagation(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
-	log.Info("Mounting Mellanox OFED driver container shared kernel headers")
+
+	propagation := d.cfg.SysMountPropagation
+	switch propagation {
+	case "":
+		propagation = constants.SysMountPropagationPrivate
+	case constants.SysMountPropagationPrivate, constants.SysMountPropagationSlave, constants.SysMountPropagationShared:
+	case constants.SysMountPropagationSkip:
+		log.V(1).Info("Skipping /sys mount propagation setup")
+		return nil
+	default:
+		return fmt.Errorf("unsupported /sys mount propagation %q", propagation)
+	}
 
 	// Make /sys mount runbindable
-	_, stderr, err := d.cmd.RunCommand(ctx, "mount", "--make-runbindable", "/sys")
+	_, _, err := d.cmd.RunCommand(ctx, "mount", "--make-runbindable", "/sys")
 	if err != nil {
-		return fmt.Errorf("failed to make /sys runbindable: %w, stderr: %s", err, stderr)
+		return fmt.Errorf("failed to make /sys runbindable: %w", err)
 	}
 
-	// Make /sys mount private
-	_, stderr, err = d.cmd.RunCommand(ctx, "mount", "--make-private", "/sys")
+	// Make /sys mount private, slave or shared
+	_, _, err = d.cmd.RunCommand(ctx, "mount", "--make-"+propagation, "/sys")
 	if err != nil {
-		return fmt.Errorf("failed to make /sys private: %w, stderr: %s", err, stderr)
+		return fmt.Errorf("failed to make /sys %s: %w", propagation, err)
 	}
+	return nil
+}
 
-	mountPath := filepath.Join(d.cfg.MlxDriversMount, d.cfg.SharedKernelHeadersDir)
+// mountRootfs mounts the shared kernel headers directory for the Mellanox OFED driver container
+func (d *driverMgr) mountRootfs(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Mounting Mellanox OFED driver container shared kernel headers")
 
-	// A mount at mountPath may already be present from a previous container instance
-	// that terminated without running its cleanup handler (e.g. killed rather than
+	if err := d.setSysMountPropagation(ctx); err != nil {
+		return err
+	}
+
+	mountPath := filepath.Join(d.cfg.MlxDriversMount, d.cfg.SharedKernelHeadersDir)
+
+	// A mount at mountPath may already be present from a previous container instance
+	// that terminated without running its cleanup handler (e.g. killed rather than
 	// gracefully stopped). Such a mount is bound to that old container's filesystem
 	// snapshot, not the driver this process just (re)built, so it must never be
 	// trusted as-is: unmount it (best effort) and always recreate it fresh below,
 	// rather than skipping the mount when one is merely present.
 	stdout, _, err := d.cmd.RunCommand(ctx, "mount", "-l")
-	if err == nil {
-		// Check if mellanox mount exists (excluding tmpfs)
-		lines := strings.Split(stdout, "\n")
-		for _, line := range lines {
-			if strings.Contains(line, "mellanox") && !strings.Contains(line, "tmpfs") {
-				log.V(1).Info("Found existing mount, unmounting before remount to avoid stale content",
-					"mount", d.cfg.MlxDriversMount)
-				if _, umountStderr, umountErr := d.cmd.RunCommand(ctx, "umount", "-l", "-R", mountPath); umountErr != nil {
-					log.V(1).Info("Failed to unmount existing mount, proceeding to remount anyway",
-						"error", umountErr, "stderr", umountStderr)
-				}
-				break
-			}
+	if err == nil && mountTargetExists(stdout, mountPath) {
+		log.V(1).Info("Found existing mount, unmounting before remount to avoid stale content",
+			"mountPath", mountPath)
+		if _, umountStderr, umountErr := d.cmd.RunCommand(ctx, "umount", "-l", "-R", mountPath); umountErr != nil {
+			log.V(1).Info("Failed to unmount existing mount, proceeding to remount anyway",
+				"error", umountErr, "stderr", umountStderr)
 		}
 	}
 
@@ -483,16 +1057,35 @@ func (d *driverMgr) mountRootfs(ctx context.Context) error {
 	}
 
 	// Mount with rbind
-	_, stderr, err = d.cmd.RunCommand(ctx, "mount", "--rbind", d.cfg.SharedKernelHeadersDir, mountPath)
+	_, _, err = d.cmd.RunCommand(ctx, "mount", "--rbind", d.cfg.SharedKernelHeadersDir, mountPath)
 	if err != nil {
-		return fmt.Errorf("failed to rbind mount %s to %s: %w, stderr: %s",
-			d.cfg.SharedKernelHeadersDir, mountPath, err, stderr)
+		return fmt.Errorf("failed to rbind mount %s to %s: %w", d.cfg.SharedKernelHeadersDir, mountPath, err)
 	}
 
 	log.V(1).Info("Successfully mounted shared kernel headers", "mountPath", mountPath)
 	return nil
 }
 
+// mountTargetExists reports whether the mount -l output has a mount other than tmpfs on target.
+// The lines, "<source> on <target> type <fstype> (<options>)", are parsed by field position
+// so that the words between the fields don't matter.
+func mountTargetExists(mountOutput, target string) bool {
+	if target == "" {
+		return false
+	}
+	target = filepath.Clean(target)
+	for _, line := range strings.Split(mountOutput, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		if filepath.Clean(fields[2]) == target && fields[4] != "tmpfs" {
+			return true
+		}
+	}
+	return false
+}
+
 // unmountRootfs unmounts the shared kernel headers directory
 func (d *driverMgr) unmountRootfs(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
@@ -520,9 +1113,9 @@ func (d *driverMgr) unmountRootfs(ctx context.Context) error {
 		log.V(1).Info("Unmounting", "mount", d.cfg.MlxDriversMount)
 
 		// Unmount with lazy unmount and recursive
-		_, stderr, err := d.cmd.RunCommand(ctx, "umount", "-l", "-R", d.cfg.MlxDriversMount)
+		_, _, err := d.cmd.RunCommand(ctx, "umount", "-l", "-R", d.cfg.MlxDriversMount)
 		if err != nil {
-			return fmt.Errorf("failed to unmount %s: %w, stderr: %s", d.cfg.MlxDriversMount, err, stderr)
+			return fmt.Errorf("failed to unmount %s: %w", d.cfg.MlxDriversMount, err)
 		}
 
 		// Remove the directory
@@ -546,6 +1139,12 @@ func (d *driverMgr) cleanupDriverInventory(ctx context.Context) error {
 		return nil
 	}
 
+	// The other versions are kept while a version is pinned, to roll forward without a rebuild
+	if d.cfg.PinnedDriverVer != "" {
+		log.V(1).Info("Driver version is pinned, skipping inventory cleanup", "version", d.cfg.PinnedDriverVer)
+		return nil
+	}
+
 	// Get current kernel version
 	kernelVersion, err := d.host.GetKernelVersion(ctx)
 	if err != nil {
@@ -573,6 +1172,10 @@ func (d *driverMgr) cleanupDriverInventory(ctx context.Context) error {
 
 		// If this is not the current kernel version, delete the entire directory
 		if kernelVerDir != kernelVersion {
+			if d.cfg.InventoryMaxKernels > 0 {
+				// The other kernels are kept up to InventoryMaxKernels, see pruneInventoryKernels
+				continue
+			}
 			kernelVerPath := filepath.Join(d.cfg.NvidiaNicDriversInventoryPath, kernelVerDir)
 			log.V(1).Info("Removing old kernel version directory", "path", kernelVerPath)
 			if err := d.os.RemoveAll(kernelVerPath); err != nil {
@@ -597,10 +1200,12 @@ func (d *driverMgr) cleanupDriverInventory(ctx context.Context) error {
 			foundItems++
 			driverVerItem := driverVerEntry.Name()
 
-			// Keep the current driver version directory, its checksum, and its build config fingerprint
-			if driverVerItem == d.cfg.NvidiaNicDriverVer ||
-				driverVerItem == d.cfg.NvidiaNicDriverVer+".checksum" ||
-				driverVerItem == d.cfg.NvidiaNicDriverVer+".buildconfig" {
+			// Keep the current driver version directory, its checksum, its build config fingerprint
+			// and the detected version it is stored under
+			if driverVerItem == d.driverVersion() ||
+				driverVerItem == d.driverVersion()+".checksum" ||
+				driverVerItem == d.driverVersion()+".buildconfig" ||
+				driverVerItem == d.cfg.NvidiaNicDriverVer+".version" {
 				continue
 			}
 
@@ -628,6 +1233,51 @@ func (d *driverMgr) cleanupDriverInventory(ctx context.Context) error {
 	return nil
 }
 
+// pruneInventoryKernels removes the least recently modified kernel version directories of the driver
+// inventory beyond InventoryMaxKernels, the directory of the current kernel is always kept
+func (d *driverMgr) pruneInventoryKernels(ctx context.Context, kernelVersion string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	entries, err := d.os.ReadDir(d.cfg.NvidiaNicDriversInventoryPath)
+	if err != nil {
+		return fmt.Errorf("failed to list inventory directory: %w", err)
+	}
+
+	type kernelDir struct {
+		path    string
+		modTime time.Time
+	}
+	var others []kernelDir
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == kernelVersion {
+			continue
+		}
+		path := filepath.Join(d.cfg.NvidiaNicDriversInventoryPath, entry.Name())
+		info, err := d.os.Stat(path)
+		if err != nil {
+			log.V(1).Info("Failed to stat kernel version directory", "path", path, "error", err)
+			continue
+		}
+		others = append(others, kernelDir{path: path, modTime: info.ModTime()})
+	}
+
+	// The current kernel takes one of the slots
+	keep := d.cfg.InventoryMaxKernels - 1
+	if len(others) <= keep {
+		return nil
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].modTime.After(others[j].modTime) })
+
+	for _, dir := range others[keep:] {
+		log.Info("Pruning driver inventory of kernel", "path", dir.path, "maxKernels", d.cfg.InventoryMaxKernels)
+		if err := d.os.RemoveAll(dir.path); err != nil {
+			log.V(1).Info("Failed to remove kernel version directory", "path", dir.path, "error", err)
+			// Non-fatal, continue with other directories
+		}
+	}
+	return nil
+}
+
 func (d *driverMgr) prepareGCC(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
 
@@ -649,14 +1299,30 @@ func (d *driverMgr) prepareGCC(ctx context.Context) error {
 		return err
 	}
 	if gccVersion == "" {
-		log.V(1).Info("Could not extract GCC version from /proc/version")
-		return nil
+		if d.cfg.ForceGCCVersion <= 0 {
+			log.V(1).Info("Could not extract GCC version from /proc/version")
+			return nil
+		}
+		majorVersion = d.cfg.ForceGCCVersion
+		gccVersion = strconv.Itoa(majorVersion)
+		log.Info("[WARN] Could not extract GCC version from /proc/version, using FORCE_GCC_VERSION",
+			"major", majorVersion)
 	}
 
 	log.V(1).Info("Kernel compiled with GCC version", "version", gccVersion, "major", majorVersion)
 
+	if d.defaultGCCMajorVersion(ctx) == majorVersion {
+		log.Info("Default GCC already matches the kernel compiler version, skipping GCC setup", "major", majorVersion)
+		return nil
+	}
+
 	// Install and configure GCC based on OS type
-	gccBinary, kernelGCCVer, err := d.installGCCForOS(ctx, osType, majorVersion)
+	var gccBinary, kernelGCCVer string
+	if d.cfg.OfflineBuild {
+		gccBinary, kernelGCCVer, err = d.offlineGCCForOS(ctx, osType, majorVersion)
+	} else {
+		gccBinary, kernelGCCVer, err = d.installGCCForOS(ctx, osType, majorVersion)
+	}
 	if err != nil {
 		return err
 	}
@@ -665,14 +1331,39 @@ func (d *driverMgr) prepareGCC(ctx context.Context) error {
 	return d.setupGCCAlternatives(ctx, gccBinary, kernelGCCVer)
 }
 
+// defaultGCCMajorVersion returns the major version of the default gcc of the container, 0 when it is unknown
+func (d *driverMgr) defaultGCCMajorVersion(ctx context.Context) int {
+	log := logr.FromContextOrDiscard(ctx)
+
+	stdout, _, err := d.cmd.RunCommand(ctx, "gcc", "--version")
+	if err != nil {
+		log.V(1).Info("Failed to get the default GCC version", "error", err)
+		return 0
+	}
+	// e.g. gcc (Ubuntu 11.4.0-1ubuntu1~22.04) 11.4.0
+	firstLine, _, _ := strings.Cut(stdout, "\n")
+	version, err := d.extractGCCVersion(firstLine)
+	if err != nil {
+		log.V(1).Info("Could not parse the default GCC version", "output", firstLine, "error", err)
+		return 0
+	}
+	majorVersion, err := d.extractMajorVersion(version)
+	if err != nil {
+		log.V(1).Info("Could not parse the default GCC major version", "version", version, "error", err)
+		return 0
+	}
+	return majorVersion
+}
+
 // extractGCCInfo extracts GCC version information from /proc/version
 func (d *driverMgr) extractGCCInfo(ctx context.Context) (string, int, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
 	// Read /proc/version to extract GCC version
-	procVersion, err := d.os.ReadFile("/proc/version")
+	procVersionPath := d.procPath("version")
+	procVersion, err := d.os.ReadFile(procVersionPath)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to read /proc/version: %w", err)
+		return "", 0, fmt.Errorf("failed to read %s: %w", procVersionPath, err)
 	}
 
 	log.V(1).Info("Kernel version info", "proc_version", string(procVersion))
@@ -693,6 +1384,35 @@ func (d *driverMgr) extractGCCInfo(ctx context.Context) (string, int, error) {
 	return gccVersion, majorVersion, nil
 }
 
+// offlineGCCForOS returns the GCC matching the kernel for OfflineBuild, it must be installed in the image
+func (d *driverMgr) offlineGCCForOS(ctx context.Context, osType string, majorVersion int) (string, string, error) {
+	switch osType {
+	case constants.OSTypeUbuntu:
+		kernelGCCVer := fmt.Sprintf("gcc-%d", majorVersion)
+		if err := d.verifyPackagesInstalled(ctx, osType, []string{kernelGCCVer}); err != nil {
+			return "", "", err
+		}
+		return "/usr/bin/" + kernelGCCVer, kernelGCCVer, nil
+	case constants.OSTypeSLES:
+		kernelGCCVerBin := fmt.Sprintf("gcc-%d", majorVersion)
+		if err := d.verifyPackagesInstalled(ctx, osType, []string{fmt.Sprintf("gcc%d", majorVersion)}); err != nil {
+			return "", "", err
+		}
+		return "/usr/bin/" + kernelGCCVerBin, kernelGCCVerBin, nil
+	case constants.OSTypeRedHat:
+		kernelGCCVer := fmt.Sprintf("gcc-toolset-%d-gcc", majorVersion)
+		if d.isPackageInstalled(ctx, osType, kernelGCCVer) {
+			return fmt.Sprintf("/opt/rh/gcc-toolset-%d/root/usr/bin/gcc", majorVersion), kernelGCCVer, nil
+		}
+		if err := d.verifyPackagesInstalled(ctx, osType, []string{"gcc"}); err != nil {
+			return "", "", err
+		}
+		return "/usr/bin/gcc", "gcc", nil
+	default:
+		return "", "", fmt.Errorf("%w: %s", ErrUnsupportedOS, osType)
+	}
+}
+
 // installGCCForOS installs GCC package based on OS type
 func (d *driverMgr) installGCCForOS(ctx context.Context, osType string, majorVersion int) (string, string, error) {
 	switch osType {
@@ -703,7 +1423,7 @@ func (d *driverMgr) installGCCForOS(ctx context.Context, osType string, majorVer
 	case constants.OSTypeRedHat:
 		return d.installGCCRedHat(ctx, majorVersion)
 	default:
-		return "", "", fmt.Errorf("unsupported OS type: %s", osType)
+		return "", "", fmt.Errorf("%w: %s", ErrUnsupportedOS, osType)
 	}
 }
 
@@ -713,11 +1433,11 @@ func (d *driverMgr) installGCCUbuntu(ctx context.Context, majorVersion int) (str
 	kernelGCCVer := fmt.Sprintf("gcc-%d", majorVersion)
 
 	log.V(1).Info("Installing GCC for Ubuntu", "package", kernelGCCVer)
-	_, _, err := d.cmd.RunCommand(ctx, "apt-get", "-yq", "update")
+	_, _, err := d.runPackageManager(ctx, "apt-get", "-yq", "update")
 	if err != nil {
 		return "", "", fmt.Errorf("failed to update apt packages: %w", err)
 	}
-	_, _, err = d.cmd.RunCommand(ctx, "apt-get", "-yq", "install", kernelGCCVer)
+	_, _, err = d.runPackageManager(ctx, "apt-get", "-yq", "install", kernelGCCVer)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to install %s: %w", kernelGCCVer, err)
 	}
@@ -733,7 +1453,7 @@ func (d *driverMgr) installGCCSLES(ctx context.Context, majorVersion int) (strin
 	kernelGCCVerBin := fmt.Sprintf("gcc-%d", majorVersion)
 
 	log.V(1).Info("Installing GCC for SLES", "package", kernelGCCVerPackage)
-	_, _, err := d.cmd.RunCommand(ctx, "zypper", "--non-interactive", "install", "--no-recommends", kernelGCCVerPackage)
+	_, _, err := d.runPackageManager(ctx, "zypper", "--non-interactive", "install", "--no-recommends", kernelGCCVerPackage)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to install %s: %w", kernelGCCVerPackage, err)
 	}
@@ -750,12 +1470,12 @@ func (d *driverMgr) installGCCRedHat(ctx context.Context, majorVersion int) (str
 	log.V(1).Info("Checking for gcc-toolset availability", "package", toolsetPackage)
 
 	// Check if gcc-toolset is available
-	_, _, err := d.cmd.RunCommand(ctx, dnfCmd, "list", "available", toolsetPackage)
+	_, _, err := d.runPackageManager(ctx, dnfCmd, "list", "available", toolsetPackage)
 	if err == nil {
 		// gcc-toolset version is available
 		kernelGCCVer := fmt.Sprintf("gcc-toolset-%d-gcc", majorVersion)
 		log.V(1).Info("Installing gcc-toolset for RedHat", "package", toolsetPackage)
-		_, _, err = d.cmd.RunCommand(ctx, dnfCmd, dnfFlagQuiet, dnfFlagYes, "install", toolsetPackage)
+		_, _, err = d.runPackageManager(ctx, dnfCmd, dnfFlagQuiet, dnfFlagYes, "install", toolsetPackage)
 		if err != nil {
 			return "", "", fmt.Errorf("failed to install %s: %w", toolsetPackage, err)
 		}
@@ -766,7 +1486,7 @@ func (d *driverMgr) installGCCRedHat(ctx context.Context, majorVersion int) (str
 	// Fall back to default gcc package
 	log.V(1).Info("gcc-toolset not available, using default gcc package")
 	kernelGCCVer := "gcc"
-	_, _, err = d.cmd.RunCommand(ctx, dnfCmd, dnfFlagQuiet, dnfFlagYes, "install", "gcc")
+	_, _, err = d.runPackageManager(ctx, dnfCmd, dnfFlagQuiet, dnfFlagYes, "install", "gcc")
 	if err != nil {
 		return "", "", fmt.Errorf("failed to install gcc: %w", err)
 	}
@@ -839,60 +1559,150 @@ func (d *driverMgr) generateOfedModulesBlacklist(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
 	log.V(1).Info("Generating OFED modules blacklist")
 
-	// Create the blacklist file
-	file, err := d.os.Create(d.cfg.OfedBlacklistModulesFile)
-	if err != nil {
-		log.Error(err, "Failed to create blacklist file", "file", d.cfg.OfedBlacklistModulesFile)
-		return fmt.Errorf("failed to create blacklist file %s: %w", d.cfg.OfedBlacklistModulesFile, err)
+	blacklistDir := filepath.Dir(d.cfg.OfedBlacklistModulesFile)
+	if err := d.checkDirWritable(blacklistDir); err != nil {
+		return fmt.Errorf("blacklist dir %s is not writable; mount it rw or set OFED_BLACKLIST_MODULES_FILE elsewhere: %w",
+			blacklistDir, err)
+	}
+
+	var existing []string
+	if d.cfg.BlacklistMergeExisting {
+		data, err := d.os.ReadFile(d.cfg.OfedBlacklistModulesFile)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read existing blacklist file %s: %w", d.cfg.OfedBlacklistModulesFile, err)
+		}
+		// Drop entries added by a previous run, they are regenerated below
+		existing = stripManagedBlacklistBlock(string(data))
+	}
+	alreadyBlacklisted := map[string]bool{}
+	for _, line := range existing {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "blacklist" {
+			alreadyBlacklisted[fields[1]] = true
+		}
 	}
-	defer file.Close()
 
 	// Build the entire content first
 	var content strings.Builder
+	var invalidModules []string
+	addModule := func(module string) bool {
+		module, ok := sanitizeKernelModuleName(module)
+		if module == "" {
+			return false
+		}
+		if !ok {
+			log.Info("[WARN] Skipping invalid module name in blacklist", "module", module)
+			invalidModules = append(invalidModules, module)
+			return false
+		}
+		if alreadyBlacklisted[module] {
+			log.V(2).Info("Module is already blacklisted", "module", module)
+			return false
+		}
+		alreadyBlacklisted[module] = true
+		fmt.Fprintf(&content, "blacklist %s\n", module)
+		return true
+	}
+
+	if d.cfg.BlacklistMergeExisting {
+		for _, line := range existing {
+			content.WriteString(line + "\n")
+		}
+		if len(existing) > 0 {
+			content.WriteString("\n")
+		}
+		content.WriteString(blacklistManagedBlockBegin + "\n")
+	}
 	content.WriteString("# blacklist ofed-related modules on host to prevent inbox or host OFED driver loading\n\n")
 
 	// Add blacklist entries for each module
 	for _, module := range d.cfg.OfedBlacklistModules {
-		module = strings.TrimSpace(module)
-		if module == "" {
-			continue
+		if addModule(module) {
+			log.V(2).Info("Added module to blacklist", "module", module)
 		}
-		fmt.Fprintf(&content, "blacklist %s\n", module)
-		log.V(2).Info("Added module to blacklist", "module", module)
 	}
 
 	if d.cfg.UnloadThirdPartyRdmaModules {
 		content.WriteString("\n# blacklist third-party RDMA modules to prevent reload conflicts\n")
 		for _, module := range d.cfg.ThirdPartyRDMAModules {
-			fmt.Fprintf(&content, "blacklist %s\n", module)
-			log.V(2).Info("Added third-party RDMA module to blacklist", "module", module)
+			if addModule(module) {
+				log.V(2).Info("Added third-party RDMA module to blacklist", "module", module)
+			}
 		}
 	}
 
 	if len(d.cfg.Mlx5AuxiliaryModules) > 0 {
 		content.WriteString("\n# blacklist mlx5 auxiliary modules to prevent reload races\n")
 		for _, module := range d.cfg.Mlx5AuxiliaryModules {
-			module = strings.TrimSpace(module)
-			if module == "" {
-				continue
+			if addModule(module) {
+				log.V(2).Info("Added mlx5 auxiliary module to blacklist", "module", module)
 			}
-			fmt.Fprintf(&content, "blacklist %s\n", module)
-			log.V(2).Info("Added mlx5 auxiliary module to blacklist", "module", module)
 		}
 	}
 
+	if d.cfg.StrictBlacklistValidation && len(invalidModules) > 0 {
+		return fmt.Errorf("invalid module names in blacklist: %q", invalidModules)
+	}
+
+	if d.cfg.BlacklistMergeExisting {
+		content.WriteString(blacklistManagedBlockEnd + "\n")
+	}
+
 	// Write all content at once
-	if _, err := file.WriteString(content.String()); err != nil {
+	if err := d.writeFileAtomic(d.cfg.OfedBlacklistModulesFile, []byte(content.String()), 0o644); err != nil {
 		log.Error(err, "Failed to write blacklist content to file")
 		return fmt.Errorf("failed to write blacklist content to file: %w", err)
 	}
 
 	log.Info("Successfully generated OFED modules blacklist", "file", d.cfg.OfedBlacklistModulesFile,
-		"ofedModules", d.cfg.OfedBlacklistModules, "unloadThirdPartyRdma", d.cfg.UnloadThirdPartyRdmaModules)
+		"ofedModules", d.cfg.OfedBlacklistModules, "unloadThirdPartyRdma", d.cfg.UnloadThirdPartyRdmaModules,
+		"mergeExisting", d.cfg.BlacklistMergeExisting)
+	return nil
+}
+
+// writeModuleOptions writes the ModuleOptions as modprobe options lines sorted by module
+func (d *driverMgr) writeModuleOptions(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if len(d.cfg.ModuleOptions) == 0 {
+		return nil
+	}
+
+	modules := make([]string, 0, len(d.cfg.ModuleOptions))
+	for module := range d.cfg.ModuleOptions {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	var content strings.Builder
+	content.WriteString("# module options set by the NVIDIA driver container\n")
+	for _, module := range modules {
+		name, ok := sanitizeKernelModuleName(module)
+		if !ok {
+			return fmt.Errorf("invalid module name %q in module options", module)
+		}
+		fmt.Fprintf(&content, "options %s %s\n", name, strings.TrimSpace(d.cfg.ModuleOptions[module]))
+	}
+
+	if err := d.writeFileAtomic(moduleOptionsFile, []byte(content.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write module options: %w", err)
+	}
+	log.Info("Wrote module options", "file", moduleOptionsFile, "modules", modules)
 	return nil
 }
 
+// removeModuleOptions removes the file written by writeModuleOptions
+func (d *driverMgr) removeModuleOptions(ctx context.Context) {
+	if len(d.cfg.ModuleOptions) == 0 {
+		return
+	}
+	if err := d.os.RemoveAll(moduleOptionsFile); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "Failed to remove module options", "file", moduleOptionsFile)
+		// Non-fatal error, continue
+	}
+}
+
 // removeOfedModulesBlacklist removes the OFED modules blacklist file from the host.
+// In merge mode only the entries added by generateOfedModulesBlacklist are removed.
 // This function is typically called during cleanup or when the blacklist is no longer needed.
 func (d *driverMgr) removeOfedModulesBlacklist(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
@@ -904,6 +1714,25 @@ func (d *driverMgr) removeOfedModulesBlacklist(ctx context.Context) error {
 		return nil
 	}
 
+	if d.cfg.BlacklistMergeExisting {
+		data, err := d.os.ReadFile(d.cfg.OfedBlacklistModulesFile)
+		if err != nil {
+			return fmt.Errorf("failed to read blacklist file %s: %w", d.cfg.OfedBlacklistModulesFile, err)
+		}
+		remaining := stripManagedBlacklistBlock(string(data))
+		if len(remaining) > 0 {
+			if err := d.writeFileAtomic(d.cfg.OfedBlacklistModulesFile,
+				[]byte(strings.Join(remaining, "\n")+"\n"), 0o644); err != nil {
+				return fmt.Errorf("failed to remove managed entries from blacklist file %s: %w",
+					d.cfg.OfedBlacklistModulesFile, err)
+			}
+			log.Info("Successfully removed managed entries from OFED modules blacklist file",
+				"file", d.cfg.OfedBlacklistModulesFile)
+			return nil
+		}
+		// Nothing but our entries in the file, remove it as a whole
+	}
+
 	// Remove the blacklist file
 	if err := d.os.RemoveAll(d.cfg.OfedBlacklistModulesFile); err != nil {
 		log.Error(err, "Failed to remove blacklist file", "file", d.cfg.OfedBlacklistModulesFile)
@@ -914,28 +1743,200 @@ func (d *driverMgr) removeOfedModulesBlacklist(ctx context.Context) error {
 	return nil
 }
 
+// stripManagedBlacklistBlock returns the lines of a blacklist file outside the block
+// added by generateOfedModulesBlacklist, with trailing empty lines trimmed
+func stripManagedBlacklistBlock(content string) []string {
+	var lines []string
+	inBlock := false
+	for _, line := range strings.Split(content, "\n") {
+		switch strings.TrimSpace(line) {
+		case blacklistManagedBlockBegin:
+			inBlock = true
+			continue
+		case blacklistManagedBlockEnd:
+			inBlock = false
+			continue
+		}
+		if !inBlock {
+			lines = append(lines, line)
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// checkDirWritable probes write access to dir by writing and removing an empty file,
+// this detects read-only mounts which the permission bits of dir don't show
+func (d *driverMgr) checkDirWritable(dir string) error {
+	probePath := filepath.Join(dir, writeProbeFileName)
+	if err := d.os.WriteFile(probePath, nil, 0o644); err != nil {
+		return err
+	}
+	// Best effort cleanup of the probe file
+	_ = d.os.RemoveAll(probePath)
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it over path,
+// so readers never observe a partially written file
+func (d *driverMgr) writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	if err := d.os.WriteFile(tmpPath, data, perm); err != nil {
+		return fmt.Errorf("failed to write temp file %s: %w", tmpPath, err)
+	}
+	if err := d.os.Rename(tmpPath, path); err != nil {
+		// Best effort cleanup of the temp file
+		_ = d.os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to rename %s to %s: %w", tmpPath, path, err)
+	}
+	return nil
+}
+
 // currentBuildConfigFingerprint returns a canonical string representing the build-affecting
 // configuration. If any of these values change between builds, the cached inventory must be
 // discarded so that the driver is rebuilt with the new flags.
-func (d *driverMgr) currentBuildConfigFingerprint() string {
-	return fmt.Sprintf("ENABLE_NFSRDMA=%v\nUSE_DKMS=%v\nAPPEND_DRIVER_BUILD_FLAGS=%s",
-		d.cfg.EnableNfsRdma, d.cfg.UseDKMS, d.cfg.AppendDriverBuildFlags)
+// The driver sources fingerprint is included so that a hotfix rebuild of the sources
+// with an unchanged NvidiaNicDriverVer also invalidates the cache.
+func (d *driverMgr) currentBuildConfigFingerprint(ctx context.Context) string {
+	return fmt.Sprintf("ENABLE_NFSRDMA=%v\nENABLE_NVME_RDMA=%v\nUSE_DKMS=%v\nENABLE_KMP=%v\nWITH_MLNX_TOOLS=%v\n"+
+		"APPEND_DRIVER_BUILD_FLAGS=%s\nSOURCE_FINGERPRINT=%s",
+		d.cfg.EnableNfsRdma, d.cfg.EnableNvmeRdma, d.cfg.UseDKMS, d.cfg.EnableKMP, d.cfg.WithMlnxTools,
+		d.cfg.AppendDriverBuildFlags, d.currentSourceFingerprint(ctx))
+}
+
+// currentSourceFingerprint returns a SHA-256 of install.pl and the package file names, sizes and
+// modification times shipped in the SOURCES and SRPMS directories of the driver sources.
+// The result is computed once and cached for the lifetime of the driver manager.
+func (d *driverMgr) currentSourceFingerprint(ctx context.Context) string {
+	if d.sourceFingerprint != "" {
+		return d.sourceFingerprint
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	hash := sha256.New()
+	installScript := filepath.Join(d.cfg.NvidiaNicDriverPath, "install.pl")
+	content, err := d.os.ReadFile(installScript)
+	if err != nil {
+		log.V(1).Info("Failed to read driver install script for source fingerprint", "path", installScript, "error", err)
+		// Non-fatal error, continue
+	}
+	hash.Write(content)
+
+	for _, dir := range []string{"SOURCES", "SRPMS"} {
+		entries, err := d.os.ReadDir(filepath.Join(d.cfg.NvidiaNicDriverPath, dir))
+		if err != nil {
+			continue
+		}
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			// A respun tarball keeps its name, its size and modification time tell it apart
+			name := entry.Name()
+			if info, err := entry.Info(); err == nil && info != nil {
+				name = fmt.Sprintf("%s:%d:%d", name, info.Size(), info.ModTime().UnixNano())
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(hash, "\n%s:%s", dir, strings.Join(names, ","))
+	}
+
+	d.sourceFingerprint = hex.EncodeToString(hash.Sum(nil))
+	log.V(1).Info("Computed driver source fingerprint", "fingerprint", d.sourceFingerprint)
+	return d.sourceFingerprint
 }
 
 // checkDriverInventory checks if driver inventory exists and validates checksums
 func (d *driverMgr) checkDriverInventory(ctx context.Context, kernelVersion string) (bool, string, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
+	if d.cfg.PinnedDriverVer != "" {
+		return d.checkPinnedDriverInventory(ctx, kernelVersion)
+	}
+
+	if d.cfg.ForceRebuild {
+		inventoryPath, _, _ := d.inventoryPaths(ctx, d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, d.driverVersion())
+		if d.cfg.NvidiaNicDriversInventoryPath == "" {
+			inventoryPath = fmt.Sprintf("/tmp/nvidia_nic_driver_%s", time.Now().Format("02-01-2006_15-04-05"))
+		}
+		log.Info("[WARN] Forced driver rebuild requested, ignoring driver inventory cache",
+			"kernel", kernelVersion, "inventory", inventoryPath)
+		return true, inventoryPath, nil
+	}
+
+	// Shared read-only caches take precedence; a hit there means no local build is needed
+	for _, root := range d.cfg.ReadOnlyInventoryPaths {
+		if root == "" {
+			continue
+		}
+		shouldBuild, inventoryPath, err := d.checkInventoryRoot(ctx, root, kernelVersion)
+		if err != nil {
+			log.V(1).Info("Failed to check read-only driver inventory, skipping", "root", root, "error", err)
+			// Non-fatal error, continue
+			continue
+		}
+		if !shouldBuild {
+			log.Info("Found driver packages in read-only inventory", "path", inventoryPath)
+			return false, inventoryPath, nil
+		}
+	}
+
 	// If no inventory path is set, always build
 	if d.cfg.NvidiaNicDriversInventoryPath == "" {
 		inventoryPath := fmt.Sprintf("/tmp/nvidia_nic_driver_%s", time.Now().Format("02-01-2006_15-04-05"))
 		return true, inventoryPath, nil
 	}
 
-	// Check if inventory directory exists
-	inventoryPath := filepath.Join(d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, d.cfg.NvidiaNicDriverVer)
-	checksumPath := filepath.Join(d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, d.cfg.NvidiaNicDriverVer+".checksum")
-	buildConfigPath := filepath.Join(d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, d.cfg.NvidiaNicDriverVer+".buildconfig")
+	return d.checkInventoryRoot(ctx, d.cfg.NvidiaNicDriversInventoryPath, kernelVersion)
+}
+
+// checkPinnedDriverInventory returns the inventory packages of PinnedDriverVer, which are never rebuilt
+func (d *driverMgr) checkPinnedDriverInventory(ctx context.Context, kernelVersion string) (bool, string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if d.cfg.NvidiaNicDriversInventoryPath == "" {
+		return false, "", fmt.Errorf("%w: NVIDIA_NIC_DRIVERS_INVENTORY_PATH is not set", ErrPinnedDriverNotFound)
+	}
+
+	inventoryPath, _, _ := d.inventoryPaths(ctx, d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, d.cfg.PinnedDriverVer)
+	if _, err := d.os.Stat(inventoryPath); err != nil {
+		return false, "", fmt.Errorf("%w: version %s for kernel %s, %s: %w",
+			ErrPinnedDriverNotFound, d.cfg.PinnedDriverVer, kernelVersion, inventoryPath, err)
+	}
+
+	log.Info("Using pinned driver version from inventory", "version", d.cfg.PinnedDriverVer,
+		"kernel", kernelVersion, "path", inventoryPath)
+	return false, inventoryPath, nil
+}
+
+// isReadOnlyInventoryPath returns true if path lives in one of the shared read-only inventories
+func (d *driverMgr) isReadOnlyInventoryPath(path string) bool {
+	for _, root := range d.cfg.ReadOnlyInventoryPaths {
+		if root == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInventoryRoot validates the <kver>/<driverVer> packages of a single inventory root
+// against the stored checksum and build config fingerprint.
+func (d *driverMgr) checkInventoryRoot(ctx context.Context, root, kernelVersion string) (bool, string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	version := d.driverVersion()
+	if d.cfg.AutoDetectDriverVersion {
+		// A previous build may have stored the packages under their detected version
+		if detected, err := d.os.ReadFile(d.driverVersionAliasPath(root, kernelVersion)); err == nil {
+			version = strings.TrimSpace(string(detected))
+			d.detectedDriverVer = version
+		}
+	}
+	inventoryPath, checksumPath, buildConfigPath := d.inventoryPaths(ctx, root, kernelVersion, version)
 
 	// Check if inventory directory exists
 	if _, err := d.os.Stat(inventoryPath); os.IsNotExist(err) {
@@ -993,7 +1994,7 @@ func (d *driverMgr) checkDriverInventory(ctx context.Context, kernelVersion stri
 		return true, inventoryPath, nil
 	}
 
-	currentConfig := d.currentBuildConfigFingerprint()
+	currentConfig := d.currentBuildConfigFingerprint(ctx)
 	if strings.TrimSpace(string(storedConfig)) != currentConfig {
 		log.Info("Build config has changed since last build, invalidating cache and rebuilding",
 			"stored", strings.TrimSpace(string(storedConfig)),
@@ -1032,16 +2033,134 @@ func (d *driverMgr) installPrerequisitesForOS(ctx context.Context, osType, kerne
 	case constants.OSTypeRedHat, constants.OSTypeOpenShift:
 		return d.installRedHatPrerequisites(ctx, kernelVersion)
 	default:
-		return fmt.Errorf("unsupported OS type: %s", osType)
+		return fmt.Errorf("%w: %s", ErrUnsupportedOS, osType)
 	}
 }
 
+// verifyKernelHeaders checks that the installed kernel headers package is the one of the running kernel,
+// a repository with only a near version would otherwise build the driver for the wrong kernel.
+// The check is skipped when no headers package is installed, the headers may come from the host then.
+func (d *driverMgr) verifyKernelHeaders(ctx context.Context, osType, kernelVersion string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	var installed []string
+	var matches func(version string) bool
+	switch osType {
+	case constants.OSTypeUbuntu:
+		// The package name carries the kernel release, e.g. linux-headers-5.15.0-91-generic
+		stdout, _, err := d.cmd.RunCommand(ctx, "dpkg-query", "-W", "-f=${Package} ${Status}\n", "linux-headers-*")
+		if err != nil {
+			log.V(1).Info("Failed to query installed kernel headers", "error", err)
+			return nil
+		}
+		for _, line := range strings.Split(stdout, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) > 1 && fields[len(fields)-1] == "installed" {
+				installed = append(installed, strings.TrimPrefix(fields[0], "linux-headers-"))
+			}
+		}
+		matches = func(version string) bool { return version == kernelVersion }
+	case constants.OSTypeSLES:
+		// The package release has an extra rebuild counter, e.g. 5.14.21-150500.55.39.1 for 5.14.21-150500.55.39-default
+		cleanedKernelVer := strings.TrimSuffix(kernelVersion, "-default")
+		installed = d.installedRPMVersions(ctx, "kernel-default-devel", "%{VERSION}-%{RELEASE}\n")
+		matches = func(version string) bool {
+			return version == cleanedKernelVer || strings.HasPrefix(version, cleanedKernelVer+".")
+		}
+	case constants.OSTypeRedHat, constants.OSTypeOpenShift:
+		// The RT and 64k kernels have a +rt / +64k suffix that isn't part of the package version
+		_, _, rtHpSubstr, _ := d.analyzeKernelType(ctx, kernelVersion, &host.RedhatVersionInfo{})
+		required := strings.TrimSuffix(strings.TrimSuffix(kernelVersion, "+rt"), "+64k")
+		installed = d.installedRPMVersions(ctx, "kernel-"+rtHpSubstr+"devel", "%{VERSION}-%{RELEASE}.%{ARCH}\n")
+		matches = func(version string) bool { return version == required }
+	default:
+		return nil
+	}
+
+	if len(installed) == 0 {
+		log.V(1).Info("No kernel headers package installed, skipping the version check", "kernel", kernelVersion)
+		return nil
+	}
+	if slices.ContainsFunc(installed, matches) {
+		log.V(1).Info("Installed kernel headers match the running kernel", "kernel", kernelVersion)
+		return nil
+	}
+	return fmt.Errorf("%w: installed %s, required %s", ErrKernelHeadersMismatch,
+		strings.Join(installed, ", "), kernelVersion)
+}
+
+// installedRPMVersions returns the versions of the installed pkg formatted with queryFormat
+func (d *driverMgr) installedRPMVersions(ctx context.Context, pkg, queryFormat string) []string {
+	stdout, _, err := d.cmd.RunCommand(ctx, "rpm", "-q", "--qf", queryFormat, pkg)
+	if err != nil {
+		// rpm -q fails when the package isn't installed
+		logr.FromContextOrDiscard(ctx).V(1).Info("Failed to query installed package", "package", pkg, "error", err)
+		return nil
+	}
+	return strings.Fields(stdout)
+}
+
+// verifyOfflinePrerequisites checks that the packages installPrerequisitesForOS and
+// installExtraBuildPackages would install are already present, for OfflineBuild
+func (d *driverMgr) verifyOfflinePrerequisites(ctx context.Context, osType, kernelVersion string) error {
+	var packages []string
+	switch osType {
+	case constants.OSTypeUbuntu:
+		packages = []string{"pkg-config", "linux-headers-" + kernelVersion}
+	case constants.OSTypeSLES:
+		packages = []string{"kernel-default-devel-" + strings.TrimSuffix(kernelVersion, "-default")}
+	case constants.OSTypeRedHat, constants.OSTypeOpenShift:
+		_, kVer, rtHpSubstr, _ := d.analyzeKernelType(ctx, kernelVersion, &host.RedhatVersionInfo{})
+		packages = []string{"kernel-" + rtHpSubstr + "devel-" + kVer}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedOS, osType)
+	}
+	packages = append(packages, d.cfg.ExtraBuildPackages...)
+
+	return d.verifyPackagesInstalled(ctx, osType, packages)
+}
+
+// verifyPackagesInstalled returns ErrMissingPackage for the first of packages that isn't installed
+func (d *driverMgr) verifyPackagesInstalled(ctx context.Context, osType string, packages []string) error {
+	for _, pkg := range packages {
+		if !d.isPackageInstalled(ctx, osType, pkg) {
+			return fmt.Errorf("%w %s; offline build cannot fetch it", ErrMissingPackage, pkg)
+		}
+	}
+	return nil
+}
+
+// isPackageInstalled queries the package database of the OS type for an installed package
+func (d *driverMgr) isPackageInstalled(ctx context.Context, osType, pkg string) bool {
+	log := logr.FromContextOrDiscard(ctx)
+
+	var err error
+	if osType == constants.OSTypeUbuntu {
+		_, _, err = d.cmd.RunCommand(ctx, "dpkg", "-s", pkg)
+	} else {
+		_, _, err = d.cmd.RunCommand(ctx, "rpm", "-q", pkg)
+	}
+	if err != nil {
+		log.V(1).Info("Package is not installed", "package", pkg, "error", err)
+		return false
+	}
+	return true
+}
+
 // installUbuntuPrerequisites installs Ubuntu-specific prerequisites
 func (d *driverMgr) installUbuntuPrerequisites(ctx context.Context, kernelVersion string) error {
 	log := logr.FromContextOrDiscard(ctx)
 
 	log.V(1).Info("Installing Ubuntu prerequisites", "kernel", kernelVersion)
 
+	if localPkgs := d.findLocalKernelPackages(ctx, ".deb", kernelVersion); len(localPkgs) > 0 {
+		command, args, _ := packageInstallCommand(constants.OSTypeUbuntu, localPkgs)
+		if _, _, err := d.runPackageManager(ctx, command, args...); err != nil {
+			return fmt.Errorf("failed to install local kernel headers packages: %w", err)
+		}
+		return nil
+	}
+
 	// Check if this is an RT (realtime) kernel
 	if strings.Contains(kernelVersion, "realtime") {
 		log.V(1).Info("RT kernel identified, copying APT configuration from host")
@@ -1054,13 +2173,18 @@ func (d *driverMgr) installUbuntuPrerequisites(ctx context.Context, kernelVersio
 	}
 
 	// Update package list
-	_, _, err := d.cmd.RunCommand(ctx, "apt-get", "update")
+	_, _, err := d.runPackageManager(ctx, "apt-get", "update")
 	if err != nil {
 		return fmt.Errorf("failed to update apt packages: %w", err)
 	}
 
+	headersPkg, err := d.kernelHeaderPackage(ctx, ubuntuKernelHeaderPackageTemplate, kernelVersion, ubuntuKernelFlavor(kernelVersion))
+	if err != nil {
+		return err
+	}
+
 	// Install pkg-config and kernel headers
-	_, _, err = d.cmd.RunCommand(ctx, "apt-get", "-yq", "install", "pkg-config", "linux-headers-"+kernelVersion)
+	_, _, err = d.runPackageManager(ctx, "apt-get", "-yq", "install", "pkg-config", headersPkg)
 	if err != nil {
 		return fmt.Errorf("failed to install Ubuntu prerequisites: %w", err)
 	}
@@ -1074,11 +2198,29 @@ func (d *driverMgr) installSLESPrerequisites(ctx context.Context, kernelVersion
 
 	log.V(1).Info("Installing SLES prerequisites", "kernel", kernelVersion)
 
-	// Clean kernel version for SLES
-	cleanedKernelVer := strings.TrimSuffix(kernelVersion, "-default")
+	// Clean kernel version for SLES, the devel package is named after the flavor
+	flavor := slesKernelFlavor(kernelVersion)
+	cleanedKernelVer := strings.TrimSuffix(kernelVersion, "-"+flavor)
+
+	if localPkgs := d.findLocalKernelPackages(ctx, ".rpm", cleanedKernelVer); len(localPkgs) > 0 {
+		command, args, _ := packageInstallCommand(constants.OSTypeSLES, localPkgs)
+		if _, _, err := d.runPackageManager(ctx, command, args...); err != nil {
+			return fmt.Errorf("failed to install local kernel devel packages: %w", err)
+		}
+		return nil
+	}
+
+	if err := d.setupSLESSpecialKernelRepos(ctx, kernelVersion, flavor); err != nil {
+		return fmt.Errorf("failed to setup special kernel repositories: %w", err)
+	}
+
+	develPkg, err := d.kernelHeaderPackage(ctx, slesKernelHeaderPackageTemplate, cleanedKernelVer, flavor)
+	if err != nil {
+		return err
+	}
 
 	// Install kernel development package
-	_, _, err := d.cmd.RunCommand(ctx, "zypper", "--non-interactive", "install", "--no-recommends", "kernel-default-devel="+cleanedKernelVer)
+	_, _, err = d.runPackageManager(ctx, "zypper", "--non-interactive", "install", "--no-recommends", develPkg)
 	if err != nil {
 		return fmt.Errorf("failed to install SLES prerequisites: %w", err)
 	}
@@ -1086,12 +2228,186 @@ func (d *driverMgr) installSLESPrerequisites(ctx context.Context, kernelVersion
 	return nil
 }
 
+// kernelHeaderPackageData is the input of KernelHeaderPackageTemplate
+type kernelHeaderPackageData struct {
+	KernelVersion string
+	Arch          string
+	// Flavor is the kernel flavor, e.g. generic on Ubuntu, default on SLES, rt or 64k on RedHat (empty for the standard kernel)
+	Flavor string
+}
+
+// kernelHeaderPackage returns the kernel headers package to install, rendered from KernelHeaderPackageTemplate
+// when set, from defaultTemplate of the OS otherwise
+func (d *driverMgr) kernelHeaderPackage(ctx context.Context, defaultTemplate, kernelVersion, flavor string) (string, error) {
+	data := kernelHeaderPackageData{KernelVersion: kernelVersion, Flavor: flavor}
+	tmplText := defaultTemplate
+	if d.cfg.KernelHeaderPackageTemplate != "" {
+		tmplText = d.cfg.KernelHeaderPackageTemplate
+		// The default templates don't use the architecture, spare them the uname call
+		data.Arch = d.getArchitecture(ctx)
+	}
+
+	tmpl, err := template.New("kernel-header-package").Option("missingkey=error").Parse(tmplText)
+	if err != nil {
+		return "", fmt.Errorf("invalid kernel header package template %q: %w", tmplText, err)
+	}
+	var pkg strings.Builder
+	if err := tmpl.Execute(&pkg, data); err != nil {
+		return "", fmt.Errorf("failed to render kernel header package template %q: %w", tmplText, err)
+	}
+	return strings.TrimSpace(pkg.String()), nil
+}
+
+// ubuntuKernelFlavor returns the flavor suffix of an Ubuntu kernel version, e.g. generic for 5.15.0-91-generic
+func ubuntuKernelFlavor(kernelVersion string) string {
+	if i := strings.LastIndex(kernelVersion, "-"); i >= 0 {
+		return kernelVersion[i+1:]
+	}
+	return ""
+}
+
+// slesKernelFlavor returns the flavor suffix of a SLES kernel version, e.g. azure for 5.14.21-150500.33.3-azure,
+// default when the version has no flavor suffix
+func slesKernelFlavor(kernelVersion string) string {
+	if i := strings.LastIndex(kernelVersion, "-"); i >= 0 {
+		// The release before the flavor is made of digits and dots only
+		if flavor := kernelVersion[i+1:]; strings.Trim(flavor, "0123456789.") != "" {
+			return flavor
+		}
+	}
+	return "default"
+}
+
+// runPackageManager runs an apt-get, dnf or zypper command. With PkgManagerLockWait set, apt-get
+// waits for the dpkg lock itself and dnf/zypper are retried while another process holds their lock,
+// so concurrent host activity (e.g. unattended-upgrades) doesn't fail the command.
+func (d *driverMgr) runPackageManager(ctx context.Context, command string, args ...string) (string, string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	wait := d.cfg.PkgManagerLockWait
+	if wait <= 0 {
+		return d.cmd.RunCommand(ctx, command, args...)
+	}
+	if command == "apt-get" {
+		lockTimeout := fmt.Sprintf("DPkg::Lock::Timeout=%d", int(wait.Seconds()))
+		return d.cmd.RunCommand(ctx, command, append([]string{"-o", lockTimeout}, args...)...)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		stdout, stderr, err := d.cmd.RunCommand(ctx, command, args...)
+		if err == nil || !isPackageManagerLocked(stdout+stderr) || time.Now().After(deadline) {
+			return stdout, stderr, err
+		}
+		log.Info("Package manager is locked by another process, retrying",
+			"command", command, "retry_in", pkgManagerLockRetryInterval)
+		select {
+		case <-ctx.Done():
+			return stdout, stderr, ctx.Err()
+		case <-time.After(pkgManagerLockRetryInterval):
+		}
+	}
+}
+
+// isPackageManagerLocked checks the dnf/zypper output for a failure to acquire the package manager lock
+func isPackageManagerLocked(output string) bool {
+	output = strings.ToLower(output)
+	return strings.Contains(output, "system management is locked") || // zypper
+		strings.Contains(output, "waiting for process with pid") || // dnf
+		strings.Contains(output, "failed to obtain the transaction lock") // dnf5
+}
+
+// packageInstallCommand returns the package manager command and arguments that install packages
+// on the OS type, ok is false for unsupported OS
+func packageInstallCommand(osType string, packages []string) (command string, args []string, ok bool) {
+	switch osType {
+	case constants.OSTypeUbuntu:
+		return "apt-get", append([]string{"-yq", "install"}, packages...), true
+	case constants.OSTypeSLES:
+		return "zypper", append([]string{"--non-interactive", "install", "--no-recommends"}, packages...), true
+	case constants.OSTypeRedHat, constants.OSTypeOpenShift:
+		return dnfCmd, append([]string{dnfFlagQuiet, dnfFlagYes, "install"}, packages...), true
+	default:
+		return "", nil, false
+	}
+}
+
+// installExtraBuildPackages installs the user-specified ExtraBuildPackages needed to build
+// the driver for custom kernels, on top of the standard prerequisites
+func (d *driverMgr) installExtraBuildPackages(ctx context.Context, osType string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if len(d.cfg.ExtraBuildPackages) == 0 {
+		return nil
+	}
+
+	command, args, ok := packageInstallCommand(osType, d.cfg.ExtraBuildPackages)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedOS, osType)
+	}
+
+	log.Info("Installing extra build packages", "os", osType, "packages", d.cfg.ExtraBuildPackages)
+	if _, _, err := d.runPackageManager(ctx, command, args...); err != nil {
+		return fmt.Errorf("failed to install %s: %w", strings.Join(d.cfg.ExtraBuildPackages, " "), err)
+	}
+
+	return nil
+}
+
+// findLocalKernelPackages returns the packages with the given extension from LocalKernelPackagesDir.
+// It returns nil (install from repos) when the dir is not configured, can't be read or
+// has no package matching kernelVersion.
+func (d *driverMgr) findLocalKernelPackages(ctx context.Context, ext, kernelVersion string) []string {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if d.cfg.LocalKernelPackagesDir == "" {
+		return nil
+	}
+
+	entries, err := d.os.ReadDir(d.cfg.LocalKernelPackagesDir)
+	if err != nil {
+		log.Info("[WARN] failed to read local kernel packages dir, falling back to repos",
+			"path", d.cfg.LocalKernelPackagesDir, "error", err)
+		return nil
+	}
+
+	var packages []string
+	matched := false
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ext) {
+			continue
+		}
+		packages = append(packages, filepath.Join(d.cfg.LocalKernelPackagesDir, entry.Name()))
+		if strings.Contains(entry.Name(), kernelVersion) {
+			matched = true
+		}
+	}
+	if !matched {
+		log.Info("[WARN] no local kernel package matches the running kernel, falling back to repos",
+			"path", d.cfg.LocalKernelPackagesDir, "kernel", kernelVersion)
+		return nil
+	}
+
+	log.Info("Installing kernel packages from local dir", "path", d.cfg.LocalKernelPackagesDir, "packages", packages)
+	return packages
+}
+
 // installRedHatPrerequisites installs RedHat-specific prerequisites
 func (d *driverMgr) installRedHatPrerequisites(ctx context.Context, kernelVersion string) error {
 	log := logr.FromContextOrDiscard(ctx)
 
 	log.V(1).Info("Installing RedHat prerequisites", "kernel", kernelVersion)
 
+	// Repos are not set up when kernel packages are installed from local files, the remaining
+	// build dependencies are expected to be present in the image
+	if localPkgs := d.findLocalKernelPackages(ctx, ".rpm", kernelVersion); len(localPkgs) > 0 {
+		command, args, _ := packageInstallCommand(constants.OSTypeRedHat, localPkgs)
+		if _, _, err := d.runPackageManager(ctx, command, args...); err != nil {
+			return fmt.Errorf("failed to install local kernel packages: %w", err)
+		}
+		return nil
+	}
+
 	// Get RedHat version information
 	versionInfo, err := d.host.GetRedHatVersionInfo(ctx)
 	if err != nil {
@@ -1125,38 +2441,59 @@ func (d *driverMgr) buildDriverFromSource(ctx context.Context, driverPath, kerne
 
 	log.V(1).Info("Building driver from source", "path", driverPath, "kernel", kernelVersion, "os", osType)
 
-	// Set build flags based on OS type
-	buildFlags := d.getBuildFlagsForOS(osType, kernelVersion)
+	args, err := d.assembleBuildArgs(ctx, driverPath, osType, kernelVersion)
+	if err != nil {
+		return err
+	}
+
+	// Execute the build
+	env := d.buildEnv()
+	log.Info("Running driver build", "command", shellquote.Join(args...), "env", env)
+	stdout, stderr, err := d.cmd.RunCommandWithEnv(ctx, env, args[0], args[1:]...)
+	if d.cfg.BuildLogDir != "" {
+		d.collectBuildLogs(ctx, driverPath)
+	}
+	if err != nil {
+		log.Info("install.pl output tail", "stdout", tailLines(stdout, buildOutputTailLines),
+			"stderr", tailLines(stderr, buildOutputTailLines))
+		return fmt.Errorf("%w from source: %w", ErrBuildFailed, err)
+	}
+
+	log.Info("Driver build completed successfully")
+	return nil
+}
 
+// assembleBuildArgs returns the full argv of the install.pl build of the driver in driverPath,
+// including the nice/ionice wrappers configured for the build
+func (d *driverMgr) assembleBuildArgs(ctx context.Context, driverPath, osType, kernelVersion string) ([]string, error) {
 	// Get package suffix based on OS type
 	pkgSuffix := d.getPackageSuffix(osType)
 
-	// Get additional build flags based on environment variables
-	appendFlags := d.getAppendDriverBuildFlags(osType)
-
-	// Construct install.pl command
-	installScript := filepath.Join(driverPath, "install.pl")
 	args := []string{
-		installScript,
+		filepath.Join(driverPath, "install.pl"),
 		"--without-depcheck",
 		"--kernel", kernelVersion,
 		"--kernel-only",
 		"--build-only",
-		"--with-mlnx-tools",
-		"--without-knem" + pkgSuffix,
-		"--without-iser" + pkgSuffix,
-		"--without-isert" + pkgSuffix,
-		"--without-srp" + pkgSuffix,
-		"--without-kernel-mft" + pkgSuffix,
-		"--without-mlnx-rdma-rxe" + pkgSuffix,
 	}
+	if d.cfg.WithMlnxTools {
+		args = append(args, "--with-mlnx-tools")
+	}
+	args = append(args,
+		"--without-knem"+pkgSuffix,
+		"--without-iser"+pkgSuffix,
+		"--without-isert"+pkgSuffix,
+		"--without-srp"+pkgSuffix,
+		"--without-kernel-mft"+pkgSuffix,
+		"--without-mlnx-rdma-rxe"+pkgSuffix,
+	)
 
 	// Add OS-specific flags
-	args = append(args, buildFlags...)
+	args = append(args, d.getBuildFlagsForOS(osType, kernelVersion)...)
 
 	distroFlags, err := d.getDistroFlagsForOS(ctx, osType)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	args = append(args, distroFlags...)
 
@@ -1167,16 +2504,79 @@ func (d *driverMgr) buildDriverFromSource(ctx context.Context, driverPath, kerne
 	}
 
 	// Add additional flags based on environment variables
-	args = append(args, appendFlags...)
+	args = append(args, d.getAppendDriverBuildFlags(osType)...)
 
-	// Execute the build
-	_, _, err = d.cmd.RunCommand(ctx, args[0], args[1:]...)
+	return d.withBuildPriority(args), nil
+}
+
+// buildEnv returns the environment of the install.pl build, MAKEFLAGS runs BuildJobs make jobs,
+// the number of CPUs by default, unless MAKEFLAGS is set in CommandEnv
+func (d *driverMgr) buildEnv() map[string]string {
+	if _, ok := d.cfg.CommandEnv["MAKEFLAGS"]; ok {
+		return nil
+	}
+	jobs := d.cfg.BuildJobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	return map[string]string{"MAKEFLAGS": "-j" + strconv.Itoa(jobs)}
+}
+
+// withBuildPriority prepends the nice/ionice wrappers configured for the build to the command args
+func (d *driverMgr) withBuildPriority(args []string) []string {
+	var wrapper []string
+	if d.cfg.BuildNiceness != 0 {
+		wrapper = append(wrapper, "nice", "-n", strconv.Itoa(d.cfg.BuildNiceness))
+	}
+	if d.cfg.BuildIoniceClass != 0 {
+		wrapper = append(wrapper, "ionice", "-c", strconv.Itoa(d.cfg.BuildIoniceClass))
+	}
+	if len(wrapper) == 0 {
+		return args
+	}
+	return append(wrapper, args...)
+}
+
+// collectBuildLogs copies the *.log files written by install.pl in driverPath to BuildLogDir.
+// This is best-effort, errors are only logged.
+func (d *driverMgr) collectBuildLogs(ctx context.Context, driverPath string) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	entries, err := d.os.ReadDir(driverPath)
 	if err != nil {
-		return fmt.Errorf("failed to build driver from source: %w", err)
+		log.V(1).Info("Failed to list build logs", "path", driverPath, "error", err)
+		return
+	}
+	if err := d.os.MkdirAll(d.cfg.BuildLogDir, 0o755); err != nil {
+		log.V(1).Info("Failed to create build log dir", "path", d.cfg.BuildLogDir, "error", err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".log") {
+			continue
+		}
+		src := filepath.Join(driverPath, entry.Name())
+		data, err := d.os.ReadFile(src)
+		if err != nil {
+			log.V(1).Info("Failed to read build log", "path", src, "error", err)
+			continue
+		}
+		dst := filepath.Join(d.cfg.BuildLogDir, entry.Name())
+		if err := d.os.WriteFile(dst, data, 0o644); err != nil {
+			log.V(1).Info("Failed to copy build log", "src", src, "dst", dst, "error", err)
+			continue
+		}
+		log.V(1).Info("Copied build log", "src", src, "dst", dst)
 	}
+}
 
-	log.Info("Driver build completed successfully")
-	return nil
+// tailLines returns the last n lines of s
+func tailLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
 }
 
 // getBuildFlagsForOS returns OS-specific build flags
@@ -1191,8 +2591,10 @@ func (d *driverMgr) getBuildFlagsForOS(osType, kernelVersion string) []string {
 		}
 		return flags
 	case constants.OSTypeSLES:
-		flags := []string{
-			flagDisableKMP,
+		flags := []string{}
+		// KMP packages are only built on request, for SUSE compliance
+		if !d.cfg.EnableKMP {
+			flags = append(flags, flagDisableKMP)
 		}
 		// Conditionally add --without-dkms based on config (must come before --kernel-sources)
 		if !d.cfg.UseDKMS {
@@ -1203,7 +2605,10 @@ func (d *driverMgr) getBuildFlagsForOS(osType, kernelVersion string) []string {
 		)
 		return flags
 	case constants.OSTypeRedHat:
-		flags := []string{flagDisableKMP}
+		flags := []string{}
+		if !d.cfg.EnableKMP {
+			flags = append(flags, flagDisableKMP)
+		}
 		// Conditionally add --without-dkms based on config
 		if !d.cfg.UseDKMS {
 			flags = append(flags, "--without-dkms")
@@ -1251,13 +2656,19 @@ func (d *driverMgr) copyBuildArtifacts(ctx context.Context, driverPath, inventor
 		sourcePath = filepath.Join(driverPath, "RPMS", "*", arch, "*.rpm")
 		packageType = "rpm"
 	default:
-		return fmt.Errorf("unsupported OS type for artifact copying: %s", osType)
+		return fmt.Errorf("%w for artifact copying: %s", ErrUnsupportedOS, osType)
 	}
 
 	log.V(1).Info("Constructed source path", "sourcePath", sourcePath, "packageType", packageType)
 
 	// Copy packages to inventory directory using shell to expand wildcards
 	cpCmd := fmt.Sprintf("cp %s %s/", sourcePath, inventoryPath)
+	if packageType == "rpm" && d.cfg.EnableKMP {
+		// The KMP packages (<name>-kmp-<flavor> on SLES, kmod-<name> on RedHat) may be written
+		// outside of the per-distro dir, so take every RPM of the arch found under RPMS
+		cpCmd = fmt.Sprintf("find %s -path '*/%s/*.rpm' -exec cp {} %s/ +",
+			filepath.Join(driverPath, "RPMS"), arch, inventoryPath)
+	}
 	log.V(1).Info("Executing copy command", "command", cpCmd)
 
 	// Debug: List source directory to see what files exist
@@ -1268,41 +2679,128 @@ func (d *driverMgr) copyBuildArtifacts(ctx context.Context, driverPath, inventor
 		log.V(1).Info("Failed to list source directory", "error", lsErr)
 	}
 
-	// Debug: Try to find files matching the pattern
-	findCmd := fmt.Sprintf("find %s -name '*.deb' 2>/dev/null || echo 'No .deb files found'", filepath.Join(driverPath, "DEBS"))
-	log.V(1).Info("Searching for .deb files", "command", findCmd)
-	_, findOutput, findErr := d.cmd.RunCommand(ctx, "sh", "-c", findCmd)
-	if findErr != nil {
-		log.V(1).Info("Failed to search for .deb files", "error", findErr)
-	} else {
-		log.V(1).Info("Found .deb files", "output", findOutput)
+	// Debug: Try to find files matching the pattern
+	findCmd := fmt.Sprintf("find %s -name '*.deb' 2>/dev/null || echo 'No .deb files found'", filepath.Join(driverPath, "DEBS"))
+	log.V(1).Info("Searching for .deb files", "command", findCmd)
+	_, findOutput, findErr := d.cmd.RunCommand(ctx, "sh", "-c", findCmd)
+	if findErr != nil {
+		log.V(1).Info("Failed to search for .deb files", "error", findErr)
+	} else {
+		log.V(1).Info("Found .deb files", "output", findOutput)
+	}
+
+	// Debug: Check if destination directory exists
+	destExistsCmd := fmt.Sprintf("ls -la %s", inventoryPath)
+	log.V(1).Info("Checking destination directory", "command", destExistsCmd)
+	_, _, destErr := d.cmd.RunCommand(ctx, "sh", "-c", destExistsCmd)
+	if destErr != nil {
+		log.V(1).Info("Destination directory check failed", "error", destErr)
+	}
+
+	_, _, err := d.cmd.RunCommand(ctx, "sh", "-c", cpCmd)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s packages to inventory: %w", packageType, err)
+	}
+
+	log.V(1).Info("Build artifacts copied successfully", "type", packageType)
+	return nil
+}
+
+// exportPackagesTarball writes the packages of the inventory dir and a build-info file describing
+// the build into the gzipped tarball at ExportPackagesTarball, so that CI can publish them as one artifact
+func (d *driverMgr) exportPackagesTarball(ctx context.Context, inventoryPath, kernelVersion, osType string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	entries, err := d.os.ReadDir(inventoryPath)
+	if err != nil {
+		return fmt.Errorf("failed to list inventory directory %s: %w", inventoryPath, err)
+	}
+
+	tarballPath := d.cfg.ExportPackagesTarball
+	tmpPath := tarballPath + ".tmp"
+	f, err := d.os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+
+	writeErr := d.writePackagesTarball(ctx, f, inventoryPath, entries, kernelVersion, osType)
+	if closeErr := f.Close(); writeErr == nil && closeErr != nil {
+		writeErr = fmt.Errorf("failed to close %s: %w", tmpPath, closeErr)
+	}
+	if writeErr != nil {
+		// Best effort cleanup of the temp file
+		_ = d.os.RemoveAll(tmpPath)
+		return writeErr
+	}
+	if err := d.os.Rename(tmpPath, tarballPath); err != nil {
+		_ = d.os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to rename %s to %s: %w", tmpPath, tarballPath, err)
+	}
+
+	log.Info("Exported driver packages", "path", tarballPath)
+	return nil
+}
+
+// writePackagesTarball writes the regular files of the inventory dir followed by the build-info file to w
+func (d *driverMgr) writePackagesTarball(ctx context.Context, w io.Writer, inventoryPath string,
+	entries []os.DirEntry, kernelVersion, osType string,
+) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	addFile := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write tar header of %s: %w", name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s to the tarball: %w", name, err)
+		}
+		return nil
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := d.os.ReadFile(filepath.Join(inventoryPath, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read package %s: %w", entry.Name(), err)
+		}
+		if err := addFile(entry.Name(), data); err != nil {
+			return err
+		}
 	}
 
-	// Debug: Check if destination directory exists
-	destExistsCmd := fmt.Sprintf("ls -la %s", inventoryPath)
-	log.V(1).Info("Checking destination directory", "command", destExistsCmd)
-	_, _, destErr := d.cmd.RunCommand(ctx, "sh", "-c", destExistsCmd)
-	if destErr != nil {
-		log.V(1).Info("Destination directory check failed", "error", destErr)
+	buildInfo := fmt.Sprintf("KERNEL_VERSION=%s\nDRIVER_VERSION=%s\nOS_TYPE=%s\nARCH=%s\n%s\n",
+		kernelVersion, d.driverVersion(), osType, d.getArchitecture(ctx), d.currentBuildConfigFingerprint(ctx))
+	if err := addFile(buildInfoFileName, []byte(buildInfo)); err != nil {
+		return err
 	}
 
-	_, _, err := d.cmd.RunCommand(ctx, "sh", "-c", cpCmd)
-	if err != nil {
-		return fmt.Errorf("failed to copy %s packages to inventory: %w", packageType, err)
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize tarball: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finalize gzip stream: %w", err)
 	}
-
-	log.V(1).Info("Build artifacts copied successfully", "type", packageType)
 	return nil
 }
 
-// calculateDriverInventoryChecksum calculates MD5 checksum of driver inventory
+// calculateDriverInventoryChecksum calculates MD5 checksum of the driver inventory files
+// selected by ChecksumIncludeGlobs and ChecksumExcludeGlobs
 func (d *driverMgr) calculateDriverInventoryChecksum(ctx context.Context, inventoryPath string) (string, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
 	log.V(1).Info("Calculating driver inventory checksum", "path", inventoryPath)
 
 	// Use find and md5sum to calculate checksum through shell to handle pipe
-	checksumCmd := fmt.Sprintf("find %s -type f -exec md5sum {} + | md5sum", inventoryPath)
+	checksumCmd := fmt.Sprintf("find %s -type f%s -exec md5sum {} + | md5sum", inventoryPath, d.checksumNameFilter())
 	log.V(1).Info("Executing checksum calculation", "command", checksumCmd)
 	stdout, _, err := d.cmd.RunCommand(ctx, "sh", "-c", checksumCmd)
 	if err != nil {
@@ -1320,13 +2818,29 @@ func (d *driverMgr) calculateDriverInventoryChecksum(ctx context.Context, invent
 	return parts[0], nil
 }
 
+// checksumNameFilter returns the find name tests selecting the files covered by the inventory checksum,
+// empty when all files are covered
+func (d *driverMgr) checksumNameFilter() string {
+	var filter strings.Builder
+	if len(d.cfg.ChecksumIncludeGlobs) > 0 {
+		names := make([]string, 0, len(d.cfg.ChecksumIncludeGlobs))
+		for _, glob := range d.cfg.ChecksumIncludeGlobs {
+			names = append(names, fmt.Sprintf("-name '%s'", glob))
+		}
+		fmt.Fprintf(&filter, " \\( %s \\)", strings.Join(names, " -o "))
+	}
+	for _, glob := range d.cfg.ChecksumExcludeGlobs {
+		fmt.Fprintf(&filter, " ! -name '%s'", glob)
+	}
+	return filter.String()
+}
+
 // storeBuildChecksum stores the build checksum and build config fingerprint so that
 // future startups can detect both file corruption and configuration drift.
 func (d *driverMgr) storeBuildChecksum(ctx context.Context, inventoryPath, kernelVersion string) error {
 	log := logr.FromContextOrDiscard(ctx)
 
-	checksumPath := filepath.Join(d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, d.cfg.NvidiaNicDriverVer+".checksum")
-	buildConfigPath := filepath.Join(d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, d.cfg.NvidiaNicDriverVer+".buildconfig")
+	_, checksumPath, buildConfigPath := d.inventoryPaths(ctx, d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, d.driverVersion())
 
 	// Calculate and store package checksum
 	checksum, err := d.calculateDriverInventoryChecksum(ctx, inventoryPath)
@@ -1339,15 +2853,151 @@ func (d *driverMgr) storeBuildChecksum(ctx context.Context, inventoryPath, kerne
 	log.V(1).Info("Stored build checksum", "path", checksumPath, "checksum", checksum)
 
 	// Store the build config fingerprint so cache invalidation can detect config drift
-	buildConfig := d.currentBuildConfigFingerprint()
+	buildConfig := d.currentBuildConfigFingerprint(ctx)
 	if err := d.os.WriteFile(buildConfigPath, []byte(buildConfig), 0o644); err != nil {
 		return fmt.Errorf("failed to write build config file: %w", err)
 	}
 	log.V(1).Info("Stored build config fingerprint", "path", buildConfigPath)
 
+	// Record the detected version, the next run looks the inventory up by NvidiaNicDriverVer
+	if d.driverVersion() != d.cfg.NvidiaNicDriverVer {
+		aliasPath := d.driverVersionAliasPath(d.cfg.NvidiaNicDriversInventoryPath, kernelVersion)
+		if err := d.os.WriteFile(aliasPath, []byte(d.driverVersion()), 0o644); err != nil {
+			return fmt.Errorf("failed to write driver version file: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// driverVersion returns the driver version keying the inventory: PinnedDriverVer when set, the one
+// detected from the built packages with AutoDetectDriverVersion, NvidiaNicDriverVer otherwise
+func (d *driverMgr) driverVersion() string {
+	if d.cfg.PinnedDriverVer != "" {
+		return d.cfg.PinnedDriverVer
+	}
+	if d.detectedDriverVer != "" {
+		return d.detectedDriverVer
+	}
+	return d.cfg.NvidiaNicDriverVer
+}
+
+// inventoryPaths returns the package dir of a driver version in an inventory root and the checksum and
+// build config files stored next to it. With InventoryArchSubdir the packages are in an <arch> subdir
+// of the version dir and the files are named after the architecture.
+func (d *driverMgr) inventoryPaths(ctx context.Context, root, kernelVersion, version string) (string, string, string) {
+	versionPath := filepath.Join(root, kernelVersion, version)
+	if !d.cfg.InventoryArchSubdir {
+		return versionPath, versionPath + ".checksum", versionPath + ".buildconfig"
+	}
+	if d.arch == "" {
+		d.arch = d.getArchitecture(ctx)
+	}
+	archPath := filepath.Join(versionPath, d.arch)
+	return archPath, archPath + ".checksum", archPath + ".buildconfig"
+}
+
+// driverVersionAliasPath returns the file recording the detected version of the packages
+// built for NvidiaNicDriverVer in an inventory root
+func (d *driverMgr) driverVersionAliasPath(root, kernelVersion string) string {
+	return filepath.Join(root, kernelVersion, d.cfg.NvidiaNicDriverVer+".version")
+}
+
+// detectDriverVersion reads the driver version from the metadata of the kernel package in dir,
+// falling back to the first package when there is no kernel package
+func (d *driverMgr) detectDriverVersion(ctx context.Context, dir, osType string) (string, error) {
+	var pattern string
+	var query func(pkg string) (string, string, error)
+	switch osType {
+	case constants.OSTypeUbuntu:
+		pattern = "*.deb"
+		query = func(pkg string) (string, string, error) {
+			return d.cmd.RunCommand(ctx, "dpkg-deb", "-f", pkg, "Version")
+		}
+	case constants.OSTypeSLES, constants.OSTypeRedHat, constants.OSTypeOpenShift:
+		pattern = "*.rpm"
+		query = func(pkg string) (string, string, error) {
+			return d.cmd.RunCommand(ctx, "rpm", "-qp", "--queryformat", "%{VERSION}-%{RELEASE}", pkg)
+		}
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedOS, osType)
+	}
+
+	entries, err := d.os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to list driver packages: %w", err)
+	}
+	var pkg string
+	for _, entry := range entries {
+		name := entry.Name()
+		if matched, _ := filepath.Match(pattern, name); !matched {
+			continue
+		}
+		if strings.Contains(name, "ofed-kernel") || strings.Contains(name, "ofa_kernel") {
+			pkg = filepath.Join(dir, name)
+			break
+		}
+		if pkg == "" {
+			pkg = filepath.Join(dir, name)
+		}
+	}
+	if pkg == "" {
+		return "", fmt.Errorf("no driver packages found in %s", dir)
+	}
+
+	stdout, _, err := query(pkg)
+	if err != nil {
+		return "", fmt.Errorf("failed to query version of %s: %w", pkg, err)
+	}
+	version := strings.TrimSpace(stdout)
+	if version == "" {
+		return "", fmt.Errorf("no version in the metadata of %s", pkg)
+	}
+	return version, nil
+}
+
+// applyDetectedDriverVersion detects the version of the packages built into inventoryPath, warns
+// when it differs from NvidiaNicDriverVer and moves a persistent inventory to the path of the
+// detected version. Returns the inventory path to use.
+func (d *driverMgr) applyDetectedDriverVersion(ctx context.Context, inventoryPath, kernelVersion, osType string) string {
+	log := logr.FromContextOrDiscard(ctx)
+
+	version, err := d.detectDriverVersion(ctx, inventoryPath, osType)
+	if err != nil {
+		log.Info("[WARN] failed to detect driver version from packages, using configured version",
+			"version", d.cfg.NvidiaNicDriverVer, "error", err)
+		return inventoryPath
+	}
+	if version == d.cfg.NvidiaNicDriverVer {
+		return inventoryPath
+	}
+	log.Info("[WARN] driver version of the built packages differs from NVIDIA_NIC_DRIVER_VER",
+		"configured", d.cfg.NvidiaNicDriverVer, "detected", version)
+
+	if d.cfg.NvidiaNicDriversInventoryPath == "" {
+		d.detectedDriverVer = version
+		return inventoryPath
+	}
+	detectedPath, _, _ := d.inventoryPaths(ctx, d.cfg.NvidiaNicDriversInventoryPath, kernelVersion, version)
+	if d.cfg.InventoryArchSubdir {
+		// Only the packages of this architecture move, the version dir may hold other ones
+		if err := d.os.MkdirAll(filepath.Dir(detectedPath), 0o755); err != nil {
+			log.Info("[WARN] failed to create inventory of the detected driver version", "path", detectedPath, "error", err)
+			return inventoryPath
+		}
+	}
+	if err := d.os.RemoveAll(detectedPath); err != nil {
+		log.Info("[WARN] failed to clean inventory of the detected driver version", "path", detectedPath, "error", err)
+		return inventoryPath
+	}
+	if err := d.os.Rename(inventoryPath, detectedPath); err != nil {
+		log.Info("[WARN] failed to move inventory to the detected driver version", "path", detectedPath, "error", err)
+		return inventoryPath
+	}
+	d.detectedDriverVer = version
+	return detectedPath
+}
+
 // fixSourceLink fixes the /usr/src/ofa_kernel/default symlink
 func (d *driverMgr) fixSourceLink(ctx context.Context, kernelVersion string) error {
 	log := logr.FromContextOrDiscard(ctx)
@@ -1360,8 +3010,21 @@ func (d *driverMgr) fixSourceLink(ctx context.Context, kernelVersion string) err
 
 	// Read current symlink target
 	linkTarget, err := d.os.Readlink(targetPath)
+	if errors.Is(err, os.ErrNotExist) {
+		// Create the link on fresh installs, but only if the source tree is actually there
+		if _, statErr := d.os.Stat(expectedTarget); statErr != nil {
+			log.V(1).Info("Source link does not exist and source tree is missing, skipping", "target", expectedTarget, "error", statErr)
+			return nil
+		}
+		_, _, err := d.cmd.RunCommand(ctx, "ln", "-snf", expectedTarget, targetPath)
+		if err != nil {
+			return fmt.Errorf("failed to create source link: %w", err)
+		}
+		log.V(1).Info("Created source link", "link", targetPath, "to", expectedTarget)
+		return nil
+	}
 	if err != nil {
-		log.V(1).Info("Source link does not exist or is not a symlink", "error", err)
+		log.V(1).Info("Source link is not a symlink", "error", err)
 		return nil
 	}
 
@@ -1378,6 +3041,30 @@ func (d *driverMgr) fixSourceLink(ctx context.Context, kernelVersion string) err
 	return nil
 }
 
+// checkArchitecture verifies that the host architecture matches the architecture the container
+// was built for, unless AllowArchMismatch is set
+func (d *driverMgr) checkArchitecture(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	containerArch, ok := goArchToUname[runtime.GOARCH]
+	if !ok {
+		log.V(1).Info("Unknown container architecture, skipping host architecture check", "goarch", runtime.GOARCH)
+		return nil
+	}
+
+	hostArch := d.getArchitecture(ctx)
+	if hostArch == containerArch {
+		return nil
+	}
+	if d.cfg.AllowArchMismatch {
+		log.Info("[WARN] container architecture doesn't match host architecture, continuing as ALLOW_ARCH_MISMATCH is set",
+			"container", containerArch, "host", hostArch)
+		return nil
+	}
+	return fmt.Errorf("container architecture %s doesn't match host architecture %s, "+
+		"use the %s driver container image or set ALLOW_ARCH_MISMATCH=true", containerArch, hostArch, hostArch)
+}
+
 // getArchitecture returns the system architecture
 func (d *driverMgr) getArchitecture(ctx context.Context) string {
 	// Execute uname -m to get the machine architecture
@@ -1392,13 +3079,68 @@ func (d *driverMgr) getArchitecture(ctx context.Context) string {
 	return strings.TrimSpace(output)
 }
 
-// installDriver installs the driver packages from the inventory directory
-func (d *driverMgr) installDriver(ctx context.Context, inventoryPath, kernelVersion, osType string) error {
-	log := logr.FromContextOrDiscard(ctx)
+// sysfsPath joins elem onto the configured sysfs root (SYSFS_ROOT, /sys by default).
+func (d *driverMgr) sysfsPath(elem ...string) string {
+	root := d.cfg.SysfsRoot
+	if root == "" {
+		root = constants.DefaultSysfsRoot
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
 
-	log.V(1).Info("Installing driver packages", "path", inventoryPath, "kernel", kernelVersion, "os", osType)
+// procPath joins elem onto the configured procfs root (PROC_ROOT, /proc by default).
+func (d *driverMgr) procPath(elem ...string) string {
+	root := d.cfg.ProcRoot
+	if root == "" {
+		root = constants.DefaultProcRoot
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+// hostRoot returns the configured host root (HOST_ROOT, /host by default).
+func (d *driverMgr) hostRoot() string {
+	if d.cfg.HostRoot == "" {
+		return constants.DefaultHostRoot
+	}
+	return d.cfg.HostRoot
+}
+
+// hostCommand returns the command and args of a command targeting the host, prefixed with HostCommandPrefix
+// (nsenter -t 1 -m -n -- by default) to run in the host namespaces when UseNsenterForHostCommands is set
+func (d *driverMgr) hostCommand(command string, args ...string) (string, []string) {
+	if !d.cfg.UseNsenterForHostCommands {
+		return command, args
+	}
+	prefix := strings.Fields(d.cfg.HostCommandPrefix)
+	if len(prefix) == 0 {
+		prefix = strings.Fields(constants.DefaultHostCommandPrefix)
+	}
+	return prefix[0], append(append(prefix[1:len(prefix):len(prefix)], command), args...)
+}
+
+// modprobeHostModule loads a host inbox module, through the host module tree mounted under HostRoot or
+// in the host namespaces, where the host modules are at their own path, with UseNsenterForHostCommands
+func (d *driverMgr) modprobeHostModule(ctx context.Context, module string) (string, string, error) {
+	if d.cfg.UseNsenterForHostCommands {
+		command, args := d.hostCommand("modprobe", module)
+		return d.cmd.RunCommand(ctx, command, args...)
+	}
+	return d.cmd.RunCommand(ctx, "modprobe", "-d", d.hostRoot(), module)
+}
+
+// openibdScriptPath returns the configured openibd script (OPENIBD_SCRIPT_PATH, /etc/init.d/openibd by default).
+func (d *driverMgr) openibdScriptPath() string {
+	if d.cfg.OpenibdScriptPath == "" {
+		return constants.DefaultOpenibdScriptPath
+	}
+	return d.cfg.OpenibdScriptPath
+}
+
+// createDepmodStubs creates the kernel modules directory and empty modules.order and modules.builtin files
+// to prevent depmod from giving a WARNING about missing files during installation. Existing files are left alone.
+func (d *driverMgr) createDepmodStubs(ctx context.Context, kernelVersion string) error {
+	log := logr.FromContextOrDiscard(ctx)
 
-	// Prevent depmod from giving a WARNING about missing files during installation
 	kernelModulesDir := filepath.Join("/lib/modules", kernelVersion)
 	if _, err := d.os.Stat(kernelModulesDir); os.IsNotExist(err) {
 		log.V(1).Info("Creating kernel modules directory", "path", kernelModulesDir)
@@ -1408,30 +3150,97 @@ func (d *driverMgr) installDriver(ctx context.Context, inventoryPath, kernelVers
 		}
 	}
 
-	// Create required files to prevent depmod warnings
-	modulesOrderPath := filepath.Join(kernelModulesDir, "modules.order")
-	modulesBuiltinPath := filepath.Join(kernelModulesDir, "modules.builtin")
+	for _, name := range []string{"modules.order", "modules.builtin"} {
+		path := filepath.Join(kernelModulesDir, name)
+		if _, err := d.os.Stat(path); err == nil {
+			log.V(1).Info("Depmod file already exists, leaving it alone", "path", path)
+			continue
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to check %s file: %w", name, err)
+		}
+		log.V(1).Info("Creating depmod file", "path", path)
+		if _, _, err := d.cmd.RunCommand(ctx, "touch", path); err != nil {
+			return fmt.Errorf("failed to create %s file: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// installDriver installs the driver packages from the inventory directory
+func (d *driverMgr) installDriver(ctx context.Context, inventoryPath, kernelVersion, osType string) error {
+	log := logr.FromContextOrDiscard(ctx)
 
-	log.V(1).Info("Creating modules.order and modules.builtin files")
-	_, _, err := d.cmd.RunCommand(ctx, "touch", modulesOrderPath)
-	if err != nil {
-		return fmt.Errorf("failed to create modules.order file: %w", err)
+	installKey := kernelVersion + "/" + d.driverVersion()
+	if d.installedDrivers[installKey] {
+		log.Info("Driver packages already installed by this run, skipping", "kernel", kernelVersion,
+			"version", d.driverVersion())
+		return nil
 	}
 
-	_, _, err = d.cmd.RunCommand(ctx, "touch", modulesBuiltinPath)
-	if err != nil {
-		return fmt.Errorf("failed to create modules.builtin file: %w", err)
+	log.V(1).Info("Installing driver packages", "path", inventoryPath, "kernel", kernelVersion, "os", osType)
+
+	if d.cfg.SkipDepmodStub {
+		log.V(1).Info("SkipDepmodStub is set, skipping the depmod stub files")
+	} else if err := d.createDepmodStubs(ctx, kernelVersion); err != nil {
+		return err
 	}
 
 	// Install packages based on OS type
+	var err error
 	switch osType {
 	case constants.OSTypeUbuntu:
-		return d.installUbuntuDriver(ctx, inventoryPath, kernelVersion)
+		err = d.installUbuntuDriver(ctx, inventoryPath, kernelVersion)
 	case constants.OSTypeSLES, constants.OSTypeRedHat, constants.OSTypeOpenShift:
-		return d.installRedHatDriver(ctx, inventoryPath, kernelVersion, osType)
+		err = d.installRedHatDriver(ctx, inventoryPath, kernelVersion, osType)
 	default:
-		return fmt.Errorf("unsupported OS type for driver installation: %s", osType)
+		return fmt.Errorf("%w for driver installation: %s", ErrUnsupportedOS, osType)
+	}
+	if err != nil {
+		return err
+	}
+
+	// With DKMS the modules are only built in Load, there is nothing to resolve yet
+	if d.cfg.UseDKMS {
+		log.V(1).Info("DKMS enabled, skipping modules.dep validation")
+	} else if err := d.validateModulesDep(ctx, kernelVersion); err != nil {
+		return err
+	}
+
+	if d.installedDrivers == nil {
+		d.installedDrivers = make(map[string]bool)
+	}
+	d.installedDrivers[installKey] = true
+	return nil
+}
+
+// validateModulesDep checks that the driver modules and their dependencies can be resolved
+// from modules.dep of the kernel, so a broken install is reported here and not by modprobe in Load
+func (d *driverMgr) validateModulesDep(ctx context.Context, kernelVersion string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	modules := d.driverModules()
+
+	log.V(1).Info("Validating modules.dep", "kernel", kernelVersion, "modules", modules)
+
+	var unresolved []string
+	for _, module := range modules {
+		_, stderr, err := d.cmd.RunCommand(ctx, "modprobe", "--dry-run", "--show-depends", "--set-version", kernelVersion, module)
+		if err != nil {
+			reason := strings.TrimSpace(stderr)
+			if reason == "" {
+				reason = err.Error()
+			}
+			log.V(1).Info("Module can't be resolved", "module", module, "reason", reason)
+			unresolved = append(unresolved, fmt.Sprintf("%s (%s)", module, reason))
+		}
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("modules can't be resolved for kernel %s after install: %s",
+			kernelVersion, strings.Join(unresolved, "; "))
 	}
+
+	log.V(1).Info("All driver modules resolved", "kernel", kernelVersion)
+	return nil
 }
 
 // installUbuntuDriver installs driver packages on Ubuntu
@@ -1440,27 +3249,30 @@ func (d *driverMgr) installUbuntuDriver(ctx context.Context, inventoryPath, kern
 
 	log.V(1).Info("Installing Ubuntu driver packages", "path", inventoryPath)
 
-	// Try to install linux-modules-extra package if available
-	modulesExtraPkg := fmt.Sprintf("linux-modules-extra-%s", kernelVersion)
-	log.V(1).Info("Attempting to install modules extra package", "package", modulesExtraPkg)
+	// The modules-extra package can only come from the network, skip it for offline builds
+	if !d.cfg.OfflineBuild {
+		// Try to install linux-modules-extra package if available
+		modulesExtraPkg := fmt.Sprintf("linux-modules-extra-%s", kernelVersion)
+		log.V(1).Info("Attempting to install modules extra package", "package", modulesExtraPkg)
 
-	// Update package list and try to install modules-extra package
-	_, _, err := d.cmd.RunCommand(ctx, "apt-get", "update")
-	if err != nil {
-		log.V(1).Info("Failed to update apt packages, continuing", "error", err)
-	}
+		// Update package list and try to install modules-extra package
+		_, _, err := d.runPackageManager(ctx, "apt-get", "update")
+		if err != nil {
+			log.V(1).Info("Failed to update apt packages, continuing", "error", err)
+		}
 
-	// Check if the package exists and install it if available
-	cmdStr := fmt.Sprintf("LC_ALL=C apt-cache show %s | grep %s && apt-get install -y %s || true",
-		modulesExtraPkg, modulesExtraPkg, modulesExtraPkg)
-	_, _, err = d.cmd.RunCommand(ctx, "sh", "-c", cmdStr)
-	if err != nil {
-		log.V(1).Info("Failed to install modules extra package, continuing", "error", err)
+		// Check if the package exists and install it if available
+		cmdStr := fmt.Sprintf("LC_ALL=C apt-cache show %s | grep %s && apt-get install -y %s || true",
+			modulesExtraPkg, modulesExtraPkg, modulesExtraPkg)
+		_, _, err = d.cmd.RunCommand(ctx, "sh", "-c", cmdStr)
+		if err != nil {
+			log.V(1).Info("Failed to install modules extra package, continuing", "error", err)
+		}
 	}
 
 	// Install driver packages using shell to expand wildcards
 	installCmd := fmt.Sprintf("apt-get install -y %s/*.deb", inventoryPath)
-	_, _, err = d.cmd.RunCommand(ctx, "sh", "-c", installCmd)
+	_, _, err := d.cmd.RunCommand(ctx, "sh", "-c", installCmd)
 	if err != nil {
 		return fmt.Errorf("failed to install Ubuntu driver packages: %w", err)
 	}
@@ -1603,12 +3415,17 @@ func (d *driverMgr) ubuntuSyncNetworkConfigurationTools(ctx context.Context) err
 		log.V(1).Info("/etc/network/interfaces not found, renaming ifup file to prevent issues with mlnx_interface_mgr.sh")
 
 		// Check if /sbin/ifup exists and rename it to /sbin/ifup.bk
-		ifupPath := "/sbin/ifup"
 		if _, err := d.os.Stat(ifupPath); err == nil {
-			_, _, err := d.cmd.RunCommand(ctx, "mv", ifupPath, ifupPath+".bk")
+			if !d.cfg.UbuntuRenameIfup {
+				log.Info("Not renaming ifup file, mlnx_interface_mgr.sh may fail reading missing /etc/network/interfaces",
+					"path", ifupPath)
+				return nil
+			}
+			_, _, err := d.cmd.RunCommand(ctx, "mv", ifupPath, ifupBackupPath)
 			if err != nil {
 				return fmt.Errorf("failed to rename ifup file: %w", err)
 			}
+			d.ifupRenamed = true
 			log.V(1).Info("Renamed ifup file to prevent mlnx_interface_mgr.sh from reading missing /etc/network/interfaces")
 		}
 	} else if err != nil {
@@ -1619,6 +3436,74 @@ func (d *driverMgr) ubuntuSyncNetworkConfigurationTools(ctx context.Context) err
 	return nil
 }
 
+// restoreIfup renames /sbin/ifup back if it was renamed by ubuntuSyncNetworkConfigurationTools
+func (d *driverMgr) restoreIfup(ctx context.Context) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if !d.ifupRenamed {
+		return
+	}
+	if _, _, err := d.cmd.RunCommand(ctx, "mv", ifupBackupPath, ifupPath); err != nil {
+		log.Error(err, "Failed to restore ifup file", "path", ifupPath)
+		return
+	}
+	d.ifupRenamed = false
+	log.V(1).Info("Restored ifup file", "path", ifupPath)
+}
+
+// copyHostResolvConf replaces /etc/resolv.conf with the host one, for clusters where only the host
+// can resolve the package mirrors. The file is written in place as it is often bind mounted.
+func (d *driverMgr) copyHostResolvConf(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if d.resolvConfReplaced {
+		return nil
+	}
+	hostResolvConf := filepath.Join(d.hostRoot(), resolvConfPath)
+	content, err := d.os.ReadFile(hostResolvConf)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Info("[WARN] Host resolv.conf not found, keeping the container one", "path", hostResolvConf)
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", hostResolvConf, err)
+	}
+
+	original, err := d.os.ReadFile(resolvConfPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", resolvConfPath, err)
+	}
+	if err := d.os.WriteFile(resolvConfPath, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", resolvConfPath, err)
+	}
+	d.resolvConfOriginal = original
+	d.resolvConfReplaced = true
+	log.V(1).Info("Copied host resolv.conf", "from", hostResolvConf, "to", resolvConfPath)
+	return nil
+}
+
+// restoreResolvConf restores /etc/resolv.conf if it was replaced by copyHostResolvConf
+func (d *driverMgr) restoreResolvConf(ctx context.Context) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if !d.resolvConfReplaced {
+		return
+	}
+	var err error
+	if d.resolvConfOriginal == nil {
+		err = d.os.RemoveAll(resolvConfPath)
+	} else {
+		err = d.os.WriteFile(resolvConfPath, d.resolvConfOriginal, 0o644)
+	}
+	if err != nil {
+		log.Error(err, "Failed to restore resolv.conf", "path", resolvConfPath)
+		return
+	}
+	d.resolvConfReplaced = false
+	d.resolvConfOriginal = nil
+	log.V(1).Info("Restored resolv.conf", "path", resolvConfPath)
+}
+
 // getPackageSuffix returns the package suffix based on OS type
 func (d *driverMgr) getPackageSuffix(osType string) string {
 	switch osType {
@@ -1633,16 +3518,17 @@ func (d *driverMgr) getPackageSuffix(osType string) string {
 
 // getAppendDriverBuildFlags returns additional build flags based on configuration
 func (d *driverMgr) getAppendDriverBuildFlags(osType string) []string {
-	// If ENABLE_NFSRDMA is false, add additional flags
+	flags := []string{}
+	pkgSuffix := d.getPackageSuffix(osType)
+	// Skip the NFS RDMA and NVMe over RDMA modules that are not enabled
 	if !d.cfg.EnableNfsRdma {
-		pkgSuffix := d.getPackageSuffix(osType)
-		return []string{
-			"--without-mlnx-nfsrdma" + pkgSuffix,
-			"--without-mlnx-nvme" + pkgSuffix,
-		}
+		flags = append(flags, "--without-mlnx-nfsrdma"+pkgSuffix)
+	}
+	if !d.cfg.EnableNvmeRdma {
+		flags = append(flags, "--without-mlnx-nvme"+pkgSuffix)
 	}
 
-	return []string{}
+	return flags
 }
 
 // setupOpenShiftRepositories configures OpenShift-specific repositories
@@ -1657,16 +3543,15 @@ func (d *driverMgr) setupOpenShiftRepositories(ctx context.Context, versionInfo
 
 	// Enable RHOCP repository
 	repoName := fmt.Sprintf("rhocp-%s-for-rhel-%d-%s-rpms", versionInfo.OpenShiftVersion, versionInfo.MajorVersion, arch)
-	_, _, err := d.cmd.RunCommand(ctx, dnfCmd, "config-manager", "--set-enabled", repoName)
-	if err != nil {
+	if err := d.enableRepo(ctx, repoName); err != nil {
 		log.V(1).Info("Failed to enable RHOCP repository, continuing", "repo", repoName, "error", err)
 	}
 
 	// Test if makecache works
-	_, _, err = d.cmd.RunCommand(ctx, dnfCmd, "makecache", "--releasever="+versionInfo.FullVersion)
+	_, _, err := d.runPackageManager(ctx, dnfCmd, "makecache", "--releasever="+versionInfo.FullVersion)
 	if err != nil {
 		log.V(1).Info("Makecache failed, disabling RHOCP repository", "error", err)
-		_, _, _ = d.cmd.RunCommand(ctx, dnfCmd, "config-manager", "--set-disabled", repoName)
+		d.disableRepo(ctx, repoName)
 	}
 }
 
@@ -1682,8 +3567,7 @@ func (d *driverMgr) setupEUSRepositories(ctx context.Context, versionInfo *host.
 		if versionInfo.FullVersion == version {
 			log.V(1).Info("Enabling EUS repository", "version", version, "arch", arch)
 			repoName := fmt.Sprintf("rhel-%d-for-%s-baseos-eus-rpms", versionInfo.MajorVersion, arch)
-			_, _, err := d.cmd.RunCommand(ctx, dnfCmd, "config-manager", "--set-enabled", repoName)
-			if err != nil {
+			if err := d.enableRepo(ctx, repoName); err != nil {
 				log.V(1).Info("Failed to enable EUS repository", "repo", repoName, "error", err)
 			}
 			break
@@ -1691,6 +3575,46 @@ func (d *driverMgr) setupEUSRepositories(ctx context.Context, versionInfo *host.
 	}
 }
 
+// enableRepo enables a dnf repo and records it to be disabled again by restoreEnabledRepos
+func (d *driverMgr) enableRepo(ctx context.Context, repoName string) error {
+	if _, _, err := d.cmd.RunCommand(ctx, dnfCmd, "config-manager", "--set-enabled", repoName); err != nil {
+		return err
+	}
+	if !slices.Contains(d.enabledRepos, repoName) {
+		d.enabledRepos = append(d.enabledRepos, repoName)
+	}
+	return nil
+}
+
+// disableRepo disables a dnf repo, errors are only logged
+func (d *driverMgr) disableRepo(ctx context.Context, repoName string) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if _, _, err := d.cmd.RunCommand(ctx, dnfCmd, "config-manager", "--set-disabled", repoName); err != nil {
+		log.V(1).Info("Failed to disable repository", "repo", repoName, "error", err)
+		return
+	}
+	d.enabledRepos = slices.DeleteFunc(d.enabledRepos, func(r string) bool { return r == repoName })
+}
+
+// restoreEnabledRepos disables the repos enabled by this run so the host repo config is left as found,
+// unless KeepEnabledRepos is set
+func (d *driverMgr) restoreEnabledRepos(ctx context.Context) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if len(d.enabledRepos) == 0 {
+		return
+	}
+	if d.cfg.KeepEnabledRepos {
+		log.V(1).Info("Keeping enabled repositories", "repos", d.enabledRepos)
+		return
+	}
+	log.Info("Disabling repositories enabled for the driver build", "repos", d.enabledRepos)
+	for _, repoName := range slices.Clone(d.enabledRepos) {
+		d.disableRepo(ctx, repoName)
+	}
+}
+
 // installKernelPackages installs kernel packages based on kernel type
 func (d *driverMgr) installKernelPackages(ctx context.Context, kernelVersion string, versionInfo *host.RedhatVersionInfo) error {
 	log := logr.FromContextOrDiscard(ctx)
@@ -1713,8 +3637,17 @@ func (d *driverMgr) installKernelPackages(ctx context.Context, kernelVersion str
 		}
 	}
 
+	if kernelType == kernelTypeStandard {
+		kVer = d.standardKernelPackageVersion(ctx, kernelVersion, releaseverStr)
+	}
+	develPkg, err := d.kernelHeaderPackage(ctx, redHatKernelHeaderPackageTemplate, kVer, strings.TrimSuffix(rtHpSubstr, "-"))
+	if err != nil {
+		return err
+	}
+
 	// Install standard kernel packages for non-RT, non-64k kernels
 	if kernelType == kernelTypeStandard {
+
 		packages := []string{
 			"kernel-" + kernelVersion,
 			"kernel-headers-" + kernelVersion,
@@ -1728,7 +3661,7 @@ func (d *driverMgr) installKernelPackages(ctx context.Context, kernelVersion str
 			}
 			args = append(args, "install", pkg)
 
-			_, _, err := d.cmd.RunCommand(ctx, args[0], args[1:]...)
+			_, _, err := d.runPackageManager(ctx, args[0], args[1:]...)
 			if err != nil {
 				return fmt.Errorf("failed to install %s: %w", pkg, err)
 			}
@@ -1739,11 +3672,11 @@ func (d *driverMgr) installKernelPackages(ctx context.Context, kernelVersion str
 		if releaseverStr != "" {
 			args = append(args, releaseverStr)
 		}
-		args = append(args, "install", "kernel-devel-"+kernelVersion, "--allowerasing")
+		args = append(args, "install", develPkg, "--allowerasing")
 
-		_, _, err := d.cmd.RunCommand(ctx, args[0], args[1:]...)
+		_, _, err := d.runPackageManager(ctx, args[0], args[1:]...)
 		if err != nil {
-			return fmt.Errorf("failed to install kernel-devel: %w", err)
+			return fmt.Errorf("failed to install %s: %w", develPkg, err)
 		}
 	}
 
@@ -1752,9 +3685,9 @@ func (d *driverMgr) installKernelPackages(ctx context.Context, kernelVersion str
 	if releaseverStr != "" {
 		args = append(args, releaseverStr)
 	}
-	args = append(args, "install", "kernel-"+rtHpSubstr+"devel-"+kVer, "kernel-"+rtHpSubstr+"modules-"+kVer)
+	args = append(args, "install", develPkg, "kernel-"+rtHpSubstr+"modules-"+kVer)
 
-	_, _, err := d.cmd.RunCommand(ctx, args[0], args[1:]...)
+	_, _, err = d.runPackageManager(ctx, args[0], args[1:]...)
 	if err != nil {
 		return fmt.Errorf("failed to install kernel development packages: %w", err)
 	}
@@ -1762,6 +3695,40 @@ func (d *driverMgr) installKernelPackages(ctx context.Context, kernelVersion str
 	return nil
 }
 
+// standardKernelPackageVersion returns the version the kernel-devel and kernel-modules packages of a
+// standard kernel are requested with. A trailing .<arch> is stripped when dnf knows the package under the
+// normalized name, otherwise the uname -r kernel version is used as is.
+func (d *driverMgr) standardKernelPackageVersion(ctx context.Context, kernelVersion, releaseverStr string) string {
+	log := logr.FromContextOrDiscard(ctx)
+
+	normalized := trimKernelArch(kernelVersion)
+	if normalized == kernelVersion {
+		return kernelVersion
+	}
+
+	args := []string{dnfFlagQuiet}
+	if releaseverStr != "" {
+		args = append(args, releaseverStr)
+	}
+	args = append(args, "list", "kernel-devel-"+normalized)
+	if _, _, err := d.runPackageManager(ctx, dnfCmd, args...); err != nil {
+		log.V(1).Info("Normalized kernel package not found, using the kernel version",
+			"normalized", normalized, "kernel", kernelVersion, "error", err)
+		return kernelVersion
+	}
+	return normalized
+}
+
+// trimKernelArch strips a trailing .<arch> (e.g. .x86_64) from a kernel version
+func trimKernelArch(kernelVersion string) string {
+	for _, arch := range goArchToUname {
+		if trimmed, ok := strings.CutSuffix(kernelVersion, "."+arch); ok {
+			return trimmed
+		}
+	}
+	return kernelVersion
+}
+
 // analyzeKernelType analyzes the kernel version to determine type and naming pattern
 func (d *driverMgr) analyzeKernelType(
 	ctx context.Context,
@@ -1821,46 +3788,57 @@ func (d *driverMgr) checkLoadedKmodSrcverVsModinfo(ctx context.Context, modules
 			return false, nil // Module not loaded, need to reload
 		}
 
-		// Get srcversion from modinfo
-		srcverFromModinfo, _, err := d.cmd.RunCommand(ctx, "modinfo", module)
-		if err != nil {
-			log.V(1).Info("Failed to get modinfo for module", "module", module, "error", err)
-			return false, nil // Module not found, need to reload
-		}
-
-		// Extract srcversion from modinfo output
-		srcverFromModinfo = strings.TrimSpace(srcverFromModinfo)
-		lines := strings.Split(srcverFromModinfo, "\n")
-		var modinfoSrcver string
-		for _, line := range lines {
-			if strings.Contains(line, "srcversion") {
-				parts := strings.Fields(line)
-				if len(parts) > 0 {
-					modinfoSrcver = parts[len(parts)-1]
-					break
-				}
+		if !d.moduleSrcversionMatches(ctx, module) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// moduleSrcversionMatches checks if the srcversion of a loaded module matches its modinfo
+func (d *driverMgr) moduleSrcversionMatches(ctx context.Context, module string) bool {
+	log := logr.FromContextOrDiscard(ctx)
+
+	// Get srcversion from modinfo
+	srcverFromModinfo, _, err := d.cmd.RunCommand(ctx, "modinfo", module)
+	if err != nil {
+		log.V(1).Info("Failed to get modinfo for module", "module", module, "error", err)
+		return false // Module not found, need to reload
+	}
+
+	// Extract srcversion from modinfo output
+	srcverFromModinfo = strings.TrimSpace(srcverFromModinfo)
+	lines := strings.Split(srcverFromModinfo, "\n")
+	var modinfoSrcver string
+	for _, line := range lines {
+		if strings.Contains(line, "srcversion") {
+			parts := strings.Fields(line)
+			if len(parts) > 0 {
+				modinfoSrcver = parts[len(parts)-1]
+				break
 			}
 		}
+	}
 
-		// Get srcversion from sysfs
-		sysfsPath := fmt.Sprintf("/sys/module/%s/srcversion", module)
-		srcverFromSysfs, _, err := d.cmd.RunCommand(ctx, "cat", sysfsPath)
-		if err != nil {
-			log.V(1).Info("Failed to read sysfs srcversion for module", "module", module, "error", err)
-			return false, nil // Module not loaded, need to reload
-		}
+	// Get srcversion from sysfs
+	sysfsPath := d.sysfsPath("module", module, "srcversion")
+	srcverFromSysfs, _, err := d.cmd.RunCommand(ctx, "cat", sysfsPath)
+	if err != nil {
+		log.V(1).Info("Failed to read sysfs srcversion for module", "module", module, "error", err)
+		return false // Module not loaded, need to reload
+	}
 
-		srcverFromSysfs = strings.TrimSpace(srcverFromSysfs)
+	srcverFromSysfs = strings.TrimSpace(srcverFromSysfs)
 
-		log.V(1).Info("Module version check", "module", module, "modinfo", modinfoSrcver, "sysfs", srcverFromSysfs)
+	log.V(1).Info("Module version check", "module", module, "modinfo", modinfoSrcver, "sysfs", srcverFromSysfs)
 
-		if modinfoSrcver != srcverFromSysfs {
-			log.V(1).Info("Module srcversion differs", "module", module)
-			return false, nil
-		}
+	if modinfoSrcver != srcverFromSysfs {
+		log.V(1).Info("Module srcversion differs", "module", module)
+		return false
 	}
 
-	return true, nil
+	return true
 }
 
 // loadHostDependencies loads dependencies for all loaded modules from the host.
@@ -1868,9 +3846,10 @@ func (d *driverMgr) loadHostDependencies(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
 	log.V(1).Info("Loading host dependencies for loaded modules")
 
-	content, err := d.os.ReadFile("/proc/modules")
+	procModulesPath := d.procPath("modules")
+	content, err := d.os.ReadFile(procModulesPath)
 	if err != nil {
-		return fmt.Errorf("failed to read /proc/modules: %w", err)
+		return fmt.Errorf("failed to read %s: %w", procModulesPath, err)
 	}
 
 	loadedModules := make(map[string]struct{})
@@ -1900,7 +3879,7 @@ func (d *driverMgr) loadModuleDependencies(ctx context.Context, modName string)
 	for _, dep := range strings.Split(output, ",") {
 		if dep = strings.TrimSpace(dep); dep != "" {
 			logr.FromContextOrDiscard(ctx).V(1).Info("Loading dependency", "dependency", dep)
-			_, _, _ = d.cmd.RunCommand(ctx, "modprobe", "-d", "/host", dep)
+			_, _, _ = d.modprobeHostModule(ctx, dep)
 		}
 	}
 }
@@ -1922,7 +3901,7 @@ func (d *driverMgr) loadModuleHostInboxDependencies(ctx context.Context, modName
 			continue
 		}
 
-		hostPath, _, err := d.cmd.RunCommand(ctx, "modinfo", "-b", "/host", "-n", dep)
+		hostPath, _, err := d.cmd.RunCommand(ctx, "modinfo", "-b", d.hostRoot(), "-n", dep)
 		if err != nil {
 			continue
 		}
@@ -1932,8 +3911,42 @@ func (d *driverMgr) loadModuleHostInboxDependencies(ctx context.Context, modName
 		}
 
 		log.V(1).Info("Loading host inbox dependency", "module", modName, "dependency", dep, "path", hostPath)
-		_, _, _ = d.cmd.RunCommand(ctx, "modprobe", "-d", "/host", dep)
+		_, _, _ = d.modprobeHostModule(ctx, dep)
+	}
+}
+
+// runWithHeartbeat runs a long command and logs a progress line with the elapsed time
+// every CommandHeartbeatInterval until it completes. A zero interval disables the heartbeat.
+func (d *driverMgr) runWithHeartbeat(ctx context.Context, command string, args ...string) (string, string, error) {
+	interval := d.cfg.CommandHeartbeatInterval
+	if interval <= 0 {
+		return d.cmd.RunCommand(ctx, command, args...)
 	}
+
+	log := logr.FromContextOrDiscard(ctx)
+	done := make(chan struct{})
+	defer close(done)
+
+	start := time.Now()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				log.Info("Command still running", "command", command, "args", args,
+					"elapsed", time.Since(start).Round(time.Second).String())
+			}
+		}
+	}()
+
+	stdout, stderr, err := d.cmd.RunCommand(ctx, command, args...)
+	log.V(1).Info("Command finished", "command", command, "elapsed", time.Since(start).Round(time.Millisecond).String())
+	return stdout, stderr, err
 }
 
 // restartDriver restarts the driver modules
@@ -1942,16 +3955,21 @@ func (d *driverMgr) restartDriver(ctx context.Context) error {
 
 	log.V(1).Info("Restarting driver modules")
 
+	if err := d.checkHostModules(ctx); err != nil {
+		return err
+	}
+
+	plan := d.buildRestartPlan(ctx)
+	log.Info("Driver restart plan", "plan", plan)
+
 	// Load dependencies for all loaded modules from host
 	if err := d.loadHostDependencies(ctx); err != nil {
 		log.V(1).Info("Failed to load host dependencies", "error", err)
 		// Non-fatal, continue
 	}
 
-	// Load pci-hyperv-intf if needed (simplified logic)
-	arch := d.getArchitecture(ctx)
-	if arch != "aarch64" {
-		_, _, err := d.cmd.RunCommand(ctx, "modprobe", "-d", "/host", "pci-hyperv-intf")
+	if plan.LoadPCIHypervIntf {
+		_, _, err := d.modprobeHostModule(ctx, "pci-hyperv-intf")
 		if err != nil {
 			log.V(1).Info("Failed to load pci-hyperv-intf module", "error", err)
 			// Non-fatal, continue
@@ -1959,7 +3977,7 @@ func (d *driverMgr) restartDriver(ctx context.Context) error {
 	}
 
 	// Unload storage modules if enabled
-	if d.cfg.UnloadStorageModules {
+	if plan.UnloadStorageModules {
 		if err := d.unloadStorageModules(ctx); err != nil {
 			log.V(1).Info("Failed to unload storage modules", "error", err)
 			// Non-fatal, continue
@@ -1968,8 +3986,8 @@ func (d *driverMgr) restartDriver(ctx context.Context) error {
 
 	unloadedMlx5AuxiliaryModules := d.unloadMlx5AuxiliaryModules(ctx)
 
-	// Restart openibd service
-	_, _, err := d.cmd.RunCommand(ctx, "/etc/init.d/openibd", "restart")
+	// Restart openibd service, it may take a while so report progress
+	_, _, err := d.runWithHeartbeat(ctx, plan.OpenibdCommand[0], plan.OpenibdCommand[1:]...)
 	if err != nil {
 		return fmt.Errorf("failed to restart openibd service: %w", err)
 	}
@@ -1981,6 +3999,59 @@ func (d *driverMgr) restartDriver(ctx context.Context) error {
 	return nil
 }
 
+// restartPlan holds the decisions of a driver restart, it is logged as one record before the
+// restart so that the reload of a node can be reproduced
+type restartPlan struct {
+	HostRoot string `json:"hostRoot"`
+	Arch     string `json:"arch"`
+	Fabric   string `json:"fabric"`
+	// LoadPCIHypervIntf is false on aarch64 and on InfiniBand, only Ethernet (netvsc) devices use it
+	LoadPCIHypervIntf bool `json:"loadPCIHypervIntf"`
+	// StorageModules are added to the openibd unload list when UnloadStorageModules is set
+	UnloadStorageModules bool     `json:"unloadStorageModules"`
+	StorageModules       []string `json:"storageModules,omitempty"`
+	Mlx5AuxiliaryModules []string `json:"mlx5AuxiliaryModules,omitempty"`
+	LoadVdpa             bool     `json:"loadVdpa"`
+	OpenibdCommand       []string `json:"openibdCommand"`
+}
+
+// buildRestartPlan collects the decisions of the driver restart before any module is touched
+func (d *driverMgr) buildRestartPlan(ctx context.Context) restartPlan {
+	plan := restartPlan{
+		HostRoot:             d.hostRoot(),
+		Arch:                 d.getArchitecture(ctx),
+		Fabric:               d.getFabric(ctx),
+		UnloadStorageModules: d.cfg.UnloadStorageModules,
+		Mlx5AuxiliaryModules: d.cfg.Mlx5AuxiliaryModules,
+		LoadVdpa:             d.cfg.LoadVdpa && slices.Contains(d.cfg.Mlx5AuxiliaryModules, moduleMlx5Vdpa),
+	}
+	// Load pci-hyperv-intf if needed (simplified logic), it is only used by Ethernet (netvsc) devices
+	plan.LoadPCIHypervIntf = plan.Arch != "aarch64" && plan.Fabric != constants.FabricIB
+	if plan.UnloadStorageModules {
+		plan.StorageModules = d.cfg.StorageModules
+	}
+	command, args := d.hostCommand(d.openibdScriptPath(), "restart")
+	plan.OpenibdCommand = append([]string{command}, args...)
+	return plan
+}
+
+// checkHostModules verifies the host modules are mounted under HostRoot, modprobe -d can't load the
+// host inbox modules without them. A missing path only fails the restart with StrictHostModules.
+func (d *driverMgr) checkHostModules(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	modulesPath := filepath.Join(d.hostRoot(), "lib", "modules")
+	if _, err := d.os.Stat(modulesPath); err != nil {
+		if d.cfg.StrictHostModules {
+			return fmt.Errorf("%w: %s (host kernel modules for modprobe -d)", ErrMissingHostMounts, modulesPath)
+		}
+		log.Info("[WARN] Host modules path is missing, the host inbox modules won't be loaded",
+			"path", modulesPath, "error", err)
+		// Non-fatal error, continue
+	}
+	return nil
+}
+
 func (d *driverMgr) unloadMlx5AuxiliaryModules(ctx context.Context) map[string]struct{} {
 	log := logr.FromContextOrDiscard(ctx)
 	unloadedModules := map[string]struct{}{}
@@ -2021,10 +4092,19 @@ func (d *driverMgr) loadMlx5AuxiliaryModules(ctx context.Context, unloadedModule
 			continue
 		}
 
+		if module == moduleMlx5Vdpa && !d.cfg.LoadVdpa {
+			log.V(1).Info("Loading of mlx5 auxiliary module is disabled, skipping", "module", module)
+			continue
+		}
+		required := module == moduleMlx5Vdpa && d.cfg.RequireVdpa
+
 		if _, _, err := d.cmd.RunCommand(ctx, "modinfo", module); err != nil {
 			if _, wasUnloaded := unloadedModules[module]; wasUnloaded {
 				return fmt.Errorf("failed to find previously unloaded mlx5 auxiliary module %s after driver restart: %w", module, err)
 			}
+			if required {
+				return fmt.Errorf("required mlx5 auxiliary module %s not found: %w", module, err)
+			}
 			log.V(1).Info("mlx5 auxiliary module not found, skipping", "module", module)
 			continue
 		}
@@ -2040,6 +4120,9 @@ func (d *driverMgr) loadMlx5AuxiliaryModules(ctx context.Context, unloadedModule
 			if _, wasUnloaded := unloadedModules[module]; wasUnloaded {
 				return fmt.Errorf("failed to reload previously unloaded mlx5 auxiliary module %s: %w", module, err)
 			}
+			if required {
+				return fmt.Errorf("failed to load required mlx5 auxiliary module %s: %w", module, err)
+			}
 		}
 	}
 
@@ -2064,6 +4147,13 @@ func (d *driverMgr) loadNfsRdma(ctx context.Context) error {
 
 	log.V(1).Info("Loading NFS RDMA modules")
 
+	// On InfiniBand RDMA CM resolves the NFS server address through IPoIB
+	if d.getFabric(ctx) == constants.FabricIB {
+		if _, _, err := d.cmd.RunCommand(ctx, "modprobe", "ib_ipoib"); err != nil {
+			return fmt.Errorf("failed to load ib_ipoib module: %w", err)
+		}
+	}
+
 	_, _, err := d.cmd.RunCommand(ctx, "modprobe", "rpcrdma")
 	if err != nil {
 		return fmt.Errorf("failed to load rpcrdma module: %w", err)
@@ -2072,53 +4162,342 @@ func (d *driverMgr) loadNfsRdma(ctx context.Context) error {
 	return nil
 }
 
+// loadPostRestartModules loads the PostRestartModules in order, a module that fails to load doesn't
+// prevent loading the next ones
+func (d *driverMgr) loadPostRestartModules(ctx context.Context) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	for _, module := range d.cfg.PostRestartModules {
+		module, ok := sanitizeKernelModuleName(module)
+		if !ok {
+			if module != "" {
+				log.Info("[WARN] Skipping invalid post-restart module name", "module", module)
+			}
+			continue
+		}
+
+		if _, _, err := d.cmd.RunCommand(ctx, "modprobe", module); err != nil {
+			log.Info("[WARN] Failed to load post-restart module", "module", module, "error", err)
+			// Non-fatal error, continue
+			continue
+		}
+		log.V(1).Info("Loaded post-restart module", "module", module)
+	}
+}
+
+// getFabric returns the configured Fabric, or for auto the one detected from the link types
+// of the Mellanox netdevs. Mixed, which loads all modules, is returned when the fabric
+// is not set or can't be determined.
+func (d *driverMgr) getFabric(ctx context.Context) string {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if d.fabric != "" {
+		return d.fabric
+	}
+
+	switch d.cfg.Fabric {
+	case constants.FabricEth, constants.FabricIB, constants.FabricMixed:
+		d.fabric = d.cfg.Fabric
+	case "":
+		d.fabric = constants.FabricMixed
+	case constants.FabricAuto:
+		d.fabric = d.detectFabric(ctx)
+	default:
+		log.Info("[WARN] unknown fabric, detecting it", "fabric", d.cfg.Fabric)
+		d.fabric = d.detectFabric(ctx)
+	}
+	log.V(1).Info("Using fabric", "fabric", d.fabric)
+	return d.fabric
+}
+
+// detectFabric derives the fabric from the ARPHRD link type in /sys/class/net/<dev>/type
+// of the Mellanox netdevs
+func (d *driverMgr) detectFabric(ctx context.Context) string {
+	log := logr.FromContextOrDiscard(ctx)
+
+	// ARPHRD_ETHER and ARPHRD_INFINIBAND from linux/if_arp.h
+	const (
+		linkTypeEther      = "1"
+		linkTypeInfiniband = "32"
+	)
+
+	entries, err := d.os.ReadDir(d.sysfsPath("class", "net"))
+	if err != nil {
+		log.V(1).Info("Failed to list network devices, assuming mixed fabric", "error", err)
+		return constants.FabricMixed
+	}
+
+	var hasEth, hasIB bool
+	for _, entry := range entries {
+		vendor, err := d.os.ReadFile(d.sysfsPath("class", "net", entry.Name(), "device", "vendor"))
+		if err != nil || strings.TrimSpace(string(vendor)) != "0x15b3" {
+			continue
+		}
+		linkType, err := d.os.ReadFile(d.sysfsPath("class", "net", entry.Name(), "type"))
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(string(linkType)) {
+		case linkTypeEther:
+			hasEth = true
+		case linkTypeInfiniband:
+			hasIB = true
+		}
+	}
+
+	switch {
+	case hasEth && !hasIB:
+		return constants.FabricEth
+	case hasIB && !hasEth:
+		return constants.FabricIB
+	default:
+		return constants.FabricMixed
+	}
+}
+
+// checkMlx5CoreSize warns when the loaded mlx5_core is smaller than Mlx5CoreMinSize, which means
+// a stub or dummy module was loaded instead of the driver. Returns false when the check fails.
+func (d *driverMgr) checkMlx5CoreSize(ctx context.Context) bool {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if d.cfg.Mlx5CoreMinSize <= 0 {
+		return true
+	}
+
+	loadedModules, err := d.host.LsMod(ctx)
+	if err != nil {
+		log.V(1).Info("Failed to check mlx5_core size", "error", err)
+		return true
+	}
+	module, loaded := loadedModules[moduleMlx5Core]
+	if !loaded {
+		return true
+	}
+	if module.Size < d.cfg.Mlx5CoreMinSize {
+		log.Info("[WARN] loaded mlx5_core is implausibly small, a stub or dummy module may have been loaded instead of the driver",
+			"size", module.Size, "minSize", d.cfg.Mlx5CoreMinSize)
+		return false
+	}
+	return true
+}
+
+// signatureFailureMarkers are the kernel log messages of a module signature verification failure.
+// "module verification failed" isn't one of them, it is the taint message of a module that was loaded.
+var signatureFailureMarkers = []string{
+	"loading of unsigned module is rejected",
+	"key was rejected by service",
+	"required key not available",
+}
+
+// readKernelLog returns the kernel log read with dmesg, or journalctl -k when dmesg isn't usable
+func (d *driverMgr) readKernelLog(ctx context.Context) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	kernelLog, _, err := d.cmd.RunCommand(ctx, "dmesg")
+	if err != nil {
+		log.V(1).Info("Failed to read kernel log with dmesg, trying journalctl", "error", err)
+		kernelLog, _, err = d.cmd.RunCommand(ctx, "journalctl", "-k", "-b", "--no-pager")
+	}
+	return kernelLog, err
+}
+
+// kernelLogMark returns the last kernel log line before the driver reload for checkSecureBootLoad,
+// or an empty string when CheckSecureBootLoad is disabled or the kernel log can't be read
+func (d *driverMgr) kernelLogMark(ctx context.Context) string {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if !d.cfg.CheckSecureBootLoad {
+		return ""
+	}
+	kernelLog, err := d.readKernelLog(ctx)
+	if err != nil {
+		log.V(1).Info("Failed to read kernel log before the driver reload", "error", err)
+		// Non-fatal error, continue
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(kernelLog, "\n"), "\n")
+	return lines[len(lines)-1]
+}
+
+// checkSecureBootLoad fails with ErrSecureBootRejected when the kernel log reports a signature
+// verification failure of a driver module, for CheckSecureBootLoad. Only the lines after mark, the
+// last line before the reload, are checked; the whole log is checked when mark is empty or was
+// rotated out. The check is skipped when the kernel log can't be read.
+func (d *driverMgr) checkSecureBootLoad(ctx context.Context, mark string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if !d.cfg.CheckSecureBootLoad {
+		return nil
+	}
+
+	kernelLog, err := d.readKernelLog(ctx)
+	if err != nil {
+		log.Info("[WARN] Failed to read kernel log, skipping Secure Boot load check", "error", err)
+		// Non-fatal error, continue
+		return nil
+	}
+
+	lines := strings.Split(kernelLog, "\n")
+	if mark != "" {
+		if i := slices.Index(lines, mark); i >= 0 {
+			lines = lines[i+1:]
+		}
+	}
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		if !strings.Contains(lower, "mlx") && !slices.ContainsFunc(d.driverModules(), func(module string) bool {
+			return strings.Contains(lower, module)
+		}) {
+			continue
+		}
+		for _, marker := range signatureFailureMarkers {
+			if strings.Contains(lower, marker) {
+				return fmt.Errorf("%w: %q, Secure Boot may be enabled, sign the driver modules with a key "+
+					"enrolled in the MOK list or disable Secure Boot", ErrSecureBootRejected, strings.TrimSpace(line))
+			}
+		}
+	}
+	return nil
+}
+
+// runPostLoadHook runs the PostLoadHook command through sh, passing the loaded driver
+// and kernel versions as LOADED_DRIVER_VERSION and KERNEL_VERSION environment variables
+func (d *driverMgr) runPostLoadHook(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if d.cfg.PostLoadHook == "" {
+		return nil
+	}
+
+	driverVersion, err := d.getLoadedDriverVersion(ctx)
+	if err != nil {
+		log.V(1).Info("Failed to get loaded driver version for post-load hook", "error", err)
+		// Non-fatal error, continue
+	}
+	kernelVersion, err := d.host.GetKernelVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get kernel version: %w", err)
+	}
+
+	log.Info("Running post-load hook", "hook", d.cfg.PostLoadHook)
+	// A failed hook error already carries its stderr
+	_, _, err = d.cmd.RunCommandWithEnv(ctx, map[string]string{
+		"LOADED_DRIVER_VERSION": driverVersion,
+		"KERNEL_VERSION":        kernelVersion,
+	}, "sh", "-c", d.cfg.PostLoadHook)
+	return err
+}
+
 // printLoadedDriverVersion prints the currently loaded driver version
 func (d *driverMgr) printLoadedDriverVersion(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
 
+	version, err := d.getLoadedDriverVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version != "" {
+		log.Info("Current mlx5_core driver version", "version", version)
+	}
+
+	return nil
+}
+
+// getLoadedDriverVersion returns the mlx5_core driver version reported by ethtool (or by the module
+// when ethtool is missing), or an empty string if it can't be determined (module not loaded, no Mellanox netdev)
+func (d *driverMgr) getLoadedDriverVersion(ctx context.Context) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
 	// Check if mlx5_core is loaded using host interface
 	loadedModules, err := d.host.LsMod(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to check loaded modules: %w", err)
+		return "", fmt.Errorf("failed to check loaded modules: %w", err)
 	}
 
 	// Check if mlx5_core is loaded
 	if _, exists := loadedModules[moduleMlx5Core]; !exists {
 		log.V(1).Info("mlx5_core module not loaded")
-		return nil
+		return "", nil
 	}
 
 	// Get first Mellanox network device name
 	netdevName, err := d.getFirstMlxNetdevName(ctx)
 	if err != nil {
 		log.V(1).Info("No Mellanox network device found", "error", err)
-		return nil
+		return "", nil
 	}
 
 	// Get driver version via ethtool
 	ethtoolOutput, _, err := d.cmd.RunCommand(ctx, "ethtool", "--driver", netdevName)
 	if err != nil {
+		var notFoundErr *cmd.ErrCommandNotFound
+		if errors.As(err, &notFoundErr) {
+			// Minimal images don't ship ethtool, read the version from the module instead
+			log.V(1).Info("ethtool not found, falling back to the mlx5_core module version")
+			return d.getModuleDriverVersion(ctx), nil
+		}
 		log.V(1).Info("Failed to get driver version via ethtool", "error", err)
-		return nil
+		return "", nil
 	}
 
-	// Extract version from ethtool output
-	lines := strings.Split(ethtoolOutput, "\n")
-	for _, line := range lines {
+	return parseVersionField(ethtoolOutput), nil
+}
+
+// getModuleDriverVersion returns the mlx5_core version from sysfs, or from modinfo when the loaded
+// module doesn't expose it, and an empty string if neither reports one
+func (d *driverMgr) getModuleDriverVersion(ctx context.Context) string {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if data, err := d.os.ReadFile(d.sysfsPath("module", moduleMlx5Core, "version")); err == nil {
+		if version := strings.TrimSpace(string(data)); version != "" {
+			return version
+		}
+	}
+
+	modinfoOutput, _, err := d.cmd.RunCommand(ctx, "modinfo", moduleMlx5Core)
+	if err != nil {
+		log.V(1).Info("Failed to get driver version via modinfo", "error", err)
+		return ""
+	}
+	return parseVersionField(modinfoOutput)
+}
+
+// parseVersionField returns the value of the "version:" line of the ethtool or modinfo output
+func parseVersionField(output string) string {
+	for _, line := range strings.Split(output, "\n") {
 		if strings.HasPrefix(line, "version:") {
-			version := strings.TrimSpace(strings.TrimPrefix(line, "version:"))
-			log.Info("Current mlx5_core driver version", "version", version)
-			break
+			return strings.TrimSpace(strings.TrimPrefix(line, "version:"))
 		}
 	}
+	return ""
+}
 
-	return nil
+// runningDriverVersionMatches returns true if SkipReloadOnVersionMatch is set, the container runs
+// precompiled packages and the running driver already has the version of these packages
+func (d *driverMgr) runningDriverVersionMatches(ctx context.Context) bool {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if !d.cfg.SkipReloadOnVersionMatch || d.containerMode != constants.DriverContainerModePrecompiled {
+		return false
+	}
+
+	running, err := d.getLoadedDriverVersion(ctx)
+	if err != nil {
+		log.V(1).Info("Failed to get running driver version", "error", err)
+		return false
+	}
+	// ethtool may report the version without the trailing build number of the package version,
+	// e.g. 25.04-0.6.1 for 25.04-0.6.1.0
+	expected := d.cfg.NvidiaNicDriverVer
+	match := running != "" && (running == expected || strings.HasPrefix(expected, running+"."))
+	log.V(1).Info("Compared running driver version", "running", running, "expected", expected, "match", match)
+	return match
 }
 
 // getFirstMlxNetdevName gets the first Mellanox network device name
 func (d *driverMgr) getFirstMlxNetdevName(ctx context.Context) (string, error) {
 	// List network devices
-	netdevOutput, _, err := d.cmd.RunCommand(ctx, "ls", "/sys/class/net/")
+	netdevOutput, _, err := d.cmd.RunCommand(ctx, "ls", d.sysfsPath("class", "net")+"/")
 	if err != nil {
 		return "", fmt.Errorf("failed to list network devices: %w", err)
 	}
@@ -2126,7 +4505,7 @@ func (d *driverMgr) getFirstMlxNetdevName(ctx context.Context) (string, error) {
 	devices := strings.Fields(netdevOutput)
 	for _, device := range devices {
 		// Check if this is a Mellanox device by looking at driver
-		driverPath := fmt.Sprintf("/sys/class/net/%s/device/driver", device)
+		driverPath := d.sysfsPath("class", "net", device, "device", "driver")
 		driverLink, _, err := d.cmd.RunCommand(ctx, "readlink", driverPath)
 		if err != nil {
 			continue
@@ -2146,8 +4525,13 @@ func (d *driverMgr) unloadStorageModules(ctx context.Context) error {
 
 	log.V(1).Info("Unloading storage modules")
 
+	if len(d.cfg.StorageModules) == 0 {
+		log.V(1).Info("No storage modules configured, skipping unload script modification")
+		return nil
+	}
+
 	// Determine the unload storage script path
-	unloadStorageScript := "/etc/init.d/openibd"
+	unloadStorageScript := d.openibdScriptPath()
 	if _, err := d.os.Stat("/usr/share/mlnx_ofed/mod_load_funcs"); err == nil {
 		unloadStorageScript = "/usr/share/mlnx_ofed/mod_load_funcs"
 	}
@@ -2168,19 +4552,22 @@ func (d *driverMgr) unloadStorageModules(ctx context.Context) error {
 		return fmt.Errorf("failed to modify unload storage script: %w", err)
 	}
 
-	// Verify the modification was successful by checking if storage modules are now in the script
-	// This matches the bash script: if [ `grep ib_isert ${unload_storage_script} -c` -lt 1 ]; then
-	grepCmd := fmt.Sprintf("grep %s %s -c", d.cfg.StorageModules[0], unloadStorageScript)
-	_, stdout, err := d.cmd.RunCommand(ctx, "sh", "-c", grepCmd)
-	if err != nil {
-		return fmt.Errorf("failed to verify storage modules injection: %w", err)
+	// Verify the modification was successful by checking that every storage module is now in the script
+	// This extends the bash script check (grep ib_isert ${unload_storage_script} -c) to the full list
+	var failedModules []string
+	for _, module := range d.cfg.StorageModules {
+		grepCmd := fmt.Sprintf("grep -c -w %s %s", module, unloadStorageScript)
+		// grep -c exits non-zero when there are no matches, so an error means the module is missing
+		stdout, _, err := d.cmd.RunCommand(ctx, "sh", "-c", grepCmd)
+		count := strings.TrimSpace(stdout)
+		log.V(1).Info("Verification result", "grepCmd", grepCmd, "count", count, "error", err)
+		if err != nil || count == "" || count == "0" {
+			failedModules = append(failedModules, module)
+		}
 	}
 
-	count := strings.TrimSpace(stdout)
-	log.V(1).Info("Verification result", "grepCmd", grepCmd, "count", count)
-
-	if count == "0" {
-		return fmt.Errorf("failed to inject storage modules for unload")
+	if len(failedModules) > 0 {
+		return fmt.Errorf("failed to inject storage modules for unload: %s", strings.Join(failedModules, ", "))
 	}
 
 	log.V(1).Info("Successfully added storage modules to unload script", "modules", d.cfg.StorageModules)
@@ -2202,6 +4589,35 @@ func (d *driverMgr) setupSpecialKernelRepos(ctx context.Context) error {
 	return nil
 }
 
+// setupSLESSpecialKernelRepos copies the zypper repos and credentials of the host for the non-default
+// SLES kernel flavors, whose devel packages are only in the repos the host is registered to
+func (d *driverMgr) setupSLESSpecialKernelRepos(ctx context.Context, kernelVersion, flavor string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if !d.cfg.CopyHostZyppRepos || flavor == "default" {
+		return nil
+	}
+
+	log.Info("Copying host zypper repositories for special SLES kernel", "kernel", kernelVersion, "flavor", flavor)
+
+	_, _, err := d.cmd.RunCommand(ctx, "cp", "-rf", "/host/etc/zypp/repos.d/.", "/etc/zypp/repos.d")
+	if err != nil {
+		return fmt.Errorf("failed to copy host zypper repos: %w", err)
+	}
+
+	// The credentials of the SUSEConnect registered repos, not all hosts have them
+	if _, err := d.os.Stat("/host/etc/zypp/credentials.d"); err != nil {
+		log.V(1).Info("No host zypper credentials to copy", "error", err)
+		return nil
+	}
+	_, _, err = d.cmd.RunCommand(ctx, "cp", "-rf", "/host/etc/zypp/credentials.d/.", "/etc/zypp/credentials.d")
+	if err != nil {
+		return fmt.Errorf("failed to copy host zypper credentials: %w", err)
+	}
+
+	return nil
+}
+
 // installRedHatDependencies installs additional RedHat dependencies
 func (d *driverMgr) installRedHatDependencies(ctx context.Context, versionInfo *host.RedhatVersionInfo) error {
 	log := logr.FromContextOrDiscard(ctx)
@@ -2223,63 +4639,97 @@ func (d *driverMgr) installRedHatDependencies(ctx context.Context, versionInfo *
 	args = append(args, dnfCmd, dnfFlagQuiet, dnfFlagYes, "--releasever="+versionInfo.FullVersion, "install")
 	args = append(args, packages...)
 
-	_, _, err := d.cmd.RunCommand(ctx, args[0], args[1:]...)
+	_, _, err := d.runPackageManager(ctx, args[0], args[1:]...)
 	if err != nil {
 		return fmt.Errorf("failed to install RedHat dependencies: %w", err)
 	}
 
 	// Test makecache and disable EUS if it fails
-	_, _, err = d.cmd.RunCommand(ctx, dnfCmd, "makecache", "--releasever="+versionInfo.FullVersion)
+	_, _, err = d.runPackageManager(ctx, dnfCmd, "makecache", "--releasever="+versionInfo.FullVersion)
 	if err != nil {
 		log.V(1).Info("Makecache failed, disabling EUS repository", "error", err)
 		arch := d.getArchitecture(ctx)
 		repoName := fmt.Sprintf("rhel-%d-for-%s-baseos-eus-rpms", versionInfo.MajorVersion, arch)
-		_, _, _ = d.cmd.RunCommand(ctx, dnfCmd, "config-manager", "--set-disabled", repoName)
+		d.disableRepo(ctx, repoName)
 	}
 
 	return nil
 }
 
-// updateCACertificates updates system CA certificates for supported OS types
-func (d *driverMgr) updateCACertificates(ctx context.Context) error {
-	log := logr.FromContextOrDiscard(ctx)
+// caTrustStore describes how CA certificates are managed on a given OS
+type caTrustStore struct {
+	// updateCommand regenerates the system CA bundle
+	updateCommand string
+	// anchorsDir is where additional CA certificates are picked up from by updateCommand
+	anchorsDir string
+	label      string
+}
 
+// caTrustStoreForOS returns the CA trust store layout for the OS type, ok is false for unsupported OS
+func caTrustStoreForOS(osType string) (caTrustStore, bool) {
 	// Constants for CA certificate update commands
 	const updateCaCertificatesCmd = "update-ca-certificates"
 	const updateCaTrustCmd = "update-ca-trust extract"
 
+	switch osType {
+	case constants.OSTypeUbuntu:
+		return caTrustStore{
+			updateCommand: updateCaCertificatesCmd,
+			anchorsDir:    "/usr/local/share/ca-certificates",
+			label:         "Ubuntu",
+		}, true
+	case constants.OSTypeSLES:
+		return caTrustStore{
+			updateCommand: updateCaCertificatesCmd,
+			anchorsDir:    "/etc/pki/trust/anchors",
+			label:         "SLES",
+		}, true
+	case constants.OSTypeRedHat, constants.OSTypeOpenShift:
+		return caTrustStore{
+			updateCommand: updateCaTrustCmd,
+			anchorsDir:    "/etc/pki/ca-trust/source/anchors",
+			label:         "RHEL/OpenShift",
+		}, true
+	default:
+		return caTrustStore{}, false
+	}
+}
+
+// RefreshCACertificates copies custom CA certificates (if configured) into the system trust store
+// and updates system CA certificates. It is safe to call multiple times.
+func (d *driverMgr) RefreshCACertificates(ctx context.Context) error {
 	// Get OS type to determine the appropriate CA certificate update command
 	osType, err := d.host.GetOSType(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get OS type: %w", err)
 	}
+	return d.updateCACertificates(ctx, osType)
+}
 
-	// Determine the command and log message based on OS type
-	var command string
-	var logMessage string
+// updateCACertificates updates system CA certificates for supported OS types
+func (d *driverMgr) updateCACertificates(ctx context.Context, osType string) error {
+	log := logr.FromContextOrDiscard(ctx)
 
-	switch osType {
-	case constants.OSTypeUbuntu:
-		command = updateCaCertificatesCmd
-		logMessage = "Updating system CA certificates (Ubuntu)..."
-	case constants.OSTypeSLES:
-		command = updateCaCertificatesCmd
-		logMessage = "Updating system CA certificates (SLES)..."
-	case constants.OSTypeRedHat, constants.OSTypeOpenShift:
-		command = updateCaTrustCmd
-		logMessage = "Updating system CA certificates (RHEL/OpenShift)..."
-	default:
+	store, ok := caTrustStoreForOS(osType)
+	if !ok {
 		log.V(1).Info("Skipping CA certificate update for unsupported OS", "os", osType)
 		return nil
 	}
 
-	log.Info(logMessage)
+	if d.cfg.CustomCACertsDir != "" {
+		if err := d.copyCustomCACertificates(ctx, store.anchorsDir); err != nil {
+			log.Info("[WARN] failed to copy custom CA certificates", "dir", d.cfg.CustomCACertsDir, "error", err)
+			// Non-fatal error, continue
+		}
+	}
+
+	log.Info(fmt.Sprintf("Updating system CA certificates (%s)...", store.label))
 
 	// Extract the base command for existence check (remove arguments)
-	baseCommand := strings.Fields(command)[0]
+	baseCommand := strings.Fields(store.updateCommand)[0]
 
 	// Check if the command exists using shell with 'command -v'
-	_, _, err = d.cmd.RunCommand(ctx, "sh", "-c", "command -v "+baseCommand)
+	_, _, err := d.cmd.RunCommand(ctx, "sh", "-c", "command -v "+baseCommand)
 	if err != nil {
 		log.Info("[WARN] CA certificate update command not found", "command", baseCommand)
 		// Command not found is not a fatal error, continue execution
@@ -2288,9 +4738,9 @@ func (d *driverMgr) updateCACertificates(ctx context.Context) error {
 
 	// Run the appropriate command with || true to ignore errors
 	// This matches the bash script pattern: exec_cmd "command || true"
-	_, _, err = d.cmd.RunCommand(ctx, "sh", "-c", command+" || true")
+	_, _, err = d.cmd.RunCommand(ctx, "sh", "-c", store.updateCommand+" || true")
 	if err != nil {
-		log.V(1).Info("CA certificate update command failed", "command", command, "error", err)
+		log.V(1).Info("CA certificate update command failed", "command", store.updateCommand, "error", err)
 		// Non-fatal error, continue
 	}
 
@@ -2298,6 +4748,35 @@ func (d *driverMgr) updateCACertificates(ctx context.Context) error {
 	return nil
 }
 
+// copyCustomCACertificates copies regular files from the custom CA certs dir into anchorsDir
+func (d *driverMgr) copyCustomCACertificates(ctx context.Context, anchorsDir string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	entries, err := d.os.ReadDir(d.cfg.CustomCACertsDir)
+	if err != nil {
+		return fmt.Errorf("failed to read custom CA certs dir: %w", err)
+	}
+	if err := d.os.MkdirAll(anchorsDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create CA anchors dir %s: %w", anchorsDir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		src := filepath.Join(d.cfg.CustomCACertsDir, entry.Name())
+		data, err := d.os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificate %s: %w", src, err)
+		}
+		dst := filepath.Join(anchorsDir, entry.Name())
+		if err := d.os.WriteFile(dst, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write CA certificate %s: %w", dst, err)
+		}
+		log.V(1).Info("Copied custom CA certificate", "src", src, "dst", dst)
+	}
+	return nil
+}
+
 // enableFIPSIfRequired enables Ubuntu Pro FIPS mode if UBUNTU_PRO_TOKEN is set.
 // This function:
 // 1. Checks for the UBUNTU_PRO_TOKEN environment variable
@@ -2354,7 +4833,7 @@ func (d *driverMgr) enableFIPSIfRequired(ctx context.Context) error {
 
 	// Install Ubuntu FIPS userspace packages
 	log.Info("Installing the OpenSSL FIPS modules")
-	if _, _, err := d.cmd.RunCommand(ctx, "apt-get", "-yqq", "install", "--no-install-recommends", "ubuntu-fips-userspace"); err != nil {
+	if _, _, err := d.runPackageManager(ctx, "apt-get", "-yqq", "install", "--no-install-recommends", "ubuntu-fips-userspace"); err != nil {
 		return fmt.Errorf("failed to install ubuntu-fips-userspace: %w", err)
 	}
 
@@ -2568,9 +5047,9 @@ func (d *driverMgr) dkmsAdd(ctx context.Context, moduleName, moduleVersion strin
 	}
 
 	// Add module to DKMS
-	_, stderr, err := d.cmd.RunCommand(ctx, "dkms", "add", "-m", moduleName, "-v", moduleVersion)
+	_, _, err = d.cmd.RunCommand(ctx, "dkms", "add", "-m", moduleName, "-v", moduleVersion)
 	if err != nil {
-		return fmt.Errorf("failed to add DKMS module: %w, stderr: %s", err, stderr)
+		return fmt.Errorf("failed to add DKMS module: %w", err)
 	}
 
 	log.Info("DKMS module added successfully", "name", moduleName, "version", moduleVersion)
@@ -2602,9 +5081,9 @@ func (d *driverMgr) dkmsBuild(ctx context.Context, moduleName, moduleVersion, ke
 	}
 
 	// Build module for current kernel
-	stdout, stderr, err := d.cmd.RunCommand(ctx, "dkms", "build", "-m", moduleName, "-v", moduleVersion, "-k", kernelVersion)
+	stdout, _, err := d.cmd.RunCommand(ctx, "dkms", "build", "-m", moduleName, "-v", moduleVersion, "-k", kernelVersion)
 	if err != nil {
-		return fmt.Errorf("failed to build DKMS module: %w, stdout: %s, stderr: %s", err, stdout, stderr)
+		return fmt.Errorf("failed to build DKMS module: %w, stdout: %s", err, stdout)
 	}
 
 	log.Info("DKMS module built successfully", "name", moduleName, "version", moduleVersion, "kernel", kernelVersion)
@@ -2628,9 +5107,9 @@ func (d *driverMgr) dkmsInstall(ctx context.Context, moduleName, moduleVersion,
 	}
 
 	// Install module
-	_, stderr, err := d.cmd.RunCommand(ctx, "dkms", "install", "-m", moduleName, "-v", moduleVersion, "-k", kernelVersion)
+	_, _, err = d.cmd.RunCommand(ctx, "dkms", "install", "-m", moduleName, "-v", moduleVersion, "-k", kernelVersion)
 	if err != nil {
-		return fmt.Errorf("failed to install DKMS module: %w, stderr: %s", err, stderr)
+		return fmt.Errorf("failed to install DKMS module: %w", err)
 	}
 
 	log.Info("DKMS module installed successfully", "name", moduleName, "version", moduleVersion, "kernel", kernelVersion)
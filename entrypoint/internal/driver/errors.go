@@ -0,0 +1,51 @@
+/*
+ Copyright 2025, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package driver
+
+import "errors"
+
+// Errors returned (wrapped) by the driver.Interface methods, match them with errors.Is
+var (
+	// ErrUnknownContainerMode is returned by PreStart for a containerMode other than sources or precompiled
+	ErrUnknownContainerMode = errors.New("unknown containerMode")
+	// ErrMissingDriverPath is returned by PreStart in sources mode when NVIDIA_NIC_DRIVER_PATH is not set
+	ErrMissingDriverPath = errors.New("NVIDIA_NIC_DRIVER_PATH environment variable must be set")
+	// ErrMissingHostMounts is returned by PreStart when host paths the container depends on are not mounted
+	ErrMissingHostMounts = errors.New("required host mounts are missing")
+	// ErrUnsupportedOS is returned when the host OS type has no build or install support
+	ErrUnsupportedOS = errors.New("unsupported OS type")
+	// ErrMissingPackage is returned by Build with OFFLINE_BUILD when a required package isn't installed
+	ErrMissingPackage = errors.New("missing required package")
+	// ErrPinnedDriverNotFound is returned by Build when the PINNED_DRIVER_VER packages aren't in the inventory
+	ErrPinnedDriverNotFound = errors.New("pinned driver version not found in inventory")
+	// ErrBuildFailed is returned by Build when compiling the driver fails
+	ErrBuildFailed = errors.New("failed to build driver")
+	// ErrRestartFailed is returned by Load when the driver modules can't be reloaded
+	ErrRestartFailed = errors.New("failed to restart driver")
+	// ErrReloadNotApplied is returned by Load with VerifyReload when the loaded modules still don't match
+	// the candidate driver after the driver restart
+	ErrReloadNotApplied = errors.New("driver restart did not update loaded modules")
+	// ErrKernelHeadersMismatch is returned by Build when the installed kernel headers don't match the running kernel
+	ErrKernelHeadersMismatch = errors.New("installed kernel headers don't match the running kernel")
+	// ErrSecureBootRejected is returned by Load with CheckSecureBootLoad when the kernel rejected a driver module signature
+	ErrSecureBootRejected = errors.New("driver module signature rejected by the kernel")
+	// ErrPrecompiledKernelChanged is returned by Reconcile in precompiled mode when the host kernel changed since
+	// the driver was loaded, the precompiled driver can't be rebuilt for the new kernel
+	ErrPrecompiledKernelChanged = errors.New("host kernel changed, the precompiled driver can't be rebuilt for it")
+	// ErrInsufficientDiskSpace is returned by Build when there is less than MinBuildDiskBytes free for the build
+	ErrInsufficientDiskSpace = errors.New("not enough free disk space for the driver build")
+)
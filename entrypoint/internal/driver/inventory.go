@@ -0,0 +1,121 @@
+/*
+ Copyright 2026, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// InventoryEntry describes the packages of one driver version cached for one kernel in the inventory
+type InventoryEntry struct {
+	KernelVersion string `json:"kernelVersion"`
+	DriverVersion string `json:"driverVersion"`
+	// Arch is set with the InventoryArchSubdir layout
+	Arch     string `json:"arch,omitempty"`
+	Path     string `json:"path"`
+	Checksum string `json:"checksum"`
+	// BuildInfo is the stored build config fingerprint on a single line
+	BuildInfo    string `json:"buildInfo"`
+	PackageCount int    `json:"packageCount"`
+	// Incomplete is set when the checksum file is missing, Build rebuilds such an entry
+	Incomplete bool `json:"incomplete"`
+}
+
+// ListInventory walks NvidiaNicDriversInventoryPath and returns its <kver>/<driverVer> entries
+// in directory name order
+func (d *driverMgr) ListInventory(ctx context.Context) ([]InventoryEntry, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	root := d.cfg.NvidiaNicDriversInventoryPath
+	if root == "" {
+		return nil, fmt.Errorf("NVIDIA_NIC_DRIVERS_INVENTORY_PATH is not set")
+	}
+
+	kernelDirs, err := d.os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inventory directory: %w", err)
+	}
+
+	var entries []InventoryEntry
+	for _, kernelDir := range kernelDirs {
+		if !kernelDir.IsDir() {
+			continue
+		}
+		kernelVersion := kernelDir.Name()
+		versionDirs, err := d.os.ReadDir(filepath.Join(root, kernelVersion))
+		if err != nil {
+			log.V(1).Info("Failed to list kernel version directory", "kernel", kernelVersion, "error", err)
+			// Non-fatal error, continue
+			continue
+		}
+		for _, versionDir := range versionDirs {
+			if !versionDir.IsDir() {
+				continue
+			}
+			versionPath := filepath.Join(root, kernelVersion, versionDir.Name())
+			if !d.cfg.InventoryArchSubdir {
+				entries = append(entries, d.inventoryEntry(kernelVersion, versionDir.Name(), "", versionPath))
+				continue
+			}
+			archDirs, err := d.os.ReadDir(versionPath)
+			if err != nil {
+				log.V(1).Info("Failed to list driver version directory", "path", versionPath, "error", err)
+				// Non-fatal error, continue
+				continue
+			}
+			for _, archDir := range archDirs {
+				if archDir.IsDir() {
+					entries = append(entries, d.inventoryEntry(kernelVersion, versionDir.Name(), archDir.Name(),
+						filepath.Join(versionPath, archDir.Name())))
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// inventoryEntry reads the checksum, build config and package count of the packages in path
+func (d *driverMgr) inventoryEntry(kernelVersion, driverVersion, arch, path string) InventoryEntry {
+	entry := InventoryEntry{
+		KernelVersion: kernelVersion,
+		DriverVersion: driverVersion,
+		Arch:          arch,
+		Path:          path,
+	}
+
+	if data, err := d.os.ReadFile(path + ".checksum"); err == nil && strings.TrimSpace(string(data)) != "" {
+		entry.Checksum = strings.TrimSpace(string(data))
+	} else {
+		entry.Incomplete = true
+	}
+	if data, err := d.os.ReadFile(path + ".buildconfig"); err == nil {
+		entry.BuildInfo = strings.Join(strings.Fields(string(data)), " ")
+	}
+	if packages, err := d.os.ReadDir(path); err == nil {
+		for _, pkg := range packages {
+			if pkg.Type().IsRegular() {
+				entry.PackageCount++
+			}
+		}
+	}
+	return entry
+}
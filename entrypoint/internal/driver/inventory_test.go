@@ -0,0 +1,108 @@
+/*
+ Copyright 2026, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/constants"
+	cmdMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd/mocks"
+	hostMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/host/mocks"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers"
+)
+
+var _ = Describe("Driver inventory listing", func() {
+	var (
+		ctx  context.Context
+		root string
+		cfg  config.Config
+	)
+
+	writeFile := func(path, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+	}
+
+	newDriverMgr := func() *driverMgr {
+		return New(constants.DriverContainerModeSources, cfg, cmdMockPkg.NewInterface(GinkgoT()),
+			hostMockPkg.NewInterface(GinkgoT()), wrappers.NewOS()).(*driverMgr)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		root = GinkgoT().TempDir()
+		cfg = config.Config{NvidiaNicDriversInventoryPath: root}
+	})
+
+	It("should list every kernel and driver version and flag the ones without checksum as incomplete", func() {
+		writeFile(filepath.Join(root, "5.15.0-1", "25.01", "mlnx-ofed-kernel.deb"), "pkg")
+		writeFile(filepath.Join(root, "5.15.0-1", "25.01", "knem.deb"), "pkg")
+		writeFile(filepath.Join(root, "5.15.0-1", "25.01.checksum"), "abc123\n")
+		writeFile(filepath.Join(root, "5.15.0-1", "25.01.buildconfig"), "ENABLE_NFSRDMA=false\nUSE_DKMS=false")
+		writeFile(filepath.Join(root, "6.8.0-2", "25.04", "mlnx-ofed-kernel.deb"), "pkg")
+
+		entries, err := newDriverMgr().ListInventory(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]InventoryEntry{
+			{
+				KernelVersion: "5.15.0-1",
+				DriverVersion: "25.01",
+				Path:          filepath.Join(root, "5.15.0-1", "25.01"),
+				Checksum:      "abc123",
+				BuildInfo:     "ENABLE_NFSRDMA=false USE_DKMS=false",
+				PackageCount:  2,
+			},
+			{
+				KernelVersion: "6.8.0-2",
+				DriverVersion: "25.04",
+				Path:          filepath.Join(root, "6.8.0-2", "25.04"),
+				PackageCount:  1,
+				Incomplete:    true,
+			},
+		}))
+	})
+
+	It("should list the architecture subdirs with InventoryArchSubdir", func() {
+		cfg.InventoryArchSubdir = true
+		writeFile(filepath.Join(root, "5.14.0", "25.01", "x86_64", "mlnx-ofa_kernel.rpm"), "pkg")
+		writeFile(filepath.Join(root, "5.14.0", "25.01", "x86_64.checksum"), "def456")
+
+		entries, err := newDriverMgr().ListInventory(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]InventoryEntry{{
+			KernelVersion: "5.14.0",
+			DriverVersion: "25.01",
+			Arch:          "x86_64",
+			Path:          filepath.Join(root, "5.14.0", "25.01", "x86_64"),
+			Checksum:      "def456",
+			PackageCount:  1,
+		}}))
+	})
+
+	It("should fail when the inventory path is not set", func() {
+		cfg.NvidiaNicDriversInventoryPath = ""
+
+		_, err := newDriverMgr().ListInventory(ctx)
+		Expect(err).To(MatchError(ContainSubstring("NVIDIA_NIC_DRIVERS_INVENTORY_PATH is not set")))
+	})
+})
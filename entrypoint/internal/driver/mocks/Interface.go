@@ -5,6 +5,7 @@ package driver
 import (
 	context "context"
 
+	driver "github.com/Mellanox/doca-driver-build/entrypoint/internal/driver"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -67,6 +68,51 @@ func (_c *Interface_Build_Call) RunAndReturn(run func(context.Context) error) *I
 	return _c
 }
 
+// BuildSource provides a mock function with no fields
+func (_m *Interface) BuildSource() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BuildSource")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// Interface_BuildSource_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BuildSource'
+type Interface_BuildSource_Call struct {
+	*mock.Call
+}
+
+// BuildSource is a helper method to define mock.On call
+func (_e *Interface_Expecter) BuildSource() *Interface_BuildSource_Call {
+	return &Interface_BuildSource_Call{Call: _e.mock.On("BuildSource")}
+}
+
+func (_c *Interface_BuildSource_Call) Run(run func()) *Interface_BuildSource_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Interface_BuildSource_Call) Return(_a0 string) *Interface_BuildSource_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Interface_BuildSource_Call) RunAndReturn(run func() string) *Interface_BuildSource_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Clear provides a mock function with given fields: ctx
 func (_m *Interface) Clear(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -113,6 +159,167 @@ func (_c *Interface_Clear_Call) RunAndReturn(run func(context.Context) error) *I
 	return _c
 }
 
+// CollectDiagnostics provides a mock function with given fields: ctx, outDir
+func (_m *Interface) CollectDiagnostics(ctx context.Context, outDir string) error {
+	ret := _m.Called(ctx, outDir)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CollectDiagnostics")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, outDir)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Interface_CollectDiagnostics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CollectDiagnostics'
+type Interface_CollectDiagnostics_Call struct {
+	*mock.Call
+}
+
+// CollectDiagnostics is a helper method to define mock.On call
+//   - ctx context.Context
+//   - outDir string
+func (_e *Interface_Expecter) CollectDiagnostics(ctx interface{}, outDir interface{}) *Interface_CollectDiagnostics_Call {
+	return &Interface_CollectDiagnostics_Call{Call: _e.mock.On("CollectDiagnostics", ctx, outDir)}
+}
+
+func (_c *Interface_CollectDiagnostics_Call) Run(run func(ctx context.Context, outDir string)) *Interface_CollectDiagnostics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Interface_CollectDiagnostics_Call) Return(_a0 error) *Interface_CollectDiagnostics_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Interface_CollectDiagnostics_Call) RunAndReturn(run func(context.Context, string) error) *Interface_CollectDiagnostics_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Drifted provides a mock function with given fields: ctx
+func (_m *Interface) Drifted(ctx context.Context) (bool, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Drifted")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (bool, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) bool); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Interface_Drifted_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Drifted'
+type Interface_Drifted_Call struct {
+	*mock.Call
+}
+
+// Drifted is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Interface_Expecter) Drifted(ctx interface{}) *Interface_Drifted_Call {
+	return &Interface_Drifted_Call{Call: _e.mock.On("Drifted", ctx)}
+}
+
+func (_c *Interface_Drifted_Call) Run(run func(ctx context.Context)) *Interface_Drifted_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Interface_Drifted_Call) Return(_a0 bool, _a1 error) *Interface_Drifted_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Interface_Drifted_Call) RunAndReturn(run func(context.Context) (bool, error)) *Interface_Drifted_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListInventory provides a mock function with given fields: ctx
+func (_m *Interface) ListInventory(ctx context.Context) ([]driver.InventoryEntry, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListInventory")
+	}
+
+	var r0 []driver.InventoryEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]driver.InventoryEntry, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []driver.InventoryEntry); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]driver.InventoryEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Interface_ListInventory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListInventory'
+type Interface_ListInventory_Call struct {
+	*mock.Call
+}
+
+// ListInventory is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Interface_Expecter) ListInventory(ctx interface{}) *Interface_ListInventory_Call {
+	return &Interface_ListInventory_Call{Call: _e.mock.On("ListInventory", ctx)}
+}
+
+func (_c *Interface_ListInventory_Call) Run(run func(ctx context.Context)) *Interface_ListInventory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Interface_ListInventory_Call) Return(_a0 []driver.InventoryEntry, _a1 error) *Interface_ListInventory_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Interface_ListInventory_Call) RunAndReturn(run func(context.Context) ([]driver.InventoryEntry, error)) *Interface_ListInventory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Load provides a mock function with given fields: ctx
 func (_m *Interface) Load(ctx context.Context) (bool, error) {
 	ret := _m.Called(ctx)
@@ -215,6 +422,62 @@ func (_c *Interface_PreStart_Call) RunAndReturn(run func(context.Context) error)
 	return _c
 }
 
+// Reconcile provides a mock function with given fields: ctx
+func (_m *Interface) Reconcile(ctx context.Context) (bool, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reconcile")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (bool, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) bool); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Interface_Reconcile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Reconcile'
+type Interface_Reconcile_Call struct {
+	*mock.Call
+}
+
+// Reconcile is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Interface_Expecter) Reconcile(ctx interface{}) *Interface_Reconcile_Call {
+	return &Interface_Reconcile_Call{Call: _e.mock.On("Reconcile", ctx)}
+}
+
+func (_c *Interface_Reconcile_Call) Run(run func(ctx context.Context)) *Interface_Reconcile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Interface_Reconcile_Call) Return(_a0 bool, _a1 error) *Interface_Reconcile_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Interface_Reconcile_Call) RunAndReturn(run func(context.Context) (bool, error)) *Interface_Reconcile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Unload provides a mock function with given fields: ctx
 func (_m *Interface) Unload(ctx context.Context) (bool, error) {
 	ret := _m.Called(ctx)
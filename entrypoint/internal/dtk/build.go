@@ -93,10 +93,11 @@ func RunBuild(ctx context.Context, log logr.Logger, cfg config.Config, cmdHelper
 		"--without-iser",
 		"--without-isert",
 		"--without-srp",
-		"--with-mlnx-tools",
-		"--with-ofed-scripts",
-		"--copy-ifnames-udev",
 	}
+	if cfg.WithMlnxTools {
+		installArgs = append(installArgs, "--with-mlnx-tools")
+	}
+	installArgs = append(installArgs, "--with-ofed-scripts", "--copy-ifnames-udev")
 
 	if !cfg.UseDKMS {
 		// Non-DKMS path: suppress DKMS source packages and produce only static
@@ -44,6 +44,7 @@ func TestRunBuild(t *testing.T) {
 		DtkOcpDoneCompileFlag:   doneFlag,
 		DtkOcpCompiledDriverVer: "1.0.0",
 		DtkOcpNicSharedDir:      tempDir,
+		WithMlnxTools:           true,
 	}
 
 	t.Run("should fail if flags are not set", func(t *testing.T) {
@@ -18,6 +18,7 @@ package entrypoint
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -34,6 +35,7 @@ import (
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/netconfig/sriovnet"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/host"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/notifier"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/ready"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/udev"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers"
@@ -44,27 +46,47 @@ import (
 //   - preStart: Cleans up, validates, and prepares. If it fails,
 //     the process exits immediately without running "stop".
 //   - start: Builds and loads the driver after preStart succeeds. If successful,
-//     the manager waits for a termination signal. If it fails, "stop" still runs.
+//     the manager waits for a termination signal, reconciling the driver every
+//     RECONCILE_INTERVAL when it is set. If it fails, "stop" still runs.
 //   - stop: Handles unloading the driver and container teardown.
 func Run(signalCh chan os.Signal, log logr.Logger, containerMode string, cfg config.Config) error {
 	osWrapper := wrappers.NewOS()
-	cmdHelper := cmd.New()
+	cmdHelper := cmd.NewFromConfig(cfg, osWrapper)
 	hostHelper := host.New(cmdHelper, osWrapper)
 	m := &entrypoint{
 		log:           log,
 		config:        cfg,
 		containerMode: containerMode,
 		readiness:     ready.New(cfg.DriverReadyPath, osWrapper),
+		notifier:      notifier.New(cfg, osWrapper),
 		udev:          udev.New(cfg.MlxUdevRulesFile, osWrapper),
 		host:          hostHelper,
 		cmd:           cmdHelper,
 		os:            osWrapper,
-		netconfig:     netconfig.New(cmdHelper, osWrapper, hostHelper, sriovnet.New(), netlink.New(), cfg.BindDelaySec),
+		netconfig:     netconfig.New(cmdHelper, osWrapper, hostHelper, sriovnet.New(), netlink.New(), cfg),
 		drivermgr:     driver.New(containerMode, cfg, cmdHelper, hostHelper, osWrapper),
 	}
 	return m.run(signalCh)
 }
 
+// CollectDiagnostics writes a support bundle of the host state into cfg.DiagnosticsDir.
+// It doesn't take the entrypoint lock, so it can run next to a failed driver container.
+func CollectDiagnostics(log logr.Logger, cfg config.Config) error {
+	osWrapper := wrappers.NewOS()
+	cmdHelper := cmd.NewFromConfig(cfg, osWrapper)
+	drivermgr := driver.New("", cfg, cmdHelper, host.New(cmdHelper, osWrapper), osWrapper)
+	return drivermgr.CollectDiagnostics(logr.NewContext(context.Background(), log), cfg.DiagnosticsDir)
+}
+
+// ListInventory returns the driver packages cached in cfg.NvidiaNicDriversInventoryPath.
+// Like CollectDiagnostics it doesn't take the entrypoint lock.
+func ListInventory(log logr.Logger, cfg config.Config) ([]driver.InventoryEntry, error) {
+	osWrapper := wrappers.NewOS()
+	cmdHelper := cmd.NewFromConfig(cfg, osWrapper)
+	drivermgr := driver.New("", cfg, cmdHelper, host.New(cmdHelper, osWrapper), osWrapper)
+	return drivermgr.ListInventory(logr.NewContext(context.Background(), log))
+}
+
 // entrypoint orchestrates the high-level logic for loading and unloading the driver.
 type entrypoint struct {
 	log logr.Logger
@@ -76,6 +98,7 @@ type entrypoint struct {
 	netconfig netconfig.Interface
 	cmd       cmd.Interface
 	readiness ready.Interface
+	notifier  notifier.Interface
 	udev      udev.Interface
 	os        wrappers.OSWrapper
 	host      host.Interface
@@ -111,6 +134,9 @@ func (e *entrypoint) run(signalCh chan os.Signal) error {
 		// explicitly cancel the start context to make sure that the stop context
 		// will receive the first sigterm signal
 		startCancel()
+	} else if e.config.ReconcileInterval > 0 {
+		e.log.Info("configuration done, reconcile", "interval", e.config.ReconcileInterval)
+		e.reconcileLoop(startCtx)
 	} else {
 		e.log.Info("configuration done, sleep")
 		<-startCtx.Done()
@@ -191,8 +217,11 @@ func (e *entrypoint) preStart(ctx context.Context) error {
 
 	if e.containerMode == constants.DriverContainerModeSources {
 		if err := e.drivermgr.Build(ctx); err != nil {
+			e.notifier.Notify(ctx, notifier.EventTypeWarning, notifier.ReasonBuildFailed, "driver build failed: "+err.Error())
 			return err
 		}
+		e.notifier.Notify(ctx, notifier.EventTypeNormal, notifier.ReasonBuildSucceeded,
+			"driver build succeeded, build_source: "+e.drivermgr.BuildSource())
 	}
 
 	return ctx.Err()
@@ -200,11 +229,13 @@ func (e *entrypoint) preStart(ctx context.Context) error {
 
 // start loads the driver and blocks until the context is canceled. The stop handler runs unconditionally after this.
 func (e *entrypoint) start(ctx context.Context) error {
-	reloaded, err := e.drivermgr.Load(ctx)
+	reloaded, err := e.loadWithRetries(ctx, e.drivermgr.Load)
 	if err != nil {
+		e.notifier.Notify(ctx, notifier.EventTypeWarning, notifier.ReasonLoadFailed, "driver load failed: "+err.Error())
 		return err
 	}
 	if reloaded {
+		e.notifier.Notify(ctx, notifier.EventTypeNormal, notifier.ReasonLoaded, "driver loaded")
 		// we need to restore configuration only if the driver was loaded
 		if err := e.netconfig.Restore(ctx); err != nil {
 			return err
@@ -216,6 +247,76 @@ func (e *entrypoint) start(ctx context.Context) error {
 	return nil
 }
 
+// loadWithRetries loads the driver with load, i.e. Load on start or Reconcile on a drift, the whole
+// load is retried up to LoadRetries times on failure with an exponential backoff starting at
+// LoadRetryBackoff. Each attempt regenerates the OFED modules blacklist, a failed attempt removes it
+// unless it is persistent. The last error is returned.
+func (e *entrypoint) loadWithRetries(ctx context.Context, load func(context.Context) (bool, error)) (bool, error) {
+	backoff := e.config.LoadRetryBackoff
+	for attempt := 0; ; attempt++ {
+		reloaded, err := load(ctx)
+		// A changed kernel can't be fixed by a retry in precompiled mode
+		if err == nil || attempt >= e.config.LoadRetries || errors.Is(err, driver.ErrPrecompiledKernelChanged) {
+			return reloaded, err
+		}
+		e.log.Info("[WARN] driver load failed, retrying", "error", err.Error(),
+			"attempt", attempt+1, "retries", e.config.LoadRetries, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return false, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// reconcileLoop reconciles the driver every ReconcileInterval until the context is canceled.
+// Reconcile failures are logged and retried on the next tick, the readiness flag is cleared meanwhile.
+func (e *entrypoint) reconcileLoop(ctx context.Context) {
+	ticker := time.NewTicker(e.config.ReconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.reconcile(ctx); err != nil {
+				e.log.Error(err, "reconcile failed")
+				if err := e.readiness.Clear(ctx); err != nil {
+					e.log.Error(err, "failed to clear readiness")
+				}
+			}
+		}
+	}
+}
+
+// reconcile reloads the driver if it drifted from the candidate driver, the network
+// configuration is saved before and restored after the reload.
+func (e *entrypoint) reconcile(ctx context.Context) error {
+	drifted, err := e.drivermgr.Drifted(ctx)
+	if err != nil {
+		return err
+	}
+	if !drifted {
+		return e.readiness.Set(ctx)
+	}
+	if err := e.netconfig.Save(ctx); err != nil {
+		return err
+	}
+	reloaded, err := e.loadWithRetries(ctx, e.drivermgr.Reconcile)
+	if err != nil {
+		e.notifier.Notify(ctx, notifier.EventTypeWarning, notifier.ReasonLoadFailed, "driver reload failed: "+err.Error())
+		return err
+	}
+	if reloaded {
+		e.notifier.Notify(ctx, notifier.EventTypeNormal, notifier.ReasonLoaded, "drifted driver reloaded")
+		if err := e.netconfig.Restore(ctx); err != nil {
+			return err
+		}
+	}
+	return e.readiness.Set(ctx)
+}
+
 // stop is the termination handler and contains the logic to be executed on container teardown.
 func (e *entrypoint) stop(ctx context.Context) error {
 	if err := e.commonCleanup(ctx); err != nil {
@@ -225,9 +326,11 @@ func (e *entrypoint) stop(ctx context.Context) error {
 		e.log.Info("restore inbox driver")
 		reloaded, err := e.drivermgr.Unload(ctx)
 		if err != nil {
+			e.notifier.Notify(ctx, notifier.EventTypeWarning, notifier.ReasonUnloadFailed, "driver unload failed: "+err.Error())
 			return err
 		}
 		if reloaded {
+			e.notifier.Notify(ctx, notifier.EventTypeNormal, notifier.ReasonUnloaded, "inbox driver restored")
 			if err := e.netconfig.Restore(ctx); err != nil {
 				return err
 			}
@@ -17,6 +17,7 @@
 package entrypoint
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"syscall"
@@ -30,15 +31,28 @@ import (
 
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/constants"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/driver"
 	driverMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/driver/mocks"
 	netconfigMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/netconfig/mocks"
 	cmdMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd/mocks"
 	hostMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/host/mocks"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/notifier"
 	readyMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/ready/mocks"
 	udevMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/udev/mocks"
 	osMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers/mocks"
 )
 
+// fakeNotifier records the reasons and messages of the notified events
+type fakeNotifier struct {
+	reasons  []string
+	messages []string
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, _, reason, message string) {
+	f.reasons = append(f.reasons, reason)
+	f.messages = append(f.messages, message)
+}
+
 var _ = Describe("Entrypoint", func() {
 	Context("Smoke test", func() {
 		var (
@@ -52,6 +66,7 @@ var _ = Describe("Entrypoint", func() {
 			osMock        *osMockPkg.OSWrapper
 			netconfigMock *netconfigMockPkg.Interface
 			driverMock    *driverMockPkg.Interface
+			events        *fakeNotifier
 		)
 		BeforeEach(func() {
 			readinessMock = readyMockPkg.NewInterface(GinkgoT())
@@ -61,6 +76,7 @@ var _ = Describe("Entrypoint", func() {
 			osMock = osMockPkg.NewOSWrapper(GinkgoT())
 			netconfigMock = netconfigMockPkg.NewInterface(GinkgoT())
 			driverMock = driverMockPkg.NewInterface(GinkgoT())
+			events = &fakeNotifier{}
 			e = &entrypoint{
 				log: logr.Discard(),
 				config: config.Config{
@@ -73,6 +89,7 @@ var _ = Describe("Entrypoint", func() {
 				netconfig:     netconfigMock,
 				cmd:           cmdMock,
 				readiness:     readinessMock,
+				notifier:      events,
 				udev:          udevMock,
 				os:            osMock,
 				host:          hostMock,
@@ -96,11 +113,16 @@ var _ = Describe("Entrypoint", func() {
 
 			driverMock.On("PreStart", mock.Anything).Return(nil).Once()
 			driverMock.On("Build", mock.Anything).Return(nil).Once()
+			driverMock.On("BuildSource").Return(driver.BuildSourceFresh).Once()
 			driverMock.On("Load", mock.Anything).Return(true, nil).Once()
 			driverMock.On("Unload", mock.Anything).Return(true, nil).Once()
 			driverMock.On("Clear", mock.Anything).Return(nil).Once()
 
 			Expect(e.run(signalCH)).NotTo(HaveOccurred())
+			Expect(events.reasons).To(Equal([]string{
+				notifier.ReasonBuildSucceeded, notifier.ReasonLoaded, notifier.ReasonUnloaded,
+			}))
+			Expect(events.messages[0]).To(Equal("driver build succeeded, build_source: fresh"))
 		})
 
 		It("preStart failed", func() {
@@ -110,6 +132,24 @@ var _ = Describe("Entrypoint", func() {
 
 			driverMock.On("PreStart", mock.Anything).Return(fmt.Errorf("test")).Once()
 			Expect(e.run(signalCH)).To(HaveOccurred())
+			Expect(events.reasons).To(BeEmpty())
+		})
+
+		It("build failed", func() {
+			osMock.On("MkdirAll", "/tmp", mock.Anything).Return(nil).Once()
+			hostMock.On("LsMod", mock.Anything).Return(nil, nil).Once()
+			udevMock.On("RemoveRules", mock.Anything).Return(nil).Once()
+			udevMock.On("CreateRules", mock.Anything).Return(nil).Once()
+			readinessMock.On("Clear", mock.Anything).Return(nil).Once()
+
+			netconfigMock.On("Save", mock.Anything).Return(nil).Once()
+			netconfigMock.On("DevicesUseNewNamingScheme", mock.Anything).Return(false, nil).Once()
+
+			driverMock.On("PreStart", mock.Anything).Return(nil).Once()
+			driverMock.On("Build", mock.Anything).Return(fmt.Errorf("test")).Once()
+
+			Expect(e.run(signalCH)).To(HaveOccurred())
+			Expect(events.reasons).To(Equal([]string{notifier.ReasonBuildFailed}))
 		})
 
 		It("start failed", func() {
@@ -126,11 +166,74 @@ var _ = Describe("Entrypoint", func() {
 
 			driverMock.On("PreStart", mock.Anything).Return(nil).Once()
 			driverMock.On("Build", mock.Anything).Return(nil).Once()
+			driverMock.On("BuildSource").Return(driver.BuildSourceFresh).Once()
 			driverMock.On("Load", mock.Anything).Return(false, fmt.Errorf("test")).Once()
 			driverMock.On("Unload", mock.Anything).Return(true, nil).Once()
 			driverMock.On("Clear", mock.Anything).Return(nil).Once()
 
 			Expect(e.run(signalCH)).To(HaveOccurred())
+			Expect(events.reasons).To(Equal([]string{
+				notifier.ReasonBuildSucceeded, notifier.ReasonLoadFailed, notifier.ReasonUnloaded,
+			}))
+		})
+
+		It("retries a failed load", func() {
+			e.config.LoadRetries = 2
+			e.config.LoadRetryBackoff = time.Millisecond
+
+			osMock.On("MkdirAll", "/tmp", mock.Anything).Return(nil).Once()
+			hostMock.On("LsMod", mock.Anything).Return(nil, nil).Once()
+			udevMock.On("RemoveRules", mock.Anything).Return(nil).Times(2)
+			udevMock.On("CreateRules", mock.Anything).Return(nil).Once()
+
+			readinessMock.On("Clear", mock.Anything).Return(nil).Times(2)
+			readinessMock.On("Set", mock.Anything).Return(nil).Run(
+				func(args mock.Arguments) { signalCH <- syscall.SIGTERM }).Once()
+
+			netconfigMock.On("Save", mock.Anything).Return(nil).Once()
+			netconfigMock.On("Restore", mock.Anything).Return(nil).Times(2)
+			netconfigMock.On("DevicesUseNewNamingScheme", mock.Anything).Return(false, nil).Once()
+
+			driverMock.On("PreStart", mock.Anything).Return(nil).Once()
+			driverMock.On("Build", mock.Anything).Return(nil).Once()
+			driverMock.On("BuildSource").Return(driver.BuildSourceFresh).Once()
+			driverMock.On("Load", mock.Anything).Return(false, fmt.Errorf("module busy")).Twice()
+			driverMock.On("Load", mock.Anything).Return(true, nil).Once()
+			driverMock.On("Unload", mock.Anything).Return(true, nil).Once()
+			driverMock.On("Clear", mock.Anything).Return(nil).Once()
+
+			Expect(e.run(signalCH)).NotTo(HaveOccurred())
+			driverMock.AssertNumberOfCalls(GinkgoT(), "Load", 3)
+			Expect(events.reasons).To(Equal([]string{
+				notifier.ReasonBuildSucceeded, notifier.ReasonLoaded, notifier.ReasonUnloaded,
+			}))
+		})
+
+		It("returns the last load error when the retries are exhausted", func() {
+			e.config.LoadRetries = 1
+			e.config.LoadRetryBackoff = time.Millisecond
+
+			osMock.On("MkdirAll", "/tmp", mock.Anything).Return(nil).Once()
+			hostMock.On("LsMod", mock.Anything).Return(nil, nil).Once()
+			udevMock.On("RemoveRules", mock.Anything).Return(nil).Times(2)
+			udevMock.On("CreateRules", mock.Anything).Return(nil).Once()
+
+			readinessMock.On("Clear", mock.Anything).Return(nil).Times(2)
+
+			netconfigMock.On("Save", mock.Anything).Return(nil).Once()
+			netconfigMock.On("Restore", mock.Anything).Return(nil).Once()
+			netconfigMock.On("DevicesUseNewNamingScheme", mock.Anything).Return(false, nil).Once()
+
+			driverMock.On("PreStart", mock.Anything).Return(nil).Once()
+			driverMock.On("Build", mock.Anything).Return(nil).Once()
+			driverMock.On("BuildSource").Return(driver.BuildSourceFresh).Once()
+			driverMock.On("Load", mock.Anything).Return(false, fmt.Errorf("first")).Once()
+			driverMock.On("Load", mock.Anything).Return(false, fmt.Errorf("last")).Once()
+			driverMock.On("Unload", mock.Anything).Return(true, nil).Once()
+			driverMock.On("Clear", mock.Anything).Return(nil).Once()
+
+			Expect(e.run(signalCH)).To(MatchError(ContainSubstring("last")))
+			driverMock.AssertNumberOfCalls(GinkgoT(), "Load", 2)
 		})
 
 		It("stop failed", func() {
@@ -149,10 +252,109 @@ var _ = Describe("Entrypoint", func() {
 
 			driverMock.On("PreStart", mock.Anything).Return(nil).Once()
 			driverMock.On("Build", mock.Anything).Return(nil).Once()
+			driverMock.On("BuildSource").Return(driver.BuildSourceFresh).Once()
 			driverMock.On("Load", mock.Anything).Return(true, nil).Once()
 			driverMock.On("Unload", mock.Anything).Return(false, fmt.Errorf("test")).Once()
 
 			Expect(e.run(signalCH)).To(HaveOccurred())
+			Expect(events.reasons).To(Equal([]string{
+				notifier.ReasonBuildSucceeded, notifier.ReasonLoaded, notifier.ReasonUnloadFailed,
+			}))
+		})
+
+		It("reconciles on the interval until signal", func() {
+			e.config.ReconcileInterval = 10 * time.Millisecond
+			reconciles := 0
+
+			osMock.On("MkdirAll", "/tmp", mock.Anything).Return(nil).Once()
+			hostMock.On("LsMod", mock.Anything).Return(nil, nil).Once()
+			udevMock.On("RemoveRules", mock.Anything).Return(nil).Times(2)
+			udevMock.On("CreateRules", mock.Anything).Return(nil).Once()
+
+			readinessMock.On("Clear", mock.Anything).Return(nil).Times(2)
+			readinessMock.On("Set", mock.Anything).Return(nil)
+
+			netconfigMock.On("Save", mock.Anything).Return(nil).Once() // Only in preStart, the ticks don't drift
+			netconfigMock.On("Restore", mock.Anything).Return(nil)
+			netconfigMock.On("DevicesUseNewNamingScheme", mock.Anything).Return(false, nil).Once()
+
+			driverMock.On("PreStart", mock.Anything).Return(nil).Once()
+			driverMock.On("Build", mock.Anything).Return(nil).Once()
+			driverMock.On("BuildSource").Return(driver.BuildSourceFresh).Once()
+			driverMock.On("Load", mock.Anything).Return(true, nil).Once()
+			driverMock.On("Drifted", mock.Anything).Return(false, nil).Run(func(args mock.Arguments) {
+				reconciles++
+				if reconciles == 3 {
+					signalCH <- syscall.SIGTERM
+				}
+			})
+			driverMock.On("Unload", mock.Anything).Return(true, nil).Once()
+			driverMock.On("Clear", mock.Anything).Return(nil).Once()
+
+			Expect(e.run(signalCH)).NotTo(HaveOccurred())
+			Expect(reconciles).To(BeNumerically(">=", 3))
+		})
+
+		It("reconcile doesn't save the network configuration when the driver didn't drift", func() {
+			driverMock.On("Drifted", mock.Anything).Return(false, nil).Once()
+			readinessMock.On("Set", mock.Anything).Return(nil).Once()
+
+			Expect(e.reconcile(context.Background())).To(Succeed())
+			netconfigMock.AssertNotCalled(GinkgoT(), "Save", mock.Anything)
+			driverMock.AssertNotCalled(GinkgoT(), "Reconcile", mock.Anything)
+			Expect(events.reasons).To(BeEmpty())
+		})
+
+		It("reconcile saves the network configuration before reloading a drifted driver", func() {
+			var calls []string
+			driverMock.On("Drifted", mock.Anything).Return(true, nil).Once()
+			netconfigMock.On("Save", mock.Anything).Return(nil).Run(func(mock.Arguments) {
+				calls = append(calls, "Save")
+			}).Once()
+			driverMock.On("Reconcile", mock.Anything).Return(true, nil).Run(func(mock.Arguments) {
+				calls = append(calls, "Reconcile")
+			}).Once()
+			netconfigMock.On("Restore", mock.Anything).Return(nil).Run(func(mock.Arguments) {
+				calls = append(calls, "Restore")
+			}).Once()
+			readinessMock.On("Set", mock.Anything).Return(nil).Once()
+
+			Expect(e.reconcile(context.Background())).To(Succeed())
+			Expect(calls).To(Equal([]string{"Save", "Reconcile", "Restore"}))
+			Expect(events.reasons).To(Equal([]string{notifier.ReasonLoaded}))
+			Expect(events.messages).To(Equal([]string{"drifted driver reloaded"}))
+		})
+
+		It("reconcile retries a failed reload like the startup load", func() {
+			e.config.LoadRetries = 1
+			e.config.LoadRetryBackoff = time.Millisecond
+
+			driverMock.On("Drifted", mock.Anything).Return(true, nil).Once()
+			netconfigMock.On("Save", mock.Anything).Return(nil).Once()
+			driverMock.On("Reconcile", mock.Anything).Return(false, fmt.Errorf("module busy")).Once()
+			driverMock.On("Reconcile", mock.Anything).Return(true, nil).Once()
+			netconfigMock.On("Restore", mock.Anything).Return(nil).Once()
+			readinessMock.On("Set", mock.Anything).Return(nil).Once()
+
+			Expect(e.reconcile(context.Background())).To(Succeed())
+			driverMock.AssertNumberOfCalls(GinkgoT(), "Reconcile", 2)
+			Expect(events.reasons).To(Equal([]string{notifier.ReasonLoaded}))
+		})
+
+		It("reconcile doesn't retry a precompiled driver on a changed kernel", func() {
+			e.config.LoadRetries = 2
+			e.config.LoadRetryBackoff = time.Millisecond
+
+			driverMock.On("Drifted", mock.Anything).Return(true, nil).Once()
+			netconfigMock.On("Save", mock.Anything).Return(nil).Once()
+			driverMock.On("Reconcile", mock.Anything).Return(false, driver.ErrPrecompiledKernelChanged).Once()
+
+			Expect(e.reconcile(context.Background())).To(MatchError(driver.ErrPrecompiledKernelChanged))
+			driverMock.AssertNumberOfCalls(GinkgoT(), "Reconcile", 1)
+			netconfigMock.AssertNotCalled(GinkgoT(), "Restore", mock.Anything)
+			readinessMock.AssertNotCalled(GinkgoT(), "Set", mock.Anything)
+			Expect(events.reasons).To(Equal([]string{notifier.ReasonLoadFailed}))
+			Expect(events.messages[0]).To(HavePrefix("driver reload failed: "))
 		})
 	})
 
@@ -6,6 +6,8 @@ import (
 	context "context"
 
 	mock "github.com/stretchr/testify/mock"
+
+	netconfig "github.com/Mellanox/doca-driver-build/entrypoint/internal/netconfig"
 )
 
 // Interface is an autogenerated mock type for the Interface type
@@ -77,6 +79,64 @@ func (_c *Interface_DevicesUseNewNamingScheme_Call) RunAndReturn(run func(contex
 	return _c
 }
 
+// ExportJSON provides a mock function with given fields: ctx
+func (_m *Interface) ExportJSON(ctx context.Context) ([]byte, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportJSON")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]byte, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []byte); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Interface_ExportJSON_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExportJSON'
+type Interface_ExportJSON_Call struct {
+	*mock.Call
+}
+
+// ExportJSON is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Interface_Expecter) ExportJSON(ctx interface{}) *Interface_ExportJSON_Call {
+	return &Interface_ExportJSON_Call{Call: _e.mock.On("ExportJSON", ctx)}
+}
+
+func (_c *Interface_ExportJSON_Call) Run(run func(ctx context.Context)) *Interface_ExportJSON_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Interface_ExportJSON_Call) Return(_a0 []byte, _a1 error) *Interface_ExportJSON_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Interface_ExportJSON_Call) RunAndReturn(run func(context.Context) ([]byte, error)) *Interface_ExportJSON_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Restore provides a mock function with given fields: ctx
 func (_m *Interface) Restore(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -169,6 +229,51 @@ func (_c *Interface_Save_Call) RunAndReturn(run func(context.Context) error) *In
 	return _c
 }
 
+// Summary provides a mock function with no fields
+func (_m *Interface) Summary() netconfig.DeviceSummary {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Summary")
+	}
+
+	var r0 netconfig.DeviceSummary
+	if rf, ok := ret.Get(0).(func() netconfig.DeviceSummary); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(netconfig.DeviceSummary)
+	}
+
+	return r0
+}
+
+// Interface_Summary_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Summary'
+type Interface_Summary_Call struct {
+	*mock.Call
+}
+
+// Summary is a helper method to define mock.On call
+func (_e *Interface_Expecter) Summary() *Interface_Summary_Call {
+	return &Interface_Summary_Call{Call: _e.mock.On("Summary")}
+}
+
+func (_c *Interface_Summary_Call) Run(run func()) *Interface_Summary_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Interface_Summary_Call) Return(_a0 netconfig.DeviceSummary) *Interface_Summary_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Interface_Summary_Call) RunAndReturn(run func() netconfig.DeviceSummary) *Interface_Summary_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewInterface creates a new instance of Interface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewInterface(t interface {
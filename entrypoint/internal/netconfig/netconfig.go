@@ -19,15 +19,20 @@ package netconfig
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/constants"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/netconfig/netlink"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/netconfig/sriovnet"
@@ -44,12 +49,16 @@ const (
 	adminStateDown       = "down"
 	eswitchModeLegacy    = "legacy"
 	eswitchModeSwitchdev = "switchdev"
-	sysClassNetPath      = "/sys/class/net/"
-	sysBusPCIDevicesPath = "/sys/bus/pci/devices/"
-	sysBusPCIDriversPath = "/sys/bus/pci/drivers/"
-	defaultDriverPath    = sysBusPCIDriversPath + "mlx5_core"
+	defaultDriverName    = "mlx5_core"
+
+	lagPortSelectModeParam = "lag_port_select_mode"
+
+	defaultPollInterval = 200 * time.Millisecond
 )
 
+// ErrIPCommandNotFound is returned when a change without a netlink equivalent needs the missing ip command
+var ErrIPCommandNotFound = errors.New("ip command not found, install iproute2 in the driver container image")
+
 // JSON structures for parsing ip command output
 type VFInfo struct {
 	Address  string `json:"address"`
@@ -67,16 +76,45 @@ func New(
 	hostHelper host.Interface,
 	sriovnetLib sriovnet.Lib,
 	netlinkLib netlink.Lib,
-	bindDelaySec int,
+	cfg config.Config,
 ) Interface {
+	sysfsRoot := cfg.SysfsRoot
+	if sysfsRoot == "" {
+		sysfsRoot = constants.DefaultSysfsRoot
+	}
+	discoveryConcurrency := cfg.NetconfigDiscoveryConcurrency
+	if discoveryConcurrency < 1 {
+		discoveryConcurrency = 1
+	}
+	pollInterval := cfg.NetconfigPollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
 	return &netconfig{
-		cmd:             cmdHelper,
-		os:              osWrapper,
-		host:            hostHelper,
-		sriovnetLib:     sriovnetLib,
-		netlinkLib:      netlinkLib,
-		mellanoxDevices: make(map[string]*MellanoxDevice),
-		bindDelaySec:    bindDelaySec,
+		cmd:                      cmdHelper,
+		os:                       osWrapper,
+		host:                     hostHelper,
+		sriovnetLib:              sriovnetLib,
+		netlinkLib:               netlinkLib,
+		mellanoxDevices:          make(map[string]*MellanoxDevice),
+		bindDelaySec:             cfg.BindDelaySec,
+		skipOnDPU:                cfg.SkipNetconfigOnDPU,
+		requireDevices:           cfg.RequireMellanoxDevices,
+		strictRestore:            cfg.StrictNetconfigRestore,
+		preferIPCommand:          cfg.PreferIPCommand,
+		include:                  cfg.NetconfigInclude,
+		exclude:                  cfg.NetconfigExclude,
+		exportPath:               cfg.NetconfigExportPath,
+		discoveryConcurrency:     discoveryConcurrency,
+		sriovNumVfsRetries:       cfg.SriovNumVfsWriteRetries,
+		sriovNumVfsRetryDelay:    time.Duration(cfg.SriovNumVfsRetryDelayMs) * time.Millisecond,
+		sriovNumVfsSettleTimeout: time.Duration(cfg.SriovNumVfsSettleTimeoutSec) * time.Second,
+		netlinkWaitTimeout:       cfg.NetlinkWaitTimeout,
+		restoreTimeout:           cfg.NetconfigRestoreTimeout,
+		pollInterval:             pollInterval,
+		sysClassNetPath:          filepath.Join(sysfsRoot, "class", "net") + "/",
+		sysBusPCIDevicesPath:     filepath.Join(sysfsRoot, "bus", "pci", "devices") + "/",
+		sysBusPCIDriversPath:     filepath.Join(sysfsRoot, "bus", "pci", "drivers") + "/",
 	}
 }
 
@@ -91,6 +129,22 @@ type Interface interface {
 	// DevicesUseNewNamingScheme returns true if interfaces with the new naming scheme
 	// are on the host or if no NVIDIA devices are found.
 	DevicesUseNewNamingScheme(ctx context.Context) (bool, error)
+	// Summary returns an aggregated view of the devices discovered by the last Save.
+	Summary() DeviceSummary
+	// ExportJSON returns the devices saved by the last Save, including their VFs and representors,
+	// as JSON sorted by netdev name, VF index and representor port for diffing by external tooling.
+	ExportJSON(ctx context.Context) ([]byte, error)
+}
+
+// DeviceSummary is a read-only aggregation of the saved Mellanox devices
+type DeviceSummary struct {
+	PFCount  int            // Number of PFs
+	VFCount  int            // Total number of VFs across all PFs
+	DevTypes map[string]int // Number of PFs per device type ("eth" or "ib")
+	// Per-PF details keyed by netdev name
+	PCIAddrs     map[string]string // Netdev name to PCI address
+	EswitchModes map[string]string // Netdev name to eswitch mode
+	NumVFs       map[string]int    // Netdev name to number of VFs
 }
 
 // VF represents a Virtual Function with all its attributes
@@ -124,16 +178,24 @@ type Representor struct {
 // MellanoxDevice represents a Mellanox network device with all its attributes
 type MellanoxDevice struct {
 	// Basic device information
-	PCIAddr     string // PCI address (e.g., "0000:08:00.0")
-	DevType     string // Device type: "eth" or "ib"
-	AdminState  string // Admin state: "up" or "down"
-	MTU         int    // MTU value
-	GUID        string // Device GUID (for IB) or "-" for Ethernet
-	EswitchMode string // Eswitch mode: "legacy" or "switchdev"
+	PCIAddr     string   // PCI address (e.g., "0000:08:00.0")
+	DevType     string   // Device type: "eth" or "ib"
+	AdminState  string   // Admin state: "up" or "down"
+	MTU         int      // MTU value
+	GUID        string   // Device GUID (for IB) or "-" for Ethernet
+	EswitchMode string   // Eswitch mode: "legacy" or "switchdev"
+	IPAddrs     []string // Static IPv4/IPv6 addresses in CIDR notation (e.g., "192.168.1.10/24")
+	// Eswitch inline-mode and encap-mode (switchdev only), empty if they couldn't be read
+	EswitchInlineMode string
+	EswitchEncapMode  string
+	// LAG membership, empty if the PF isn't enslaved to a bond
+	LAGMaster         string // Bond netdev the PF is enslaved to, the mlx5 LAG is formed once all members are enslaved
+	LAGPortSelectMode string // devlink lag_port_select_mode, empty if it couldn't be read
 
 	// SRIOV information
 	PfNumVfs     int           // Number of VFs configured (from sriov_numvfs)
 	VFs          []VF          // Array of VF information
+	PartialVFs   bool          // Details of some VFs couldn't be collected, VFs holds fewer than PfNumVfs entries
 	Representors []Representor // Array of representor information (for switchdev mode)
 }
 
@@ -147,6 +209,33 @@ type netconfig struct {
 	// In-memory storage - Mellanox device information
 	mellanoxDevices map[string]*MellanoxDevice
 	bindDelaySec    int
+	skipOnDPU       bool
+	requireDevices  bool     // Save fails when mlx5_core is loaded but no Mellanox device is found
+	strictRestore   bool     // Restore fails when some devices or VFs couldn't be restored
+	preferIPCommand bool     // set the VF GUIDs, VF admin MACs, LAG masters and representor names with the ip command instead of netlink
+	include         []string // netdev name or PCI address globs to manage, empty means all
+	exclude         []string // netdev name or PCI address globs to never manage
+	exportPath      string   // file the saved devices are exported to as JSON after Save, empty disables it
+
+	// maximum number of netdevs inspected in parallel by discoverMellanoxDevices
+	discoveryConcurrency int
+
+	// sriov_numvfs write retry and settle settings
+	sriovNumVfsRetries       int
+	sriovNumVfsRetryDelay    time.Duration
+	sriovNumVfsSettleTimeout time.Duration
+
+	// how long restore waits for a link to be registered after the driver reload
+	netlinkWaitTimeout time.Duration
+	// deadline of the whole Restore, zero means no deadline
+	restoreTimeout time.Duration
+	// interval of the sriov_numvfs, VF netdev and link readiness polling
+	pollInterval time.Duration
+
+	// sysfs directories resolved against the configured sysfs root, with trailing slash
+	sysClassNetPath      string
+	sysBusPCIDevicesPath string
+	sysBusPCIDriversPath string
 }
 
 // Save discovers and stores the current SRIOV configuration
@@ -165,6 +254,11 @@ func (n *netconfig) Save(ctx context.Context) error {
 		return nil
 	}
 
+	if n.shouldSkipOnDPU(ctx) {
+		log.Info("BlueField DPU mode detected, skipped store netdev conf info")
+		return nil
+	}
+
 	// Clear existing configuration
 	n.mellanoxDevices = make(map[string]*MellanoxDevice)
 
@@ -175,6 +269,9 @@ func (n *netconfig) Save(ctx context.Context) error {
 	}
 
 	if len(devices) == 0 {
+		if n.requireDevices {
+			return fmt.Errorf("no Mellanox devices found while mlx5_core is loaded and RequireMellanoxDevices is set")
+		}
 		log.Info("No Mellanox devices found, skipping SRIOV configuration")
 		return nil
 	}
@@ -185,10 +282,76 @@ func (n *netconfig) Save(ctx context.Context) error {
 		return fmt.Errorf("failed to discover switchdev representors: %w", err)
 	}
 
+	summary := n.Summary()
+	log.Info("Discovered devices summary", "pfs", summary.PFCount, "vfs", summary.VFCount,
+		"devTypes", summary.DevTypes, "eswitchModes", summary.EswitchModes, "numVfs", summary.NumVFs,
+		"pciAddrs", summary.PCIAddrs)
+
+	if n.exportPath != "" {
+		if err := n.writeExport(ctx); err != nil {
+			log.Error(err, "Failed to export SRIOV configuration", "path", n.exportPath)
+			// Non-fatal error, continue
+		}
+	}
+
 	log.Info("SRIOV configuration saved successfully", "devices", len(n.mellanoxDevices))
 	return nil
 }
 
+// ExportJSON is the default implementation of the netconfig.Interface.
+func (n *netconfig) ExportJSON(_ context.Context) ([]byte, error) {
+	devices := make(map[string]MellanoxDevice, len(n.mellanoxDevices))
+	for devName, device := range n.mellanoxDevices {
+		exported := *device
+		exported.VFs = make([]VF, len(device.VFs))
+		copy(exported.VFs, device.VFs)
+		sort.SliceStable(exported.VFs, func(i, j int) bool {
+			return exported.VFs[i].VFIndex < exported.VFs[j].VFIndex
+		})
+		exported.Representors = sortRepresentors(device.Representors)
+		devices[devName] = exported
+	}
+
+	// Map keys are sorted by encoding/json
+	data, err := json.MarshalIndent(devices, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SRIOV configuration: %w", err)
+	}
+	return data, nil
+}
+
+// writeExport writes the ExportJSON output to the configured export path
+func (n *netconfig) writeExport(ctx context.Context) error {
+	data, err := n.ExportJSON(ctx)
+	if err != nil {
+		return err
+	}
+	if err := n.os.WriteFile(n.exportPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", n.exportPath, err)
+	}
+	logr.FromContextOrDiscard(ctx).V(1).Info("Exported SRIOV configuration", "path", n.exportPath)
+	return nil
+}
+
+// Summary is the default implementation of the netconfig.Interface.
+func (n *netconfig) Summary() DeviceSummary {
+	summary := DeviceSummary{
+		DevTypes:     make(map[string]int),
+		PCIAddrs:     make(map[string]string),
+		EswitchModes: make(map[string]string),
+		NumVFs:       make(map[string]int),
+	}
+	for devName, device := range n.mellanoxDevices {
+		summary.PFCount++
+		summary.VFCount += device.PfNumVfs
+		summary.DevTypes[device.DevType]++
+		summary.PCIAddrs[devName] = device.PCIAddr
+		summary.EswitchModes[devName] = device.EswitchMode
+		summary.NumVFs[devName] = device.PfNumVfs
+	}
+	return summary
+}
+
 // Restore restores the saved SRIOV configuration
 func (n *netconfig) Restore(ctx context.Context) error {
 	log := logr.FromContextOrDiscard(ctx)
@@ -199,110 +362,422 @@ func (n *netconfig) Restore(ctx context.Context) error {
 		return nil
 	}
 
+	if n.shouldSkipOnDPU(ctx) {
+		log.Info("BlueField DPU mode detected, skipping SRIOV configuration restore")
+		return nil
+	}
+
+	if n.restoreTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.restoreTimeout)
+		defer cancel()
+	}
+
+	newScheme := n.representorsUseNewNamingScheme(ctx)
+
 	// Restore each device
+	var stats restoreStats
 	for devName, device := range n.mellanoxDevices {
-		log.Info("Restoring SRIOV config for device", "device", devName, "vfs", device.PfNumVfs)
-
-		// Skip devices with no VFs configured
-		if device.PfNumVfs == 0 {
-			log.V(1).Info("Device has no VFs configured, skipping", "device", devName)
-			continue
+		if err := ctx.Err(); err != nil {
+			log.Error(err, "SRIOV configuration restore timed out", "device", devName, "timeout", n.restoreTimeout)
+			return fmt.Errorf("restore of device %s timed out: %w", devName, err)
 		}
+		log.Info("Restoring SRIOV config for device", "device", devName, "vfs", device.PfNumVfs)
 
-		// Restore PF and VF configuration
-		if err := n.restoreDeviceConfig(ctx, devName, device); err != nil {
+		// Restore PF and VF configuration, a PF without VFs only gets its own settings back
+		stats.devices++
+		stats.vfs += len(device.VFs)
+		failedVFs, err := n.restoreDeviceConfig(ctx, devName, device, newScheme)
+		stats.failedVFs += failedVFs
+		if err != nil {
 			log.Error(err, "Failed to restore device config", "device", devName)
+			if ctx.Err() != nil {
+				// The remaining devices can't be restored within the deadline either
+				return fmt.Errorf("restore of device %s timed out: %w", devName, err)
+			}
+			stats.failedDevices = append(stats.failedDevices, devName)
 			continue
 		}
 
 		log.Info("Successfully restored SRIOV config for device", "device", devName, "vfs", device.PfNumVfs)
 	}
 
+	// The LAGs are formed last, a LAG needs all its member PFs back
+	if err := n.restoreLAGs(ctx, &stats); err != nil {
+		return err
+	}
+
+	sort.Strings(stats.failedDevices)
+	log.Info("SRIOV configuration restore summary", "devices", stats.devices, "failed_devices", len(stats.failedDevices),
+		"vfs", stats.vfs, "failed_vfs", stats.failedVFs, "lags", stats.lags, "failed_lags", len(stats.failedLAGs))
+	if stats.failed() {
+		if n.strictRestore {
+			return fmt.Errorf("SRIOV configuration partially restored: %s", stats)
+		}
+		log.Info("[WARN] SRIOV configuration partially restored", "failed_device_names", stats.failedDevices,
+			"failed_lag_names", stats.failedLAGs)
+		return nil
+	}
+
 	log.Info("SRIOV configuration restored successfully")
 	return nil
 }
 
-// restoreDeviceConfig restores the configuration for a single device and its VFs
-func (n *netconfig) restoreDeviceConfig(ctx context.Context, devName string, device *MellanoxDevice) error {
+// restoreStats counts the devices, VFs and LAGs Restore went through and the ones it failed to restore
+type restoreStats struct {
+	devices       int
+	failedDevices []string
+	vfs           int
+	failedVFs     int
+	lags          int
+	failedLAGs    []string
+}
+
+// failed reports whether a device, a VF or a LAG couldn't be restored
+func (s restoreStats) failed() bool {
+	return len(s.failedDevices) > 0 || s.failedVFs > 0 || len(s.failedLAGs) > 0
+}
+
+// String summarizes the failures for the strict restore error
+func (s restoreStats) String() string {
+	summary := fmt.Sprintf("%d of %d devices and %d of %d VFs failed", len(s.failedDevices), s.devices, s.failedVFs, s.vfs)
+	if len(s.failedDevices) > 0 {
+		summary += " (failed devices: " + strings.Join(s.failedDevices, ", ") + ")"
+	}
+	if len(s.failedLAGs) > 0 {
+		summary += fmt.Sprintf(", %d of %d LAGs failed (failed LAGs: %s)", len(s.failedLAGs), s.lags, strings.Join(s.failedLAGs, ", "))
+	}
+	return summary
+}
+
+// restoreLAGs re-enslaves the PFs to the bonds they were members of, one bond at a time.
+// The LAGs it went through and the failed ones are counted in stats.
+func (n *netconfig) restoreLAGs(ctx context.Context, stats *restoreStats) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	members := make(map[string][]*MellanoxDevice)
+	for _, device := range n.mellanoxDevices {
+		if device.LAGMaster != "" {
+			members[device.LAGMaster] = append(members[device.LAGMaster], device)
+		}
+	}
+	bonds := make([]string, 0, len(members))
+	for bond := range members {
+		bonds = append(bonds, bond)
+	}
+	sort.Strings(bonds)
+
+	for _, bond := range bonds {
+		if err := ctx.Err(); err != nil {
+			log.Error(err, "SRIOV configuration restore timed out", "bond", bond, "timeout", n.restoreTimeout)
+			return fmt.Errorf("restore of LAG %s timed out: %w", bond, err)
+		}
+		sort.Slice(members[bond], func(i, j int) bool {
+			return members[bond][i].PCIAddr < members[bond][j].PCIAddr
+		})
+		stats.lags++
+		if err := n.restoreLAG(ctx, bond, members[bond]); err != nil {
+			log.Error(err, "Failed to restore LAG", "bond", bond)
+			if ctx.Err() != nil {
+				return fmt.Errorf("restore of LAG %s timed out: %w", bond, err)
+			}
+			stats.failedLAGs = append(stats.failedLAGs, bond)
+			continue
+		}
+		log.Info("Successfully restored LAG", "bond", bond, "members", len(members[bond]))
+	}
+	return nil
+}
+
+// restoreLAG enslaves the member PFs to the bond. Nothing is enslaved until the netdevs of
+// all the members are registered, so the mlx5 LAG is never formed with a partial member set.
+func (n *netconfig) restoreLAG(ctx context.Context, bond string, members []*MellanoxDevice) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	bondLink, err := n.waitForLink(ctx, bond, n.netlinkWaitTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to get bond %s: %w", bond, err)
+	}
+
+	memberNames := make([]string, len(members))
+	memberLinks := make([]netlink.Link, len(members))
+	for i, device := range members {
+		memberNames[i], err = n.waitForPFNetdev(ctx, device.PCIAddr, n.netlinkWaitTimeout)
+		if err != nil {
+			return fmt.Errorf("LAG member is missing: %w", err)
+		}
+		memberLinks[i], err = n.waitForLink(ctx, memberNames[i], n.netlinkWaitTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to get LAG member %s: %w", memberNames[i], err)
+		}
+	}
+
+	// The port select mode can only be changed while the LAG is not formed
+	for i, device := range members {
+		if device.LAGPortSelectMode == "" || memberLinks[i].Attrs().MasterIndex == bondLink.Attrs().Index {
+			continue
+		}
+		if err := n.setLAGPortSelectMode(ctx, device.PCIAddr, device.LAGPortSelectMode); err != nil {
+			log.Error(err, "Failed to restore LAG port select mode", "device", memberNames[i], "pci", device.PCIAddr)
+			// Non-fatal error, continue
+		}
+	}
+
+	for i, device := range members {
+		if memberLinks[i].Attrs().MasterIndex == bondLink.Attrs().Index {
+			log.V(1).Info("LAG member already enslaved", "device", memberNames[i], "bond", bond)
+			continue
+		}
+		// A netdev has to be down to be enslaved to a bond
+		if err := n.netlinkLib.LinkSetDown(memberLinks[i]); err != nil {
+			return fmt.Errorf("failed to set LAG member %s down: %w", memberNames[i], err)
+		}
+		var err error
+		if n.preferIPCommand {
+			_, _, err = n.runIP(ctx, "link", "set", "dev", memberNames[i], "master", bond)
+		} else {
+			err = n.netlinkLib.LinkSetMaster(memberLinks[i], bondLink)
+		}
+		if err != nil {
+			// Don't leave the member down, it stays a standalone netdev in its saved admin state
+			if device.AdminState == adminStateUp {
+				if err := n.netlinkLib.LinkSetUp(memberLinks[i]); err != nil {
+					log.Error(err, "Failed to restore LAG member admin state", "device", memberNames[i])
+					// Non-fatal error, continue
+				}
+			}
+			return fmt.Errorf("failed to enslave %s to %s: %w", memberNames[i], bond, err)
+		}
+	}
+	return nil
+}
+
+// setLAGPortSelectMode sets the runtime value of the lag_port_select_mode devlink param
+func (n *netconfig) setLAGPortSelectMode(ctx context.Context, pciAddr, mode string) error {
+	_, _, err := n.cmd.RunCommand(ctx, "devlink", "dev", "param", "set", fmt.Sprintf("pci/%s", pciAddr),
+		"name", lagPortSelectModeParam, "value", mode, "cmode", "runtime")
+	if err != nil {
+		return fmt.Errorf("failed to set LAG port select mode to %s: %w", mode, err)
+	}
+	return nil
+}
+
+// representorsUseNewNamingScheme detects the naming scheme once per Restore, it tells the phys_port_name
+// format the representors are re-identified by. The new scheme is assumed when it can't be detected,
+// the detection is skipped when no switchdev device has representors to restore.
+func (n *netconfig) representorsUseNewNamingScheme(ctx context.Context) bool {
+	log := logr.FromContextOrDiscard(ctx)
+
+	hasRepresentors := false
+	for _, device := range n.mellanoxDevices {
+		if device.EswitchMode == eswitchModeSwitchdev && len(device.Representors) > 0 {
+			hasRepresentors = true
+			break
+		}
+	}
+	if !hasRepresentors {
+		return true
+	}
+
+	newScheme, err := n.DevicesUseNewNamingScheme(ctx)
+	if err != nil {
+		log.Info("[WARN] Failed to detect the naming scheme, assuming the new one for the representors", "error", err)
+		return true
+	}
+	return newScheme
+}
+
+// restoreDeviceConfig restores the configuration for a single device and its VFs. The VFs that
+// couldn't be restored don't fail the device, their number is returned. newScheme is the naming
+// scheme the representors are re-identified by, see representorsUseNewNamingScheme.
+func (n *netconfig) restoreDeviceConfig(ctx context.Context, devName string, device *MellanoxDevice, newScheme bool) (int, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
 	// Get the current device name (might have changed after driver reload)
 	currentDevName, err := n.getCurrentDeviceName(device.PCIAddr)
 	if err != nil {
-		return fmt.Errorf("failed to get current device name: %w", err)
+		return 0, fmt.Errorf("failed to get current device name: %w", err)
 	}
 
 	log.Info("Restoring device config", "original_name", devName, "current_name", currentDevName, "pci", device.PCIAddr)
 
+	if device.PfNumVfs == 0 {
+		return 0, n.restorePFSettings(ctx, currentDevName, device)
+	}
+
 	// Handle switchdev mode (set to legacy first if needed)
 	// To support the old kernel versions, we need to follow the recommended way of creating switchdev VFs
 	// 1) Set the NIC in legacy mode
 	// 2) Create the required amount of VFs
 	// 3) Unbind all of the VFs
 	// 4) Set the NIC in switchdev mode
+	// The device may already be back in switchdev mode with its VFs after the reload, the toggling
+	// is skipped then as bouncing the eswitch disrupts the offloads
+	recreate := true
+	var current eswitchSettings
 	if device.EswitchMode == eswitchModeSwitchdev {
+		current, recreate = n.eswitchNeedsRecreate(ctx, device)
+	}
+	if !recreate {
+		log.Info("Device is already in switchdev mode with its VFs, skipping eswitch mode toggling",
+			"device", currentDevName, "vfs", device.PfNumVfs)
+	}
+	if device.EswitchMode == eswitchModeSwitchdev && recreate {
 		if err := n.setEswitchMode(ctx, device.PCIAddr, eswitchModeLegacy); err != nil {
 			log.Error(err, "Failed to set eswitch mode to legacy", "device", currentDevName)
-			return err
+			return 0, err
 		}
 	}
 
+	// The PF GUID can't be restored, a change is only reported
+	n.detectPFGUIDChange(ctx, currentDevName, device)
+
 	// Restore PF admin state
-	if err := n.setDeviceAdminState(currentDevName, device.AdminState); err != nil {
+	if err := n.setDeviceAdminState(ctx, currentDevName, device.AdminState); err != nil {
 		log.Error(err, "Failed to set PF admin state", "device", currentDevName, "state", device.AdminState)
-		return err
+		return 0, err
 	}
 
-	// Create VFs
-	if err := n.createVFs(device.PCIAddr, device.PfNumVfs); err != nil {
-		log.Error(err, "Failed to create VFs", "device", currentDevName, "vfs", device.PfNumVfs)
-		return err
+	// All the VFs are created, the ones without saved details keep the defaults set by the driver
+	if device.PartialVFs || len(device.VFs) != device.PfNumVfs {
+		log.Info("[WARN] Saved VF details don't cover all VFs, VFs without them keep driver defaults",
+			"device", currentDevName, "vfs", device.PfNumVfs, "saved", len(device.VFs),
+			"missing_vf_indexes", missingVFIndexes(device))
 	}
 
-	// Sleep to wait until NIC device is initialized and udev rules are applied (matches bash script)
-	time.Sleep(time.Duration(n.bindDelaySec) * time.Second)
+	if recreate {
+		// Create VFs
+		if err := n.createVFs(ctx, device.PCIAddr, device.PfNumVfs); err != nil {
+			log.Error(err, "Failed to create VFs", "device", currentDevName, "vfs", device.PfNumVfs)
+			return 0, err
+		}
+
+		// Sleep to wait until NIC device is initialized and udev rules are applied (matches bash script)
+		if err := n.waitBindDelay(ctx); err != nil {
+			return 0, err
+		}
+	}
 
 	// Restore VF configurations (but don't rebind VFs if in switchdev mode)
-	if err := n.restoreVFConfigurations(ctx, currentDevName, device, device.EswitchMode); err != nil {
+	failedVFs, err := n.restoreVFConfigurations(ctx, currentDevName, device, device.EswitchMode)
+	if err != nil {
 		log.Error(err, "Failed to restore VF configurations", "device", currentDevName)
-		return err
+		return failedVFs, err
 	}
 
 	// Set switchdev mode if needed
 	if device.EswitchMode == eswitchModeSwitchdev {
-		if err := n.setEswitchMode(ctx, device.PCIAddr, eswitchModeSwitchdev); err != nil {
-			log.Error(err, "Failed to set eswitch mode to switchdev", "device", currentDevName)
-			return err
+		if recreate {
+			if err := n.setEswitchMode(ctx, device.PCIAddr, eswitchModeSwitchdev); err != nil {
+				log.Error(err, "Failed to set eswitch mode to switchdev", "device", currentDevName)
+				return failedVFs, err
+			}
+		}
+
+		if recreate || current.InlineMode != device.EswitchInlineMode || current.EncapMode != device.EswitchEncapMode {
+			if err := n.setEswitchInlineAndEncapMode(ctx, device.PCIAddr, device.EswitchInlineMode, device.EswitchEncapMode); err != nil {
+				log.Error(err, "Failed to restore eswitch inline/encap mode", "device", currentDevName,
+					"inline_mode", device.EswitchInlineMode, "encap_mode", device.EswitchEncapMode)
+				// Non-fatal error, continue
+			}
 		}
 
 		// Rebind VFs in switchdev mode
 		if err := n.rebindVFsInSwitchdevMode(ctx, device); err != nil {
 			log.Error(err, "Failed to rebind VFs in switchdev mode", "device", currentDevName)
-			return err
+			return failedVFs, err
 		}
 	}
 
 	// Restore PF MTU
-	if err := n.setDeviceMTU(currentDevName, device.MTU); err != nil {
+	if err := n.setDeviceMTU(ctx, currentDevName, device.MTU); err != nil {
 		log.Error(err, "Failed to set PF MTU", "device", currentDevName, "mtu", device.MTU)
-		return err
+		return failedVFs, err
+	}
+
+	// Restore PF IP addresses
+	if err := n.restoreDeviceIPAddrs(ctx, currentDevName, device.IPAddrs); err != nil {
+		log.Error(err, "Failed to restore PF IP addresses", "device", currentDevName)
+		// Non-fatal error, continue
 	}
 
 	// Restore representors if in switchdev mode
 	if device.EswitchMode == eswitchModeSwitchdev && len(device.Representors) > 0 {
-		if err := n.restoreRepresentors(ctx, currentDevName, device); err != nil {
+		if err := n.restoreRepresentors(ctx, currentDevName, device, newScheme); err != nil {
 			log.Error(err, "Failed to restore representors", "device", currentDevName)
 			// Don't fail the entire restore for representor issues
 		}
 	}
 
+	return failedVFs, nil
+}
+
+// restorePFSettings restores the admin state, MTU and IP addresses of a PF without VFs, the VF,
+// eswitch and representor steps of restoreDeviceConfig don't apply to it
+func (n *netconfig) restorePFSettings(ctx context.Context, devName string, device *MellanoxDevice) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	// The PF GUID can't be restored, a change is only reported
+	n.detectPFGUIDChange(ctx, devName, device)
+
+	if err := n.setDeviceAdminState(ctx, devName, device.AdminState); err != nil {
+		log.Error(err, "Failed to set PF admin state", "device", devName, "state", device.AdminState)
+		return err
+	}
+	if err := n.setDeviceMTU(ctx, devName, device.MTU); err != nil {
+		log.Error(err, "Failed to set PF MTU", "device", devName, "mtu", device.MTU)
+		return err
+	}
+	if err := n.restoreDeviceIPAddrs(ctx, devName, device.IPAddrs); err != nil {
+		log.Error(err, "Failed to restore PF IP addresses", "device", devName)
+		// Non-fatal error, continue
+	}
 	return nil
 }
 
+// eswitchNeedsRecreate reports whether the legacy/switchdev toggling and the VFs creation are needed
+// to restore a switchdev device, they aren't when the device is already in switchdev mode with the
+// saved number of VFs. The current eswitch settings are returned along.
+func (n *netconfig) eswitchNeedsRecreate(ctx context.Context, device *MellanoxDevice) (eswitchSettings, bool) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	current, err := n.getEswitchSettings(ctx, device.PCIAddr)
+	if err != nil {
+		log.V(1).Info("Failed to get current eswitch mode", "pci", device.PCIAddr, "error", err)
+		return eswitchSettings{}, true
+	}
+	if current.Mode != eswitchModeSwitchdev {
+		return current, true
+	}
+
+	sriovNumVfsPath := fmt.Sprintf("%s%s/sriov_numvfs", n.sysBusPCIDevicesPath, device.PCIAddr)
+	data, err := n.os.ReadFile(sriovNumVfsPath)
+	if err != nil {
+		log.V(1).Info("Failed to read current number of VFs", "path", sriovNumVfsPath, "error", err)
+		return current, true
+	}
+	return current, strings.TrimSpace(string(data)) != strconv.Itoa(device.PfNumVfs)
+}
+
+// missingVFIndexes returns the indexes of the device VFs that have no saved details
+func missingVFIndexes(device *MellanoxDevice) []int {
+	saved := make(map[int]bool, len(device.VFs))
+	for _, vf := range device.VFs {
+		saved[vf.VFIndex] = true
+	}
+	missing := []int{}
+	for vfIndex := range device.PfNumVfs {
+		if !saved[vfIndex] {
+			missing = append(missing, vfIndex)
+		}
+	}
+	return missing
+}
+
 // getCurrentDeviceName gets the current device name after driver reload
 func (n *netconfig) getCurrentDeviceName(pciAddr string) (string, error) {
 	// Get device name from PCI path: /sys/bus/pci/devices/{pci_addr}/net/
-	pciDevPath := fmt.Sprintf("%s%s/net", sysBusPCIDevicesPath, pciAddr)
+	pciDevPath := fmt.Sprintf("%s%s/net", n.sysBusPCIDevicesPath, pciAddr)
 	entries, err := n.os.ReadDir(pciDevPath)
 	if err != nil {
 		return "", err
@@ -318,17 +793,17 @@ func (n *netconfig) getCurrentDeviceName(pciAddr string) (string, error) {
 // setEswitchMode sets the eswitch mode for a device
 func (n *netconfig) setEswitchMode(ctx context.Context, pciAddr, mode string) error {
 	// Use devlink command: devlink dev eswitch set pci/{pci_addr} mode {mode}
-	_, stderr, err := n.cmd.RunCommand(ctx, "devlink", "dev", "eswitch", "set", fmt.Sprintf("pci/%s", pciAddr), "mode", mode)
+	_, _, err := n.cmd.RunCommand(ctx, "devlink", "dev", "eswitch", "set", fmt.Sprintf("pci/%s", pciAddr), "mode", mode)
 	if err != nil {
-		return fmt.Errorf("failed to set eswitch mode to %s: %w, stderr: %s", mode, err, stderr)
+		return fmt.Errorf("failed to set eswitch mode to %s: %w", mode, err)
 	}
 	return nil
 }
 
 // setDeviceAdminState sets the admin state of a device
-func (n *netconfig) setDeviceAdminState(devName, state string) error {
+func (n *netconfig) setDeviceAdminState(ctx context.Context, devName, state string) error {
 	// Use netlink instead of ip command for better error handling and performance
-	link, err := n.netlinkLib.LinkByName(devName)
+	link, err := n.waitForLink(ctx, devName, n.netlinkWaitTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to get link %s: %w", devName, err)
 	}
@@ -345,34 +820,167 @@ func (n *netconfig) setDeviceAdminState(devName, state string) error {
 	return nil
 }
 
-// createVFs creates the specified number of VFs
-func (n *netconfig) createVFs(pciAddr string, numVFs int) error {
+// waitForLink gets a link by name, polling until timeout since right after the driver reload
+// the link may not be registered yet. A zero timeout tries once.
+func (n *netconfig) waitForLink(ctx context.Context, name string, timeout time.Duration) (netlink.Link, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		link, err := n.netlinkLib.LinkByName(name)
+		if err == nil {
+			return link, nil
+		}
+		if !time.Now().Before(deadline) {
+			if timeout > 0 {
+				return nil, fmt.Errorf("not registered within %s: %w", timeout, err)
+			}
+			return nil, err
+		}
+		if err := n.waitPollInterval(ctx); err != nil {
+			return nil, fmt.Errorf("link %s not registered: %w", name, err)
+		}
+	}
+}
+
+// waitForVFNetdev gets the netdev name of a VF, polling until timeout since the VF netdev
+// is registered asynchronously after the VFs are created. A zero timeout tries once.
+func (n *netconfig) waitForVFNetdev(ctx context.Context, vfPCIAddr string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		name, err := n.getCurrentVFName(vfPCIAddr)
+		if err == nil {
+			return name, nil
+		}
+		if !time.Now().Before(deadline) {
+			if timeout > 0 {
+				return "", fmt.Errorf("VF %s netdev not registered within %s: %w", vfPCIAddr, timeout, err)
+			}
+			return "", err
+		}
+		if err := n.waitPollInterval(ctx); err != nil {
+			return "", fmt.Errorf("VF %s netdev not registered: %w", vfPCIAddr, err)
+		}
+	}
+}
+
+// waitForPFNetdev gets the netdev name of a PF, polling until timeout since the PF netdev
+// is registered asynchronously after the driver reload. A zero timeout tries once.
+func (n *netconfig) waitForPFNetdev(ctx context.Context, pciAddr string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		name, err := n.getCurrentDeviceName(pciAddr)
+		if err == nil {
+			return name, nil
+		}
+		if !time.Now().Before(deadline) {
+			if timeout > 0 {
+				return "", fmt.Errorf("PF %s netdev not registered within %s: %w", pciAddr, timeout, err)
+			}
+			return "", err
+		}
+		if err := n.waitPollInterval(ctx); err != nil {
+			return "", fmt.Errorf("PF %s netdev not registered: %w", pciAddr, err)
+		}
+	}
+}
+
+// waitPollInterval waits for the poll interval, returning early with the context error
+// when the Restore deadline is hit
+func (n *netconfig) waitPollInterval(ctx context.Context) error {
+	return wait(ctx, n.pollInterval)
+}
+
+// waitBindDelay waits for the bind delay after the VFs are created or bound, returning early
+// with the context error when the Restore deadline is hit
+func (n *netconfig) waitBindDelay(ctx context.Context) error {
+	return wait(ctx, time.Duration(n.bindDelaySec)*time.Second)
+}
+
+// wait waits for the given duration or until the context is done, the context error is returned then
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// createVFs creates the specified number of VFs.
+// The write is retried since some firmware returns EBUSY right after a driver reload,
+// then sriov_numvfs is polled until the kernel reports the requested VF count.
+func (n *netconfig) createVFs(ctx context.Context, pciAddr string, numVFs int) error {
+	log := logr.FromContextOrDiscard(ctx)
+
 	// Write to sriov_numvfs: echo {num_vfs} > /sys/bus/pci/devices/{pci_addr}/sriov_numvfs
-	sriovNumVfsPath := fmt.Sprintf("%s%s/sriov_numvfs", sysBusPCIDevicesPath, pciAddr)
+	sriovNumVfsPath := fmt.Sprintf("%s%s/sriov_numvfs", n.sysBusPCIDevicesPath, pciAddr)
 	numVFsStr := fmt.Sprintf("%d", numVFs)
 
-	// Use the OS wrapper to write the file
-	if err := n.os.WriteFile(sriovNumVfsPath, []byte(numVFsStr), 0o644); err != nil {
+	delay := n.sriovNumVfsRetryDelay
+	var err error
+	for attempt := 0; attempt <= n.sriovNumVfsRetries; attempt++ {
+		if attempt > 0 {
+			log.V(1).Info("Retrying sriov_numvfs write", "pci", pciAddr, "attempt", attempt, "error", err)
+			if err := wait(ctx, delay); err != nil {
+				return fmt.Errorf("failed to create %d VFs: %w", numVFs, err)
+			}
+			delay *= 2
+		}
+		if err = n.os.WriteFile(sriovNumVfsPath, []byte(numVFsStr), 0o644); err == nil {
+			break
+		}
+	}
+	if err != nil {
 		return fmt.Errorf("failed to create %d VFs: %w", numVFs, err)
 	}
 
-	return nil
+	return n.waitForNumVFs(ctx, sriovNumVfsPath, numVFsStr)
 }
 
-// restoreVFConfigurations restores the configuration for all VFs
-func (n *netconfig) restoreVFConfigurations(ctx context.Context, devName string, device *MellanoxDevice, eswitchMode string) error {
+// waitForNumVFs polls sriov_numvfs until it reads the expected value or the settle timeout elapses
+func (n *netconfig) waitForNumVFs(ctx context.Context, sriovNumVfsPath, expected string) error {
+	deadline := time.Now().Add(n.sriovNumVfsSettleTimeout)
+	lastValue := ""
+	for {
+		data, err := n.os.ReadFile(sriovNumVfsPath)
+		if err == nil {
+			lastValue = strings.TrimSpace(string(data))
+			if lastValue == expected {
+				return nil
+			}
+		} else {
+			lastValue = fmt.Sprintf("<read error: %v>", err)
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%s did not converge to %s within %s, last value: %s",
+				sriovNumVfsPath, expected, n.sriovNumVfsSettleTimeout, lastValue)
+		}
+		if err := n.waitPollInterval(ctx); err != nil {
+			return fmt.Errorf("%s did not converge to %s, last value: %s: %w", sriovNumVfsPath, expected, lastValue, err)
+		}
+	}
+}
+
+// restoreVFConfigurations restores the configuration for all VFs and returns the number of VFs it failed to restore
+func (n *netconfig) restoreVFConfigurations(ctx context.Context, devName string, device *MellanoxDevice, eswitchMode string) (int, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
+	failed := 0
 	for _, vf := range device.VFs {
 		log.V(1).Info("Restoring VF config", "device", devName, "vf_index", vf.VFIndex, "vf_pci", vf.VFPCIAddr)
 
 		if err := n.restoreSingleVFConfig(ctx, devName, vf, device.DevType, eswitchMode); err != nil {
 			log.Error(err, "Failed to restore VF config", "device", devName, "vf_index", vf.VFIndex)
+			failed++
+			if ctx.Err() != nil {
+				return failed, fmt.Errorf("VF %d (%s): %w", vf.VFIndex, vf.VFPCIAddr, err)
+			}
 			continue // Continue with other VFs
 		}
 	}
 
-	return nil
+	return failed, nil
 }
 
 // restoreSingleVFConfig restores the configuration for a single VF
@@ -388,6 +996,12 @@ func (n *netconfig) restoreSingleVFConfig(ctx context.Context, devName string, v
 				return err
 			}
 		}
+	} else if eswitchMode == eswitchModeSwitchdev {
+		// The VF netdev only exists after the rebind, the hardware MAC is set in rebindVFsInSwitchdevMode
+		if err := n.setVFAdminMAC(ctx, devName, vf); err != nil {
+			log.Error(err, "Failed to set VF admin MAC", "device", devName, "vf_index", vf.VFIndex)
+			return err
+		}
 	} else {
 		// For Ethernet devices, set MAC addresses
 		if err := n.setEthernetMACs(ctx, devName, vf); err != nil {
@@ -411,10 +1025,12 @@ func (n *netconfig) restoreSingleVFConfig(ctx context.Context, devName string, v
 		}
 
 		// Wait for bind delay (matches bash script)
-		time.Sleep(time.Duration(n.bindDelaySec) * time.Second)
+		if err := n.waitBindDelay(ctx); err != nil {
+			return err
+		}
 
 		// Restore VF MTU and admin state after rebind
-		if err := n.restoreVFState(vf); err != nil {
+		if err := n.restoreVFState(ctx, vf); err != nil {
 			log.Error(err, "Failed to restore VF state after rebind", "device", devName, "vf_index", vf.VFIndex, "vf_pci", vf.VFPCIAddr)
 			return err
 		}
@@ -430,6 +1046,11 @@ func (n *netconfig) restoreSingleVFConfig(ctx context.Context, devName string, v
 func (n *netconfig) setIBGUIDs(ctx context.Context, devName string, vfIndex int, guid string) error {
 	log := logr.FromContextOrDiscard(ctx)
 
+	guid, err := n.restructureGUID(guid)
+	if err != nil {
+		return fmt.Errorf("failed to set GUIDs for VF %d: %w", vfIndex, err)
+	}
+
 	// Check for invalid GUID (all zeros) - matches bash script line 880
 	// Do not set invalid GUID as it will cause issues
 	if guid == constants.InvalidGUID {
@@ -438,23 +1059,92 @@ func (n *netconfig) setIBGUIDs(ctx context.Context, devName string, vfIndex int,
 		return nil
 	}
 
+	if n.preferIPCommand {
+		return n.setIBGUIDsWithIP(ctx, devName, vfIndex, guid)
+	}
+
+	hwGUID, err := net.ParseMAC(guid)
+	if err != nil {
+		return fmt.Errorf("failed to parse VF GUID %s: %w", guid, err)
+	}
+	link, err := n.netlinkLib.LinkByName(devName)
+	if err != nil {
+		return fmt.Errorf("failed to get link %s: %w", devName, err)
+	}
+	if err := n.netlinkLib.LinkSetVfPortGUID(link, vfIndex, hwGUID); err != nil {
+		return fmt.Errorf("failed to set port GUID: %w", err)
+	}
+	if err := n.netlinkLib.LinkSetVfNodeGUID(link, vfIndex, hwGUID); err != nil {
+		return fmt.Errorf("failed to set node GUID: %w", err)
+	}
+
+	return nil
+}
+
+// setIBGUIDsWithIP sets the GUIDs for an IB VF with the ip command
+func (n *netconfig) setIBGUIDsWithIP(ctx context.Context, devName string, vfIndex int, guid string) error {
 	// Set port GUID: ip link set {dev_name} vf {vf_index} port_guid {guid}
-	_, stderr, err := n.cmd.RunCommand(ctx, "ip", "link", "set", devName, "vf", fmt.Sprintf("%d", vfIndex), "port_guid", guid)
+	_, _, err := n.runIP(ctx, "link", "set", devName, "vf", fmt.Sprintf("%d", vfIndex), "port_guid", guid)
 	if err != nil {
-		return fmt.Errorf("failed to set port GUID: %w, stderr: %s", err, stderr)
+		return fmt.Errorf("failed to set port GUID: %w", err)
 	}
 
 	// Set node GUID: ip link set {dev_name} vf {vf_index} node_guid {guid}
-	_, stderr, err = n.cmd.RunCommand(ctx, "ip", "link", "set", devName, "vf", fmt.Sprintf("%d", vfIndex), "node_guid", guid)
+	_, _, err = n.runIP(ctx, "link", "set", devName, "vf", fmt.Sprintf("%d", vfIndex), "node_guid", guid)
 	if err != nil {
-		return fmt.Errorf("failed to set node GUID: %w, stderr: %s", err, stderr)
+		return fmt.Errorf("failed to set node GUID: %w", err)
 	}
 
 	return nil
 }
 
+// runIP runs the ip command, a missing ip binary is reported with ErrIPCommandNotFound
+func (n *netconfig) runIP(ctx context.Context, args ...string) (string, string, error) {
+	stdout, stderr, err := n.cmd.RunCommand(ctx, "ip", args...)
+	var notFoundErr *cmd.ErrCommandNotFound
+	if errors.As(err, &notFoundErr) {
+		return stdout, stderr, fmt.Errorf("%w: %w", ErrIPCommandNotFound, err)
+	}
+	return stdout, stderr, err
+}
+
+// detectPFGUIDChange reports and warns when the node GUID of an IB PF changed across the driver
+// reload. This is detection only: the PF GUID is assigned by the firmware and the kernel has no
+// interface to set it at runtime, unlike the VF GUIDs restored by setIBGUIDs. Ethernet PFs, PFs
+// without a saved GUID and PFs whose current GUID can't be read are reported as unchanged.
+func (n *netconfig) detectPFGUIDChange(ctx context.Context, devName string, device *MellanoxDevice) bool {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if device.DevType != devTypeIB || device.GUID == "-" || device.GUID == "" || device.GUID == constants.InvalidGUID {
+		return false
+	}
+
+	current, err := n.getIBGUID(devName)
+	if err == nil {
+		current, err = n.restructureGUID(current)
+	}
+	if err != nil {
+		log.V(1).Info("Failed to read PF GUID", "device", devName, "error", err)
+		return false
+	}
+	if current == device.GUID {
+		return false
+	}
+	log.Info("[WARN] PF GUID changed across the driver reload, it is assigned by the firmware and isn't restored",
+		"device", devName, "saved", device.GUID, "current", current)
+	return true
+}
+
 // setEthernetMACs sets the MAC addresses for an Ethernet VF
 func (n *netconfig) setEthernetMACs(ctx context.Context, devName string, vf VF) error {
+	if err := n.setVFHardwareMAC(vf); err != nil {
+		return err
+	}
+	return n.setVFAdminMAC(ctx, devName, vf)
+}
+
+// setVFHardwareMAC sets the MAC address of the VF netdev, the VF must be bound to the driver
+func (n *netconfig) setVFHardwareMAC(vf VF) error {
 	// Get current VF device name
 	currentVFName, err := n.getCurrentVFName(vf.VFPCIAddr)
 	if err != nil {
@@ -477,11 +1167,30 @@ func (n *netconfig) setEthernetMACs(ctx context.Context, devName string, vf VF)
 		return fmt.Errorf("failed to set VF hardware MAC: %w", err)
 	}
 
-	// Set VF admin MAC: ip link set dev {pf_name} vf {vf_index} mac {admin_mac}
-	// Note: This still requires ip command as netlink doesn't have direct VF admin MAC support
-	_, stderr, err := n.cmd.RunCommand(ctx, "ip", "link", "set", "dev", devName, "vf", fmt.Sprintf("%d", vf.VFIndex), "mac", vf.AdminMAC)
+	return nil
+}
+
+// setVFAdminMAC sets the admin MAC of a VF through its PF, the VF netdev is not needed
+func (n *netconfig) setVFAdminMAC(ctx context.Context, devName string, vf VF) error {
+	if n.preferIPCommand {
+		// Set VF admin MAC: ip link set dev {pf_name} vf {vf_index} mac {admin_mac}
+		_, _, err := n.runIP(ctx, "link", "set", "dev", devName, "vf", fmt.Sprintf("%d", vf.VFIndex), "mac", vf.AdminMAC)
+		if err != nil {
+			return fmt.Errorf("failed to set VF admin MAC: %w", err)
+		}
+		return nil
+	}
+
+	mac, err := net.ParseMAC(vf.AdminMAC)
 	if err != nil {
-		return fmt.Errorf("failed to set VF admin MAC: %w, stderr: %s", err, stderr)
+		return fmt.Errorf("failed to parse VF admin MAC %s: %w", vf.AdminMAC, err)
+	}
+	link, err := n.netlinkLib.LinkByName(devName)
+	if err != nil {
+		return fmt.Errorf("failed to get link %s: %w", devName, err)
+	}
+	if err := n.netlinkLib.LinkSetVfHardwareAddr(link, vf.VFIndex, mac); err != nil {
+		return fmt.Errorf("failed to set VF admin MAC: %w", err)
 	}
 
 	return nil
@@ -490,7 +1199,7 @@ func (n *netconfig) setEthernetMACs(ctx context.Context, devName string, vf VF)
 // getCurrentVFName gets the current VF device name after driver reload
 func (n *netconfig) getCurrentVFName(vfPCIAddr string) (string, error) {
 	// Get VF name from PCI path: /sys/bus/pci/devices/{vf_pci_addr}/net/
-	vfPciDevPath := fmt.Sprintf("%s%s/net", sysBusPCIDevicesPath, vfPCIAddr)
+	vfPciDevPath := fmt.Sprintf("%s%s/net", n.sysBusPCIDevicesPath, vfPCIAddr)
 	entries, err := n.os.ReadDir(vfPciDevPath)
 	if err != nil {
 		return "", err
@@ -517,10 +1226,20 @@ func (n *netconfig) rebindVFsInSwitchdevMode(ctx context.Context, device *Mellan
 		}
 
 		// Wait for bind delay (matches bash script)
-		time.Sleep(time.Duration(n.bindDelaySec) * time.Second)
+		if err := n.waitBindDelay(ctx); err != nil {
+			return err
+		}
+
+		// The admin MAC was set before the rebind, the hardware MAC needs the VF netdev
+		if device.DevType != devTypeIB {
+			if err := n.setVFHardwareMAC(vf); err != nil {
+				log.Error(err, "Failed to set VF hardware MAC", "vf_pci", vf.VFPCIAddr)
+				continue
+			}
+		}
 
 		// Restore VF MTU and admin state
-		if err := n.restoreVFState(vf); err != nil {
+		if err := n.restoreVFState(ctx, vf); err != nil {
 			log.Error(err, "Failed to restore VF state", "vf_pci", vf.VFPCIAddr)
 			continue
 		}
@@ -532,22 +1251,22 @@ func (n *netconfig) rebindVFsInSwitchdevMode(ctx context.Context, device *Mellan
 // getDriverPath gets the driver path for a VF PCI address
 func (n *netconfig) getDriverPath(vfPCIAddr string) string {
 	// Try to get the current driver from the VF's driver symlink
-	driverLink := fmt.Sprintf("%s%s/driver", sysBusPCIDevicesPath, vfPCIAddr)
+	driverLink := fmt.Sprintf("%s%s/driver", n.sysBusPCIDevicesPath, vfPCIAddr)
 	driverPath, err := n.os.Readlink(driverLink)
 	if err != nil {
 		// If no driver is bound, use the default mlx5_core driver
-		return defaultDriverPath
+		return n.sysBusPCIDriversPath + defaultDriverName
 	}
 
 	// Extract the driver name from the symlink path
 	// driverPath is like "../../../../bus/pci/drivers/mlx5_core"
 	parts := strings.Split(driverPath, "/")
 	if len(parts) == 0 {
-		return defaultDriverPath // Fallback to default
+		return n.sysBusPCIDriversPath + defaultDriverName // Fallback to default
 	}
 
 	driverName := parts[len(parts)-1]
-	return fmt.Sprintf("%s%s", sysBusPCIDriversPath, driverName)
+	return fmt.Sprintf("%s%s", n.sysBusPCIDriversPath, driverName)
 }
 
 // unbindVFFromDriver unbinds a VF from its driver
@@ -581,15 +1300,15 @@ func (n *netconfig) bindVFToDriver(vfPCIAddr string) error {
 }
 
 // restoreVFState restores the MTU and admin state of a VF
-func (n *netconfig) restoreVFState(vf VF) error {
-	// Get current VF name
-	currentVFName, err := n.getCurrentVFName(vf.VFPCIAddr)
+func (n *netconfig) restoreVFState(ctx context.Context, vf VF) error {
+	// Get current VF name, the VF netdev may not be registered yet right after the VFs are created
+	currentVFName, err := n.waitForVFNetdev(ctx, vf.VFPCIAddr, n.netlinkWaitTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to get current VF name: %w", err)
 	}
 
 	// Get VF link once and use it for both operations
-	link, err := n.netlinkLib.LinkByName(currentVFName)
+	link, err := n.waitForLink(ctx, currentVFName, n.netlinkWaitTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to get VF link %s: %w", currentVFName, err)
 	}
@@ -613,10 +1332,77 @@ func (n *netconfig) restoreVFState(vf VF) error {
 	return nil
 }
 
+// getDeviceIPAddrs returns the static addresses assigned to the link in CIDR notation.
+// IPv6 link-local addresses are skipped, the kernel re-adds them when the link comes up,
+// and so are the DHCP and SLAAC addresses, their clients re-add them.
+func (n *netconfig) getDeviceIPAddrs(link netlink.Link) ([]string, error) {
+	addrs, err := n.netlinkLib.AddrList(link, netlink.FamilyAll)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses for %s: %w", link.Attrs().Name, err)
+	}
+
+	var ipAddrs []string
+	for _, addr := range addrs {
+		if addr.IPNet == nil {
+			continue
+		}
+		if addr.IP.To4() == nil && addr.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		// Dynamic addresses are renewed by their DHCP client or SLAAC, restoring them as static
+		// addresses would conflict once the lease changes
+		if addr.Flags&netlink.AddrFlagPermanent == 0 && addr.ValidLft != netlink.InfiniteLifetime {
+			continue
+		}
+		ipAddrs = append(ipAddrs, addr.IPNet.String())
+	}
+	return ipAddrs, nil
+}
+
+// restoreDeviceIPAddrs adds the saved addresses to the device, skipping those already present
+func (n *netconfig) restoreDeviceIPAddrs(ctx context.Context, devName string, ipAddrs []string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if len(ipAddrs) == 0 {
+		return nil
+	}
+
+	link, err := n.netlinkLib.LinkByName(devName)
+	if err != nil {
+		return fmt.Errorf("failed to get link %s: %w", devName, err)
+	}
+
+	current, err := n.getDeviceIPAddrs(link)
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(current))
+	for _, addr := range current {
+		present[addr] = true
+	}
+
+	for _, ipAddr := range ipAddrs {
+		if present[ipAddr] {
+			log.V(1).Info("IP address already present, skipping", "device", devName, "address", ipAddr)
+			continue
+		}
+		ip, ipNet, err := net.ParseCIDR(ipAddr)
+		if err != nil {
+			return fmt.Errorf("invalid IP address %q: %w", ipAddr, err)
+		}
+		ipNet.IP = ip
+		if err := n.netlinkLib.AddrAdd(link, &netlink.Addr{IPNet: ipNet}); err != nil {
+			return fmt.Errorf("failed to add IP address %s to %s: %w", ipAddr, devName, err)
+		}
+		log.V(1).Info("Restored IP address", "device", devName, "address", ipAddr)
+	}
+	return nil
+}
+
 // setDeviceMTU sets the MTU of a device
-func (n *netconfig) setDeviceMTU(devName string, mtu int) error {
+func (n *netconfig) setDeviceMTU(ctx context.Context, devName string, mtu int) error {
 	// Use netlink instead of sysfs for better error handling and performance
-	link, err := n.netlinkLib.LinkByName(devName)
+	link, err := n.waitForLink(ctx, devName, n.netlinkWaitTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to get link %s: %w", devName, err)
 	}
@@ -642,74 +1428,108 @@ func (n *netconfig) isMlx5CoreLoaded(ctx context.Context) (bool, error) {
 
 // discoverMellanoxDevices discovers all Mellanox network devices and collects detailed information
 func (n *netconfig) discoverMellanoxDevices(ctx context.Context) ([]string, error) {
-	log := logr.FromContextOrDiscard(ctx)
-
 	// Get all network interfaces from sysfs (matches bash script approach)
-	entries, err := n.os.ReadDir(sysClassNetPath)
+	entries, err := n.os.ReadDir(n.sysClassNetPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read /sys/class/net: %w", err)
+		return nil, fmt.Errorf("failed to read %s: %w", n.sysClassNetPath, err)
 	}
 
 	devices := make([]string, 0, len(entries))
 
-	// Filter for Mellanox devices and collect detailed info
+	// Discover devices with a bounded number of workers, failures of one device don't affect others
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, n.discoveryConcurrency)
+	)
 	for _, entry := range entries {
 		devName := entry.Name()
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			device := n.discoverMellanoxDevice(ctx, devName)
+			if device == nil {
+				return
+			}
 
-		// Check vendor first (more efficient than PCI lookup)
-		if !n.isMellanoxDeviceByInterface(devName) {
-			continue
-		}
-
-		// Get PCI address using sriovnet library
-		pciAddr, err := n.sriovnetLib.GetPciFromNetDevice(devName)
-		if err != nil {
-			log.V(1).Info("Could not get PCI address for device", "device", devName, "error", err)
-			continue
-		}
+			// Store the device information
+			mu.Lock()
+			defer mu.Unlock()
+			n.mellanoxDevices[devName] = device
+			devices = append(devices, devName)
+		}()
+	}
+	wg.Wait()
 
-		log.V(1).Info("Found Mellanox device", "device", devName, "pci", pciAddr)
+	// Keep the result (and the logs that follow) independent of the discovery order
+	sort.Strings(devices)
+	return devices, nil
+}
 
-		// Get netlink link for additional attributes (admin state, MTU)
-		link, err := n.netlinkLib.LinkByName(devName)
-		if err != nil {
-			log.V(1).Info("Could not get netlink link", "device", devName, "error", err)
-			// Continue without netlink info - we can still collect basic info
-			link = nil
-		}
-		// Get eswitch mode
-		// This matches bash: eswitch_mode=$(devlink dev eswitch show pci/$pci_addr 2>/dev/null |
-		// awk '{for (i=1; i<=NF; i++) if ($i == "mode") {print $(i+1); exit}}')
-		eswitchMode, err := n.getEswitchMode(ctx, pciAddr)
-		if err != nil {
-			log.V(1).Info("Could not get eswitch mode", "device", devName, "pci", pciAddr, "error", err)
-			eswitchMode = eswitchModeLegacy // Default to legacy mode
-		}
+// discoverMellanoxDevice collects the information of a single netdev.
+// It returns nil if the netdev is not a Mellanox PF managed by netconfig.
+func (n *netconfig) discoverMellanoxDevice(ctx context.Context, devName string) *MellanoxDevice {
+	log := logr.FromContextOrDiscard(ctx)
 
-		if eswitchMode == eswitchModeSwitchdev {
-			// Skip VF representors
-			if n.isRepresentor(devName) {
-				log.V(1).Info("Skipping VF representor", "device", devName)
-				continue
-			}
-		}
+	// Check vendor first (more efficient than PCI lookup)
+	if !n.isMellanoxDeviceByInterface(devName) {
+		return nil
+	}
 
-		// Collect detailed device information
-		device := n.collectDeviceInfo(ctx, devName, pciAddr, link)
+	// Get PCI address using sriovnet library
+	pciAddr, err := n.sriovnetLib.GetPciFromNetDevice(devName)
+	if err != nil {
+		log.V(1).Info("Could not get PCI address for device", "device", devName, "error", err)
+		return nil
+	}
 
-		device.EswitchMode = eswitchMode
+	if !n.isDeviceManaged(devName, pciAddr) {
+		log.Info("Device is filtered out by netconfig include/exclude lists, skipping", "device", devName, "pci", pciAddr)
+		return nil
+	}
 
-		// Collect VF information if VFs are configured
-		n.collectVFInfo(ctx, devName, device)
+	log.V(1).Info("Found Mellanox device", "device", devName, "pci", pciAddr)
 
-		// Store the device information
-		n.mellanoxDevices[devName] = device
-		devices = append(devices, devName)
+	// Get netlink link for additional attributes (admin state, MTU)
+	link, err := n.netlinkLib.LinkByName(devName)
+	if err != nil {
+		log.V(1).Info("Could not get netlink link", "device", devName, "error", err)
+		// Continue without netlink info - we can still collect basic info
+		link = nil
+	}
+	// Get eswitch mode
+	// This matches bash: eswitch_mode=$(devlink dev eswitch show pci/$pci_addr 2>/dev/null |
+	// awk '{for (i=1; i<=NF; i++) if ($i == "mode") {print $(i+1); exit}}')
+	eswitch, err := n.getEswitchSettings(ctx, pciAddr)
+	if err != nil {
+		log.V(1).Info("Could not get eswitch mode", "device", devName, "pci", pciAddr, "error", err)
+		eswitch = eswitchSettings{Mode: eswitchModeLegacy} // Default to legacy mode
+	}
+	eswitchMode := eswitch.Mode
 
-		log.V(1).Info("Collected device info", "device", devName, "device", device, "vfs", len(device.VFs))
+	if eswitchMode == eswitchModeSwitchdev {
+		// Skip VF representors
+		if n.isRepresentor(devName) {
+			log.V(1).Info("Skipping VF representor", "device", devName)
+			return nil
+		}
 	}
 
-	return devices, nil
+	// Collect detailed device information
+	device := n.collectDeviceInfo(ctx, devName, pciAddr, link)
+
+	device.EswitchMode = eswitchMode
+	device.EswitchInlineMode = eswitch.InlineMode
+	device.EswitchEncapMode = eswitch.EncapMode
+
+	// Collect VF information if VFs are configured
+	n.collectVFInfo(ctx, devName, device)
+
+	log.V(1).Info("Collected device info", "device", devName, "device", device, "vfs", len(device.VFs))
+	return device
 }
 
 // collectDeviceInfo collects detailed information about a Mellanox device
@@ -735,6 +1555,13 @@ func (n *netconfig) collectDeviceInfo(ctx context.Context, devName, pciAddr stri
 
 		// Get MTU from netlink attributes
 		device.MTU = link.Attrs().MTU
+
+		// Get IP addresses assigned to the PF
+		ipAddrs, err := n.getDeviceIPAddrs(link)
+		if err != nil {
+			log.V(1).Info("Could not get IP addresses", "device", devName, "error", err)
+		}
+		device.IPAddrs = ipAddrs
 	} else {
 		// Fallback: read from sysfs directly (should be rare with netlink)
 		log.V(1).Info("Netlink unavailable, falling back to sysfs", "device", devName)
@@ -751,8 +1578,9 @@ func (n *netconfig) collectDeviceInfo(ctx context.Context, devName, pciAddr stri
 		if err != nil {
 			log.V(1).Info("Could not get IB GUID", "device", devName, "error", err)
 			device.GUID = "-"
-		} else {
-			device.GUID = n.restructureGUID(guid)
+		} else if device.GUID, err = n.restructureGUID(guid); err != nil {
+			log.V(1).Info("Could not parse IB GUID", "device", devName, "guid", guid, "error", err)
+			device.GUID = "-"
 		}
 	} else {
 		device.DevType = devTypeEth
@@ -760,11 +1588,58 @@ func (n *netconfig) collectDeviceInfo(ctx context.Context, devName, pciAddr stri
 	}
 
 	// Get number of VFs from sysfs (matches bash script approach)
-	device.PfNumVfs = n.getPfNumVfsFromSysfs(devName)
+	device.PfNumVfs = n.getPfNumVfsFromSysfs(ctx, devName)
+
+	if link != nil && link.Attrs().MasterIndex != 0 {
+		n.collectLAGInfo(ctx, devName, pciAddr, device)
+	}
 
 	return device
 }
 
+// collectLAGInfo records the bond the PF is enslaved to and its devlink LAG port select mode
+func (n *netconfig) collectLAGInfo(ctx context.Context, devName, pciAddr string, device *MellanoxDevice) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	// /sys/class/net/{dev}/master links to the bond netdev
+	master, err := n.os.Readlink(n.sysClassNetPath + devName + "/master")
+	if err != nil {
+		log.V(1).Info("Could not get LAG master", "device", devName, "error", err)
+		return
+	}
+	// Only a bond forms a LAG, the PF may also be a port of an OVS bridge, a Linux bridge or a VRF
+	master = filepath.Base(master)
+	if _, err := n.os.Stat(n.sysClassNetPath + master + "/bonding"); err != nil {
+		log.V(1).Info("Master is not a bond, skipping LAG", "device", devName, "master", master)
+		return
+	}
+	device.LAGMaster = master
+
+	portSelectMode, err := n.getLAGPortSelectMode(ctx, pciAddr)
+	if err != nil {
+		log.V(1).Info("Could not get LAG port select mode", "device", devName, "pci", pciAddr, "error", err)
+	}
+	device.LAGPortSelectMode = portSelectMode
+}
+
+// getLAGPortSelectMode reads the runtime value of the lag_port_select_mode devlink param
+func (n *netconfig) getLAGPortSelectMode(ctx context.Context, pciAddr string) (string, error) {
+	stdout, _, err := n.cmd.RunCommand(ctx, "devlink", "dev", "param", "show", fmt.Sprintf("pci/%s", pciAddr),
+		"name", lagPortSelectModeParam)
+	if err != nil {
+		return "", fmt.Errorf("failed to run devlink command: %w", err)
+	}
+
+	// Parse the output, e.g. "cmode runtime value queue_affinity"
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 4 && fields[0] == "cmode" && fields[1] == "runtime" && fields[2] == "value" {
+			return fields[3], nil
+		}
+	}
+	return "", fmt.Errorf("no runtime value in devlink output")
+}
+
 // collectVFInfo collects detailed information about VFs for a given PF
 func (n *netconfig) collectVFInfo(ctx context.Context, devName string, device *MellanoxDevice) {
 	log := logr.FromContextOrDiscard(ctx)
@@ -787,6 +1662,12 @@ func (n *netconfig) collectVFInfo(ctx context.Context, devName string, device *M
 		device.VFs = append(device.VFs, *vf)
 		log.V(1).Info("Collected VF info", "device", devName, "vf", vf)
 	}
+
+	if len(device.VFs) < device.PfNumVfs {
+		device.PartialVFs = true
+		log.Info("[WARN] Could not collect the details of all VFs, they will be restored with driver defaults",
+			"device", devName, "vfs", device.PfNumVfs, "collected", len(device.VFs))
+	}
 }
 
 // collectSingleVFInfo collects information for a single VF
@@ -794,7 +1675,7 @@ func (n *netconfig) collectSingleVFInfo(ctx context.Context, devName string, vfI
 	log := logr.FromContextOrDiscard(ctx)
 
 	// VF device path: /sys/class/net/{PF_NAME}/device/virtfn{N}/net/{VF_NAME}
-	vfDevBasePath := fmt.Sprintf("%s%s/device/virtfn%d/net/", sysClassNetPath, devName, vfIndex)
+	vfDevBasePath := fmt.Sprintf("%s%s/device/virtfn%d/net/", n.sysClassNetPath, devName, vfIndex)
 
 	// Get VF name
 	vfName, err := n.getVFName(vfDevBasePath)
@@ -894,7 +1775,7 @@ func (n *netconfig) getVFAttributesFromNetlink(vfName string) (string, string, i
 func (n *netconfig) getIBGUID(devName string) (string, error) {
 	// This matches bash: sysfs_guid=$(cat ${netdev_path}/device/infiniband/*/node_guid)
 	// Look for the first infiniband directory under the device
-	devicePath := fmt.Sprintf("%s%s/device/infiniband", sysClassNetPath, devName)
+	devicePath := fmt.Sprintf("%s%s/device/infiniband", n.sysClassNetPath, devName)
 
 	// List infiniband directories
 	entries, err := n.os.ReadDir(devicePath)
@@ -916,45 +1797,155 @@ func (n *netconfig) getIBGUID(devName string) (string, error) {
 	return strings.TrimSpace(string(guidData)), nil
 }
 
-// restructureGUID restructures the GUID format
-func (n *netconfig) restructureGUID(guid string) string {
-	// This matches the improved implementation
-	// sysfs_guid is like "0c42a1030016054c"
-	// restructure as "0c42:a103:0016:054c"
-	raw := strings.TrimSpace(guid)
+// restructureGUID normalizes a GUID to the colon-separated byte form expected by
+// `ip link set ... port_guid/node_guid` (e.g. "0c:42:a1:03:00:16:05:4c").
+// The input may be a raw sysfs GUID ("0c42a1030016054c") or use colon or dash
+// separators in any grouping, as long as it contains exactly 16 hex digits.
+func (n *netconfig) restructureGUID(guid string) (string, error) {
+	raw := strings.NewReplacer(":", "", "-", "").Replace(strings.TrimSpace(guid))
 	if len(raw) != 16 {
-		return guid // Return original if not expected format
+		return "", fmt.Errorf("invalid GUID %q: expected 16 hex digits", guid)
+	}
+	if _, err := strconv.ParseUint(raw, 16, 64); err != nil {
+		return "", fmt.Errorf("invalid GUID %q: %w", guid, err)
+	}
+	raw = strings.ToLower(raw)
+	parts := make([]string, 0, 8)
+	for i := 0; i < len(raw); i += 2 {
+		parts = append(parts, raw[i:i+2])
 	}
-	return fmt.Sprintf("%s:%s:%s:%s", raw[0:4], raw[4:8], raw[8:12], raw[12:16])
+	return strings.Join(parts, ":"), nil
 }
 
-// getEswitchMode gets the eswitch mode for a PCI device
-func (n *netconfig) getEswitchMode(ctx context.Context, pciAddr string) (string, error) {
+// isDeviceManaged checks the netdev name and PCI address against the configured include/exclude
+// glob patterns. An empty include list matches all devices; exclude always wins.
+func (n *netconfig) isDeviceManaged(devName, pciAddr string) bool {
+	if matchesAnyPattern(n.exclude, devName, pciAddr) {
+		return false
+	}
+	if len(n.include) == 0 {
+		return true
+	}
+	return matchesAnyPattern(n.include, devName, pciAddr)
+}
+
+// matchesAnyPattern returns true if any of the values matches any of the glob patterns
+func matchesAnyPattern(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		for _, value := range values {
+			if matched, err := filepath.Match(pattern, value); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shouldSkipOnDPU returns true when netconfig handling should be skipped because
+// SkipNetconfigOnDPU is set and the node runs a BlueField in DPU mode
+func (n *netconfig) shouldSkipOnDPU(ctx context.Context) bool {
+	if !n.skipOnDPU {
+		return false
+	}
+	dpuMode, err := n.isDPUMode(ctx)
+	if err != nil {
+		// Non-fatal error, continue
+		logr.FromContextOrDiscard(ctx).Error(err, "Failed to detect BlueField DPU mode, assuming regular NIC")
+		return false
+	}
+	return dpuMode
+}
+
+// isDPUMode detects whether a BlueField runs in DPU (embedded CPU) mode.
+// In this mode the embedded CPU function owns the eswitch and exposes devlink ports
+// for the external host PFs, reported as "external true" by devlink port show.
+func (n *netconfig) isDPUMode(ctx context.Context) (bool, error) {
+	stdout, _, err := n.cmd.RunCommand(ctx, "devlink", "port", "show")
+	if err != nil {
+		return false, fmt.Errorf("failed to run devlink command: %w", err)
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if field == "external" && i+1 < len(fields) && fields[i+1] == "true" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// eswitchSettings holds the devlink eswitch attributes of a PCI device
+type eswitchSettings struct {
+	Mode       string
+	InlineMode string // empty if not reported
+	EncapMode  string // empty if not reported
+}
+
+// getEswitchSettings gets the eswitch mode, inline-mode and encap-mode for a PCI device
+func (n *netconfig) getEswitchSettings(ctx context.Context, pciAddr string) (eswitchSettings, error) {
 	// This matches bash: eswitch_mode=$(devlink dev eswitch show pci/$pci_addr 2>/dev/null |
 	// awk '{for (i=1; i<=NF; i++) if ($i == "mode") {print $(i+1); exit}}')
-	stdout, stderr, err := n.cmd.RunCommand(ctx, "devlink", "dev", "eswitch", "show", fmt.Sprintf("pci/%s", pciAddr))
+	stdout, _, err := n.cmd.RunCommand(ctx, "devlink", "dev", "eswitch", "show", fmt.Sprintf("pci/%s", pciAddr))
 	if err != nil {
-		return "", fmt.Errorf("failed to run devlink command: %w, stderr: %s", err, stderr)
+		return eswitchSettings{}, fmt.Errorf("failed to run devlink command: %w", err)
 	}
 
-	// Parse the output to find the mode
+	// Parse the output, e.g. "pci/0000:08:00.0: mode switchdev inline-mode none encap-mode basic"
+	settings := eswitchSettings{}
 	lines := strings.Split(stdout, "\n")
 	for _, line := range lines {
 		fields := strings.Fields(line)
-		for i, field := range fields {
-			if field == "mode" && i+1 < len(fields) {
-				return fields[i+1], nil
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "mode":
+				if settings.Mode == "" {
+					settings.Mode = fields[i+1]
+				}
+			case "inline-mode":
+				settings.InlineMode = fields[i+1]
+			case "encap-mode":
+				settings.EncapMode = fields[i+1]
 			}
 		}
 	}
 
-	return "legacy", nil // Default to legacy if not found
+	if settings.Mode == "" {
+		settings.Mode = eswitchModeLegacy // Default to legacy if not found
+	}
+	return settings, nil
+}
+
+// setEswitchInlineAndEncapMode restores the eswitch inline-mode and encap-mode, skipping unknown values
+func (n *netconfig) setEswitchInlineAndEncapMode(ctx context.Context, pciAddr, inlineMode, encapMode string) error {
+	if inlineMode == "" && encapMode == "" {
+		return nil
+	}
+
+	args := []string{"dev", "eswitch", "set", fmt.Sprintf("pci/%s", pciAddr)}
+	if inlineMode != "" {
+		args = append(args, "inline-mode", inlineMode)
+	}
+	if encapMode != "" {
+		args = append(args, "encap-mode", encapMode)
+	}
+
+	_, _, err := n.cmd.RunCommand(ctx, "devlink", args...)
+	if err != nil {
+		return fmt.Errorf("failed to set eswitch inline/encap mode: %w", err)
+	}
+	return nil
 }
 
 // isMellanoxDeviceByInterface checks if a network interface is a Mellanox device by vendor
 func (n *netconfig) isMellanoxDeviceByInterface(devName string) bool {
 	// Read vendor ID from sysfs
-	vendorPath := fmt.Sprintf("%s%s/device/vendor", sysClassNetPath, devName)
+	vendorPath := fmt.Sprintf("%s%s/device/vendor", n.sysClassNetPath, devName)
 	vendorData, err := n.os.ReadFile(vendorPath)
 	if err != nil {
 		return false
@@ -967,7 +1958,7 @@ func (n *netconfig) isMellanoxDeviceByInterface(devName string) bool {
 // isRepresentor checks if a device is a VF representor
 func (n *netconfig) isRepresentor(devName string) bool {
 	// Read phys_port_name to check if it's a representor
-	physPortNamePath := fmt.Sprintf("%s%s/phys_port_name", sysClassNetPath, devName)
+	physPortNamePath := fmt.Sprintf("%s%s/phys_port_name", n.sysClassNetPath, devName)
 	physPortNameData, err := n.os.ReadFile(physPortNamePath)
 	if err != nil {
 		return false
@@ -981,10 +1972,10 @@ func (n *netconfig) isRepresentor(devName string) bool {
 // getNetNamePath gets the udev-based network name path
 func (n *netconfig) getNetNamePath(ctx context.Context, devName string) (string, error) {
 	// This matches: udevadm info --query=property /sys/class/net/{iface}
-	stdout, stderr, err := n.cmd.RunCommand(ctx, "udevadm", "info", "--query=property",
-		fmt.Sprintf("%s%s", sysClassNetPath, devName))
+	stdout, _, err := n.cmd.RunCommand(ctx, "udevadm", "info", "--query=property",
+		fmt.Sprintf("%s%s", n.sysClassNetPath, devName))
 	if err != nil {
-		return "", fmt.Errorf("failed to run udevadm command: %w, stderr: %s", err, stderr)
+		return "", fmt.Errorf("failed to run udevadm command: %w", err)
 	}
 
 	// Parse the output to find ID_NET_NAME_PATH
@@ -1001,7 +1992,7 @@ func (n *netconfig) getNetNamePath(ctx context.Context, devName string) (string,
 // getAdminStateFromSysfs gets the admin state from sysfs flags
 func (n *netconfig) getAdminStateFromSysfs(devName string) string {
 	// Read flags from sysfs: /sys/class/net/{dev}/flags
-	flagsPath := fmt.Sprintf("%s%s/flags", sysClassNetPath, devName)
+	flagsPath := fmt.Sprintf("%s%s/flags", n.sysClassNetPath, devName)
 	flagsData, err := n.os.ReadFile(flagsPath)
 	if err != nil {
 		return adminStateDown // Default to down if we can't read
@@ -1042,7 +2033,7 @@ func (n *netconfig) getAdminStateFromSysfs(devName string) string {
 // getMTUFromSysfs gets the MTU from sysfs
 func (n *netconfig) getMTUFromSysfs(devName string) int {
 	// Read MTU from sysfs: /sys/class/net/{dev}/mtu
-	mtuPath := fmt.Sprintf("%s%s/mtu", sysClassNetPath, devName)
+	mtuPath := fmt.Sprintf("%s%s/mtu", n.sysClassNetPath, devName)
 	mtuData, err := n.os.ReadFile(mtuPath)
 	if err != nil {
 		return 1500 // Default MTU if we can't read
@@ -1063,10 +2054,14 @@ func (n *netconfig) getMTUFromSysfs(devName string) int {
 	return mtu
 }
 
-// getPfNumVfsFromSysfs gets the number of VFs from sysfs
-func (n *netconfig) getPfNumVfsFromSysfs(devName string) int {
+// getPfNumVfsFromSysfs gets the number of VFs from sysfs. sriov_numvfs is the
+// primary source; when it reports 0 while virtfn* links exist below the device
+// directory, the number of virtfn* links is used instead.
+func (n *netconfig) getPfNumVfsFromSysfs(ctx context.Context, devName string) int {
+	log := logr.FromContextOrDiscard(ctx)
+
 	// Read sriov_numvfs from sysfs: /sys/class/net/{dev}/device/sriov_numvfs
-	sriovNumVfsPath := fmt.Sprintf("%s%s/device/sriov_numvfs", sysClassNetPath, devName)
+	sriovNumVfsPath := fmt.Sprintf("%s%s/device/sriov_numvfs", n.sysClassNetPath, devName)
 	sriovNumVfsData, err := n.os.ReadFile(sriovNumVfsPath)
 	if err != nil {
 		return 0 // Default to 0 if we can't read (device not SRIOV capable)
@@ -1084,9 +2079,34 @@ func (n *netconfig) getPfNumVfsFromSysfs(devName string) int {
 		return 0 // Default to 0 if parsing fails
 	}
 
+	if sriovNumVfs == 0 {
+		if virtfnCount := n.countVirtfnEntries(devName); virtfnCount > 0 {
+			log.Info("[WARN] sriov_numvfs reports 0 VFs but virtfn entries exist, using virtfn count",
+				"device", devName, "virtfnCount", virtfnCount)
+			return virtfnCount
+		}
+	}
+
 	return sriovNumVfs
 }
 
+// countVirtfnEntries counts the virtfn* entries below /sys/class/net/{dev}/device/
+func (n *netconfig) countVirtfnEntries(devName string) int {
+	deviceDir := fmt.Sprintf("%s%s/device/", n.sysClassNetPath, devName)
+	entries, err := n.os.ReadDir(deviceDir)
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "virtfn") {
+			count++
+		}
+	}
+	return count
+}
+
 // getVFName gets the VF netdev name from the VF device base path
 func (n *netconfig) getVFName(vfDevBasePath string) (string, error) {
 	// List the net directory to get VF name (matches bash: vf_name=$(ls "$vf_dev_base_path"))
@@ -1196,9 +2216,9 @@ func (n *netconfig) getVFMTU(vfNetdevPath string) (int, error) {
 // getVFAdminMACAndGUID gets VF admin MAC and GUID using ip command (matches bash script approach)
 func (n *netconfig) getVFAdminMACAndGUID(ctx context.Context, devName string, vfIndex int, devType string) (string, string, error) {
 	// Use ip command to get VF info (matches bash: vf_ip_link_json=$(ip -j link show $mlnx_dev_name | jq -r .[0].vfinfo_list[$vf_index]))
-	stdout, stderr, err := n.cmd.RunCommand(ctx, "ip", "-j", "link", "show", devName)
+	stdout, _, err := n.runIP(ctx, "-j", "link", "show", devName)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to run ip command: %w, stderr: %s", err, stderr)
+		return "", "", fmt.Errorf("failed to run ip command: %w", err)
 	}
 
 	// Parse JSON output to get VF info
@@ -1293,7 +2313,7 @@ func (n *netconfig) discoverSwitchdevRepresentors(ctx context.Context) error {
 
 // getPhysPortName gets the physical port name for a device
 func (n *netconfig) getPhysPortName(devName string) (string, error) {
-	physPortPath := fmt.Sprintf("%s%s/phys_port_name", sysClassNetPath, devName)
+	physPortPath := fmt.Sprintf("%s%s/phys_port_name", n.sysClassNetPath, devName)
 	physPortName, err := n.os.ReadFile(physPortPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read phys_port_name: %w", err)
@@ -1303,7 +2323,7 @@ func (n *netconfig) getPhysPortName(devName string) (string, error) {
 
 // getPhysSwitchID gets the physical switch ID for a device
 func (n *netconfig) getPhysSwitchID(devName string) (string, error) {
-	physSwitchPath := fmt.Sprintf("%s%s/phys_switch_id", sysClassNetPath, devName)
+	physSwitchPath := fmt.Sprintf("%s%s/phys_switch_id", n.sysClassNetPath, devName)
 	physSwitchID, err := n.os.ReadFile(physSwitchPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read phys_switch_id: %w", err)
@@ -1311,6 +2331,20 @@ func (n *netconfig) getPhysSwitchID(devName string) (string, error) {
 	return strings.TrimSpace(string(physSwitchID)), nil
 }
 
+// getPFPhysPortNumber gets the physical port number of a PF from its phys_port_name
+func (n *netconfig) getPFPhysPortNumber(pfName string) (string, error) {
+	pfPhysPortName, err := n.getPhysPortName(pfName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get PF physical port name: %w", err)
+	}
+
+	pfPhysPortNum, err := n.parsePhysPortNumber(pfPhysPortName)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PF physical port number: %w", err)
+	}
+	return pfPhysPortNum, nil
+}
+
 // parsePhysPortNumber parses the physical port number from phys_port_name
 // Format: "p1", "p2", etc. -> returns "1", "2", etc.
 func (n *netconfig) parsePhysPortNumber(physPortName string) (string, error) {
@@ -1326,7 +2360,7 @@ func (n *netconfig) findDeviceRepresentors(ctx context.Context, devName, physSwi
 	representors := make([]Representor, 0, 10) // Pre-allocate with capacity
 
 	// Look for representors in the device's subsystem
-	subsystemPath := fmt.Sprintf("%s%s/subsystem", sysClassNetPath, devName)
+	subsystemPath := fmt.Sprintf("%s%s/subsystem", n.sysClassNetPath, devName)
 	entries, err := n.os.ReadDir(subsystemPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read subsystem directory: %w", err)
@@ -1334,7 +2368,7 @@ func (n *netconfig) findDeviceRepresentors(ctx context.Context, devName, physSwi
 
 	for _, entry := range entries {
 		representorName := entry.Name()
-		representorPath := fmt.Sprintf("%s%s/subsystem/%s", sysClassNetPath, devName, representorName)
+		representorPath := fmt.Sprintf("%s%s/subsystem/%s", n.sysClassNetPath, devName, representorName)
 
 		// Check if this is a representor by examining phys_port_name
 		physPortNamePath := fmt.Sprintf("%s/phys_port_name", representorPath)
@@ -1443,10 +2477,11 @@ type representorRenameOp struct {
 	MTU        int
 }
 
-// restoreRepresentors restores representor configurations
-func (n *netconfig) restoreRepresentors(ctx context.Context, pfName string, device *MellanoxDevice) error {
+// restoreRepresentors restores representor configurations. newScheme is the result of DevicesUseNewNamingScheme,
+// with the old naming scheme the PF may have no port number and the representors are matched by VF index only.
+func (n *netconfig) restoreRepresentors(ctx context.Context, pfName string, device *MellanoxDevice, newScheme bool) error {
 	log := logr.FromContextOrDiscard(ctx)
-	log.Info("Restoring representors", "device", pfName, "count", len(device.Representors))
+	log.Info("Restoring representors", "device", pfName, "count", len(device.Representors), "new_naming_scheme", newScheme)
 
 	// Get PF physical switch ID for matching
 	pfPhysSwitchID, err := n.getPhysSwitchID(pfName)
@@ -1455,27 +2490,27 @@ func (n *netconfig) restoreRepresentors(ctx context.Context, pfName string, devi
 	}
 
 	// Get PF physical port number for matching
-	pfPhysPortName, err := n.getPhysPortName(pfName)
-	if err != nil {
-		return fmt.Errorf("failed to get PF physical port name: %w", err)
-	}
-
-	pfPhysPortNum, err := n.parsePhysPortNumber(pfPhysPortName)
+	pfPhysPortNum, err := n.getPFPhysPortNumber(pfName)
 	if err != nil {
-		return fmt.Errorf("failed to parse PF physical port number: %w", err)
+		if newScheme {
+			return err
+		}
+		log.V(1).Info("PF has no physical port number with the old naming scheme", "device", pfName, "error", err)
+		pfPhysPortNum = ""
 	}
 
 	// Two-phase rename array - used to avoid name collisions when interfaces are swapped after driver reload
 	renameOps := make([]representorRenameOp, 0, len(device.Representors))
 
 	// Phase 1: Rename current representor names to temporary names (frees up the target namespace)
+	// Representors are processed in PhysPortNum/VFID order so that restore is deterministic
 	log.Info("Phase 1: Renaming representors to temporary names")
-	for _, representor := range device.Representors {
+	for _, representor := range sortRepresentors(device.Representors) {
 		log.V(1).Info("Processing representor for Phase 1",
 			"name", representor.Name, "vf_id", representor.VFID)
 
 		// Find the current representor device
-		currentRepresentorName, err := n.findCurrentRepresentor(ctx, pfPhysSwitchID, pfPhysPortNum, representor.VFID)
+		currentRepresentorName, err := n.findCurrentRepresentor(ctx, pfPhysSwitchID, pfPhysPortNum, representor.VFID, newScheme)
 		if err != nil {
 			log.Error(err, "Failed to find current representor", "original_name", representor.Name, "vf_id", representor.VFID)
 			continue
@@ -1544,12 +2579,36 @@ func (n *netconfig) restoreRepresentors(ctx context.Context, pfName string, devi
 	return nil
 }
 
+// sortRepresentors returns a copy of representors sorted by PhysPortNum and then VFID,
+// compared numerically when both values are numbers
+func sortRepresentors(representors []Representor) []Representor {
+	sorted := make([]Representor, len(representors))
+	copy(sorted, representors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].PhysPortNum != sorted[j].PhysPortNum {
+			return lessNumeric(sorted[i].PhysPortNum, sorted[j].PhysPortNum)
+		}
+		return lessNumeric(sorted[i].VFID, sorted[j].VFID)
+	})
+	return sorted
+}
+
+// lessNumeric compares a and b as integers if both parse, as strings otherwise
+func lessNumeric(a, b string) bool {
+	aNum, aErr := strconv.Atoi(a)
+	bNum, bErr := strconv.Atoi(b)
+	if aErr == nil && bErr == nil {
+		return aNum < bNum
+	}
+	return a < b
+}
+
 // findCurrentRepresentor finds the current representor device based on physical attributes
-func (n *netconfig) findCurrentRepresentor(ctx context.Context, physSwitchID, physPortNum, vfID string) (string, error) {
+func (n *netconfig) findCurrentRepresentor(ctx context.Context, physSwitchID, physPortNum, vfID string, newScheme bool) (string, error) {
 	log := logr.FromContextOrDiscard(ctx)
 
 	// Scan all network devices to find the representor
-	entries, err := n.os.ReadDir(sysClassNetPath)
+	entries, err := n.os.ReadDir(n.sysClassNetPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read network devices: %w", err)
 	}
@@ -1578,19 +2637,8 @@ func (n *netconfig) findCurrentRepresentor(ctx context.Context, physSwitchID, ph
 			continue // Skip if we can't read phys_port_name
 		}
 
-		// Check if this is a representor for our VF
-		if !n.isRepresentorPhysPortName(devPhysPortName) {
-			continue
-		}
-
-		// Parse representor information
-		pfPortNum, devVFID, err := n.parseRepresentorPhysPortName(devPhysPortName)
-		if err != nil {
-			continue
-		}
-
 		// Check if this representor belongs to our PF and VF
-		if pfPortNum == physPortNum && devVFID == vfID {
+		if n.representorMatches(devPhysPortName, physPortNum, vfID, newScheme) {
 			log.V(1).Info("Found current representor", "name", devName, "vf_id", vfID)
 			return devName, nil
 		}
@@ -1599,12 +2647,33 @@ func (n *netconfig) findCurrentRepresentor(ctx context.Context, physSwitchID, ph
 	return "", fmt.Errorf("representor not found for VF ID %s", vfID)
 }
 
+// representorMatches checks if a representor phys_port_name belongs to the VF vfID of the PF port physPortNum.
+// With the new naming scheme it is "pf{port_num}vf{vf_id}". With the old one the kernel may only expose the
+// VF index and the PF no port number, the phys_switch_id already tells the PFs apart then.
+func (n *netconfig) representorMatches(physPortName, physPortNum, vfID string, newScheme bool) bool {
+	if pfPortNum, devVFID, err := n.parseRepresentorPhysPortName(physPortName); err == nil {
+		return devVFID == vfID && (pfPortNum == physPortNum || (!newScheme && physPortNum == ""))
+	}
+	return !newScheme && physPortName == vfID
+}
+
 // renameRepresentor renames a representor device
 func (n *netconfig) renameRepresentor(ctx context.Context, currentName, newName string) error {
-	// Use ip link set dev {current_name} name {new_name}
-	_, stderr, err := n.cmd.RunCommand(ctx, "ip", "link", "set", "dev", currentName, "name", newName)
+	if n.preferIPCommand {
+		// Use ip link set dev {current_name} name {new_name}
+		_, _, err := n.runIP(ctx, "link", "set", "dev", currentName, "name", newName)
+		if err != nil {
+			return fmt.Errorf("failed to rename representor from %s to %s: %w", currentName, newName, err)
+		}
+		return nil
+	}
+
+	link, err := n.netlinkLib.LinkByName(currentName)
 	if err != nil {
-		return fmt.Errorf("failed to rename representor from %s to %s: %w, stderr: %s", currentName, newName, err, stderr)
+		return fmt.Errorf("failed to get representor link %s: %w", currentName, err)
+	}
+	if err := n.netlinkLib.LinkSetName(link, newName); err != nil {
+		return fmt.Errorf("failed to rename representor from %s to %s: %w", currentName, newName, err)
 	}
 	return nil
 }
@@ -1653,7 +2722,7 @@ func (n *netconfig) DevicesUseNewNamingScheme(ctx context.Context) (bool, error)
 	npPattern := regexp.MustCompile(`np[0-3]$`)
 
 	// Get all network interfaces from sysfs (reuse existing logic)
-	entries, err := n.os.ReadDir(sysClassNetPath)
+	entries, err := n.os.ReadDir(n.sysClassNetPath)
 	if err != nil {
 		log.Error(err, "failed to list network devices")
 		return false, err
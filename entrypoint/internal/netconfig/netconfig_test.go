@@ -18,21 +18,36 @@ package netconfig
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
-
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 	"github.com/vishvananda/netlink"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
+	netlinkPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/netconfig/netlink"
 	netlinkMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/netconfig/netlink/mocks"
 	sriovnetMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/netconfig/sriovnet/mocks"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd"
 	cmdMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd/mocks"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/host"
 	hostMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/host/mocks"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers"
 	osMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers/mocks"
 )
 
@@ -58,7 +73,7 @@ var _ = Describe("Netconfig", func() {
 			sriovnetMock := sriovnetMockPkg.NewLib(GinkgoT())
 
 			netlinkMock := netlinkMockPkg.NewLib(GinkgoT())
-			netconfig := New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, 4)
+			netconfig := New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{BindDelaySec: 4})
 			Expect(netconfig).NotTo(BeNil())
 		})
 	})
@@ -80,7 +95,7 @@ var _ = Describe("Netconfig", func() {
 			hostMock = hostMockPkg.NewInterface(GinkgoT())
 			sriovnetMock = sriovnetMockPkg.NewLib(GinkgoT())
 			netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
-			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, 4).(*netconfig)
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{BindDelaySec: 4}).(*netconfig)
 			ctx = context.Background()
 		})
 
@@ -109,6 +124,24 @@ var _ = Describe("Netconfig", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("should fail when mlx5_core is loaded but no devices found with RequireMellanoxDevices", func() {
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{RequireMellanoxDevices: true}).(*netconfig)
+			hostMock.On("LsMod", mock.Anything).Return(map[string]host.LoadedModule{
+				"mlx5_core": {Name: "mlx5_core", RefCount: 1, UsedBy: []string{}},
+			}, nil).Once()
+			osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{}, nil).Once()
+
+			err := nc.Save(ctx)
+			Expect(err).To(MatchError(ContainSubstring("no Mellanox devices found")))
+		})
+
+		It("should succeed without devices when RequireMellanoxDevices is set but mlx5_core is not loaded", func() {
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{RequireMellanoxDevices: true}).(*netconfig)
+			hostMock.On("LsMod", mock.Anything).Return(map[string]host.LoadedModule{}, nil).Once()
+
+			Expect(nc.Save(ctx)).To(Succeed())
+		})
+
 		It("should succeed when mlx5_core is loaded and devices are found", func() {
 			// Mock LsMod to return mlx5_core as loaded
 			hostMock.On("LsMod", mock.Anything).Return(map[string]host.LoadedModule{
@@ -136,11 +169,13 @@ var _ = Describe("Netconfig", func() {
 
 			// Mock netlink call for device info collection
 			netlinkMock.On("LinkByName", "eth0").Return(mockLink, nil).Once()
+			netlinkMock.On("AddrList", mockLink, netlinkPkg.FamilyAll).Return([]netlink.Addr{}, nil).Once()
 
 			// Mock device attributes (fallback when netlink fails)
 			osMock.On("ReadFile", "/sys/class/net/eth0/flags").Return([]byte("0x1003"), nil).Maybe()
 			osMock.On("ReadFile", "/sys/class/net/eth0/mtu").Return([]byte("1500"), nil).Maybe()
 			osMock.On("ReadFile", "/sys/class/net/eth0/device/sriov_numvfs").Return([]byte("0"), nil).Once()
+			osMock.On("ReadDir", "/sys/class/net/eth0/device/").Return([]os.DirEntry{}, nil).Once()
 
 			// Mock devlink command
 			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", mock.Anything).Return("mode legacy", "", nil).Once()
@@ -179,6 +214,111 @@ var _ = Describe("Netconfig", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("failed to discover Mellanox devices"))
 		})
+
+		Context("when SkipNetconfigOnDPU is set", func() {
+			BeforeEach(func() {
+				nc.skipOnDPU = true
+				hostMock.On("LsMod", mock.Anything).Return(map[string]host.LoadedModule{
+					"mlx5_core": {Name: "mlx5_core", RefCount: 1, UsedBy: []string{}},
+				}, nil).Once()
+			})
+
+			It("should skip device discovery when DPU mode is detected", func() {
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "port", "show").Return(
+					"pci/0000:03:00.0/65535: type eth netdev p0 flavour physical port 0 splittable false\n"+
+						"pci/0000:03:00.0/0: type eth netdev pf0hpf flavour pcipf controller 1 pfnum 0 external true splittable false\n",
+					"", nil).Once()
+
+				err := nc.Save(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nc.mellanoxDevices).To(BeEmpty())
+			})
+
+			It("should proceed with device discovery on a regular NIC", func() {
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "port", "show").Return(
+					"pci/0000:08:00.0/65535: type eth netdev eth0 flavour physical port 0 splittable false\n", "", nil).Once()
+				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{}, nil).Once()
+
+				err := nc.Save(ctx)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should proceed with device discovery when DPU detection fails", func() {
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "port", "show").Return("", "devlink not found", fmt.Errorf("exit 1")).Once()
+				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{}, nil).Once()
+
+				err := nc.Save(ctx)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("parallel discovery", func() {
+			const concurrency = 2
+
+			BeforeEach(func() {
+				nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock,
+					config.Config{BindDelaySec: 4, NetconfigDiscoveryConcurrency: concurrency}).(*netconfig)
+			})
+
+			It("should populate all devices without exceeding the concurrency limit", func() {
+				names := []string{"eth5", "eth3", "eth0", "eth4", "eth1", "eth2"}
+				entries := make([]os.DirEntry, 0, len(names)+1)
+				for _, name := range names {
+					entries = append(entries, &mockDirEntry{name: name})
+				}
+				// A device whose PCI lookup fails must not abort discovery of the others
+				entries = append(entries, &mockDirEntry{name: "broken0"})
+				osMock.On("ReadDir", "/sys/class/net/").Return(entries, nil).Once()
+
+				var inFlight, maxInFlight atomic.Int32
+				trackInFlight := func(pciAddr string, err error) func(string) (string, error) {
+					return func(string) (string, error) {
+						current := inFlight.Add(1)
+						defer inFlight.Add(-1)
+						for {
+							seen := maxInFlight.Load()
+							if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+								break
+							}
+						}
+						time.Sleep(20 * time.Millisecond)
+						return pciAddr, err
+					}
+				}
+
+				osMock.On("ReadFile", "/sys/class/net/broken0/device/vendor").Return([]byte("0x15b3"), nil).Once()
+				sriovnetMock.EXPECT().GetPciFromNetDevice("broken0").
+					RunAndReturn(trackInFlight("", fmt.Errorf("PCI address not found"))).Once()
+
+				for i, name := range names {
+					pciAddr := fmt.Sprintf("0000:08:00.%d", i)
+					link := &mockLink{attrs: &netlink.LinkAttrs{Name: name, Flags: net.FlagUp, MTU: 1500}}
+
+					osMock.On("ReadFile", "/sys/class/net/"+name+"/device/vendor").Return([]byte("0x15b3"), nil).Once()
+					sriovnetMock.EXPECT().GetPciFromNetDevice(name).RunAndReturn(trackInFlight(pciAddr, nil)).Once()
+					netlinkMock.On("LinkByName", name).Return(link, nil).Once()
+					netlinkMock.On("AddrList", link, netlinkPkg.FamilyAll).Return([]netlink.Addr{}, nil).Once()
+					osMock.On("ReadFile", "/sys/class/net/"+name+"/flags").Return([]byte("0x1003"), nil).Maybe()
+					osMock.On("ReadFile", "/sys/class/net/"+name+"/mtu").Return([]byte("1500"), nil).Maybe()
+					osMock.On("ReadFile", "/sys/class/net/"+name+"/device/sriov_numvfs").Return([]byte("0"), nil).Once()
+					osMock.On("ReadDir", "/sys/class/net/"+name+"/device/").Return([]os.DirEntry{}, nil).Once()
+					cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/"+pciAddr).
+						Return("mode legacy", "", nil).Once()
+				}
+
+				devices, err := nc.discoverMellanoxDevices(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(devices).To(Equal([]string{"eth0", "eth1", "eth2", "eth3", "eth4", "eth5"}))
+				Expect(nc.mellanoxDevices).To(HaveLen(len(names)))
+				for i, name := range names {
+					Expect(nc.mellanoxDevices).To(HaveKey(name))
+					Expect(nc.mellanoxDevices[name].PCIAddr).To(Equal(fmt.Sprintf("0000:08:00.%d", i)))
+				}
+				Expect(nc.mellanoxDevices).NotTo(HaveKey("broken0"))
+				Expect(maxInFlight.Load()).To(BeNumerically("<=", concurrency))
+				Expect(maxInFlight.Load()).To(BeNumerically(">", 1), "devices should be discovered in parallel")
+			})
+		})
 	})
 
 	Context("Restore", func() {
@@ -198,7 +338,7 @@ var _ = Describe("Netconfig", func() {
 			hostMock = hostMockPkg.NewInterface(GinkgoT())
 			sriovnetMock = sriovnetMockPkg.NewLib(GinkgoT())
 			netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
-			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, 4).(*netconfig)
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{BindDelaySec: 4}).(*netconfig)
 			ctx = context.Background()
 		})
 
@@ -207,7 +347,318 @@ var _ = Describe("Netconfig", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("should succeed when device has no VFs", func() {
+		It("should restore eswitch inline-mode and encap-mode right after switchdev mode", func() {
+			nc.bindDelaySec = 0
+			device := &MellanoxDevice{
+				PCIAddr:           "0000:08:00.0",
+				DevType:           devTypeEth,
+				AdminState:        adminStateUp,
+				MTU:               1500,
+				GUID:              "-",
+				EswitchMode:       eswitchModeSwitchdev,
+				EswitchInlineMode: "none",
+				EswitchEncapMode:  "basic",
+				PfNumVfs:          2,
+				VFs:               []VF{},
+			}
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil)
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+			osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("2"), os.FileMode(0o644)).Return(nil).Once()
+			osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("2"), nil).Once()
+			mock.InOrder(
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/0000:08:00.0").
+					Return("pci/0000:08:00.0: mode legacy inline-mode none", "", nil).Once(),
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "set", "pci/0000:08:00.0", "mode", "legacy").Return("", "", nil).Once(),
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "set", "pci/0000:08:00.0", "mode", "switchdev").Return("", "", nil).Once(),
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "set", "pci/0000:08:00.0",
+					"inline-mode", "none", "encap-mode", "basic").Return("", "", nil).Once(),
+			)
+
+			_, err := nc.restoreDeviceConfig(ctx, "eth0", device, true)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should skip the eswitch mode toggling when the device is already in switchdev mode with its VFs", func() {
+			nc.bindDelaySec = 0
+			device := &MellanoxDevice{
+				PCIAddr:           "0000:08:00.0",
+				DevType:           devTypeEth,
+				AdminState:        adminStateUp,
+				MTU:               1500,
+				GUID:              "-",
+				EswitchMode:       eswitchModeSwitchdev,
+				EswitchInlineMode: "none",
+				EswitchEncapMode:  "basic",
+				PfNumVfs:          2,
+				VFs:               []VF{},
+			}
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil)
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/0000:08:00.0").
+				Return("pci/0000:08:00.0: mode switchdev inline-mode none encap-mode basic", "", nil).Once()
+			osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("2\n"), nil).Once()
+
+			// No eswitch mode change and no sriov_numvfs write are expected
+			_, err := nc.restoreDeviceConfig(ctx, "eth0", device, true)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should toggle the eswitch mode when the switchdev device lost its VFs", func() {
+			nc.bindDelaySec = 0
+			device := &MellanoxDevice{
+				PCIAddr:     "0000:08:00.0",
+				DevType:     devTypeEth,
+				AdminState:  adminStateUp,
+				MTU:         1500,
+				GUID:        "-",
+				EswitchMode: eswitchModeSwitchdev,
+				PfNumVfs:    2,
+				VFs:         []VF{},
+			}
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil)
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+			mock.InOrder(
+				osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("0"), nil).Once(),
+				osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("2"), os.FileMode(0o644)).Return(nil).Once(),
+				osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("2"), nil).Once(),
+			)
+			mock.InOrder(
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/0000:08:00.0").
+					Return("pci/0000:08:00.0: mode switchdev", "", nil).Once(),
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "set", "pci/0000:08:00.0", "mode", "legacy").Return("", "", nil).Once(),
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "set", "pci/0000:08:00.0", "mode", "switchdev").Return("", "", nil).Once(),
+			)
+
+			_, err := nc.restoreDeviceConfig(ctx, "eth0", device, true)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should not read or change the eswitch mode of a legacy device", func() {
+			nc.bindDelaySec = 0
+			device := &MellanoxDevice{
+				PCIAddr:     "0000:08:00.0",
+				DevType:     devTypeEth,
+				AdminState:  adminStateUp,
+				MTU:         1500,
+				GUID:        "-",
+				EswitchMode: eswitchModeLegacy,
+				PfNumVfs:    1,
+				VFs:         []VF{},
+			}
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil)
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+			osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("1"), os.FileMode(0o644)).Return(nil).Once()
+			osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("1"), nil).Once()
+
+			// No devlink command is expected
+			_, err := nc.restoreDeviceConfig(ctx, "eth0", device, true)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should create all VFs when the VF details were only partially saved", func() {
+			nc.bindDelaySec = 0
+			device := &MellanoxDevice{
+				PCIAddr:     "0000:08:00.0",
+				DevType:     devTypeEth,
+				AdminState:  adminStateUp,
+				MTU:         1500,
+				GUID:        "-",
+				EswitchMode: eswitchModeLegacy,
+				PfNumVfs:    3,
+				PartialVFs:  true,
+			}
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil)
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+			osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("3"), os.FileMode(0o644)).Return(nil).Once()
+			osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("3"), nil).Once()
+
+			_, err := nc.restoreDeviceConfig(ctx, "eth0", device, true)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when one of two devices fails", func() {
+			BeforeEach(func() {
+				nc.bindDelaySec = 0
+				nc.mellanoxDevices["eth0"] = &MellanoxDevice{
+					PCIAddr:     "0000:08:00.0",
+					DevType:     devTypeEth,
+					AdminState:  adminStateUp,
+					MTU:         1500,
+					GUID:        "-",
+					EswitchMode: eswitchModeLegacy,
+					PfNumVfs:    2,
+					PartialVFs:  true,
+				}
+				nc.mellanoxDevices["eth1"] = &MellanoxDevice{
+					PCIAddr:     "0000:08:00.1",
+					DevType:     devTypeEth,
+					AdminState:  adminStateUp,
+					MTU:         1500,
+					GUID:        "-",
+					EswitchMode: eswitchModeLegacy,
+					PfNumVfs:    2,
+					PartialVFs:  true,
+				}
+				link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+				osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil)
+				netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+				netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+				osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("2"), os.FileMode(0o644)).Return(nil).Once()
+				osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("2"), nil).Once()
+				// The netdev of the second PF never came back
+				osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.1/net").Return(nil, os.ErrNotExist).Once()
+			})
+
+			It("should fail with the failure summary with StrictNetconfigRestore", func() {
+				nc.strictRestore = true
+
+				err := nc.Restore(ctx)
+				Expect(err).To(MatchError("SRIOV configuration partially restored: 1 of 2 devices and 0 of 0 VFs failed " +
+					"(failed devices: eth1)"))
+			})
+
+			It("should only log the failure summary by default", func() {
+				var logs []string
+				ctx = logr.NewContext(ctx, funcr.New(func(_, args string) {
+					logs = append(logs, args)
+				}, funcr.Options{}))
+
+				Expect(nc.Restore(ctx)).To(Succeed())
+				Expect(logs).To(ContainElement(SatisfyAll(
+					ContainSubstring(`"msg"="SRIOV configuration restore summary"`),
+					ContainSubstring(`"devices"=2 "failed_devices"=1`),
+				)))
+				Expect(logs).To(ContainElement(ContainSubstring("[WARN] SRIOV configuration partially restored")))
+				Expect(logs).NotTo(ContainElement(ContainSubstring("SRIOV configuration restored successfully")))
+			})
+		})
+
+		It("should skip restore when SkipNetconfigOnDPU is set and DPU mode is detected", func() {
+			nc.skipOnDPU = true
+			nc.mellanoxDevices["eth0"] = &MellanoxDevice{PCIAddr: "0000:03:00.0", DevType: devTypeEth, PfNumVfs: 4}
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "port", "show").Return(
+				"pci/0000:03:00.0/0: type eth netdev pf0hpf flavour pcipf controller 1 pfnum 0 external true splittable false\n",
+				"", nil).Once()
+
+			// No sysfs or netlink access is expected
+			err := nc.Restore(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should fail with a bounded deadline error naming the device whose VFs never appear", func() {
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{
+				NetconfigRestoreTimeout:     300 * time.Millisecond,
+				NetconfigPollInterval:       10 * time.Millisecond,
+				SriovNumVfsSettleTimeoutSec: 60,
+			}).(*netconfig)
+			nc.mellanoxDevices["eth0"] = &MellanoxDevice{
+				PCIAddr:     "0000:08:00.0",
+				DevType:     devTypeEth,
+				AdminState:  adminStateUp,
+				MTU:         1500,
+				GUID:        "-",
+				EswitchMode: eswitchModeLegacy,
+				PfNumVfs:    4,
+			}
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("4"), os.FileMode(0o644)).Return(nil).Once()
+			osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("0"), nil)
+
+			start := time.Now()
+			err := nc.Restore(ctx)
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(err).To(MatchError(ContainSubstring("restore of device eth0 timed out")))
+		})
+
+		It("should return promptly when the deadline is hit during the bind delay", func() {
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{
+				NetconfigRestoreTimeout: 300 * time.Millisecond,
+				NetconfigPollInterval:   10 * time.Millisecond,
+				BindDelaySec:            60,
+			}).(*netconfig)
+			nc.mellanoxDevices["eth0"] = &MellanoxDevice{
+				PCIAddr:     "0000:08:00.0",
+				DevType:     devTypeEth,
+				AdminState:  adminStateUp,
+				MTU:         1500,
+				GUID:        "-",
+				EswitchMode: eswitchModeLegacy,
+				PfNumVfs:    1,
+			}
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("1"), os.FileMode(0o644)).Return(nil).Once()
+			osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("1"), nil).Once()
+
+			start := time.Now()
+			err := nc.Restore(ctx)
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(err).To(MatchError(ContainSubstring("restore of device eth0 timed out")))
+		})
+
+		It("should return promptly when the deadline is hit between sriov_numvfs write retries", func() {
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{
+				NetconfigRestoreTimeout: 300 * time.Millisecond,
+				NetconfigPollInterval:   10 * time.Millisecond,
+				SriovNumVfsWriteRetries: 3,
+				SriovNumVfsRetryDelayMs: 60000,
+			}).(*netconfig)
+			nc.mellanoxDevices["eth0"] = &MellanoxDevice{
+				PCIAddr:     "0000:08:00.0",
+				DevType:     devTypeEth,
+				AdminState:  adminStateUp,
+				MTU:         1500,
+				GUID:        "-",
+				EswitchMode: eswitchModeLegacy,
+				PfNumVfs:    1,
+			}
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("1"), os.FileMode(0o644)).
+				Return(syscall.EBUSY).Once()
+
+			start := time.Now()
+			err := nc.Restore(ctx)
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(err).To(MatchError(ContainSubstring("restore of device eth0 timed out")))
+		})
+
+		It("should restore the admin state and MTU of a device without VFs", func() {
 			device := &MellanoxDevice{
 				PCIAddr:     "0000:08:00.0",
 				DevType:     devTypeEth,
@@ -219,10 +670,62 @@ var _ = Describe("Netconfig", func() {
 				VFs:         []VF{},
 			}
 			nc.mellanoxDevices["eth0"] = device
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
 
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(link, nil).Twice()
+			netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+
+			// No VF, eswitch or representor steps are expected
 			err := nc.Restore(ctx)
 			Expect(err).NotTo(HaveOccurred())
 		})
+
+		It("should round-trip the static addresses of a PF without VFs across the reload", func() {
+			staticAddr := func(cidr string) netlink.Addr {
+				addr, err := netlink.ParseAddr(cidr)
+				Expect(err).NotTo(HaveOccurred())
+				addr.Flags = netlinkPkg.AddrFlagPermanent
+				addr.ValidLft = netlinkPkg.InfiniteLifetime
+				return *addr
+			}
+			saved := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0", Flags: net.FlagUp, MTU: 9000}}
+			hostMock.On("LsMod", mock.Anything).Return(map[string]host.LoadedModule{
+				"mlx5_core": {Name: "mlx5_core", RefCount: 1, UsedBy: []string{}},
+			}, nil).Once()
+			osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			osMock.On("ReadFile", "/sys/class/net/eth0/device/vendor").Return([]byte("0x15b3"), nil).Once()
+			sriovnetMock.On("GetPciFromNetDevice", "eth0").Return("0000:08:00.0", nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(saved, nil).Once()
+			netlinkMock.On("AddrList", saved, netlinkPkg.FamilyAll).Return([]netlink.Addr{
+				staticAddr("192.168.1.10/24"), staticAddr("2001:db8::10/64"),
+			}, nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/0000:08:00.0").
+				Return("pci/0000:08:00.0: mode legacy", "", nil).Once()
+			osMock.On("ReadFile", "/sys/class/net/eth0/device/sriov_numvfs").Return([]byte("0"), nil).Once()
+			osMock.On("ReadDir", "/sys/class/net/eth0/device/").Return([]os.DirEntry{}, nil).Once()
+
+			Expect(nc.Save(ctx)).To(Succeed())
+			Expect(nc.mellanoxDevices["eth0"].PfNumVfs).To(Equal(0))
+			Expect(nc.mellanoxDevices["eth0"].IPAddrs).To(Equal([]string{"192.168.1.10/24", "2001:db8::10/64"}))
+
+			// The PF comes back from the reload down, with the default MTU and without addresses
+			restored := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(restored, nil).Times(3)
+			netlinkMock.On("LinkSetUp", restored).Return(nil).Once()
+			netlinkMock.On("LinkSetMTU", restored, 9000).Return(nil).Once()
+			netlinkMock.On("AddrList", restored, netlinkPkg.FamilyAll).Return([]netlink.Addr{}, nil).Once()
+			netlinkMock.On("AddrAdd", restored, mock.MatchedBy(func(addr *netlink.Addr) bool {
+				return addr.IPNet.String() == "192.168.1.10/24"
+			})).Return(nil).Once()
+			netlinkMock.On("AddrAdd", restored, mock.MatchedBy(func(addr *netlink.Addr) bool {
+				return addr.IPNet.String() == "2001:db8::10/64"
+			})).Return(nil).Once()
+
+			Expect(nc.Restore(ctx)).To(Succeed())
+		})
 	})
 
 	Context("Helper functions", func() {
@@ -240,7 +743,7 @@ var _ = Describe("Netconfig", func() {
 			hostMock = hostMockPkg.NewInterface(GinkgoT())
 			sriovnetMock = sriovnetMockPkg.NewLib(GinkgoT())
 			netlinkMock := netlinkMockPkg.NewLib(GinkgoT())
-			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, 4).(*netconfig)
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{BindDelaySec: 4}).(*netconfig)
 		})
 
 		Context("getCurrentDeviceName", func() {
@@ -285,20 +788,194 @@ var _ = Describe("Netconfig", func() {
 			})
 		})
 
+		Context("isDeviceManaged", func() {
+			devices := map[string]string{
+				"eth0":   "0000:08:00.0",
+				"eth1":   "0000:08:00.1",
+				"enp3s0": "0000:03:00.0",
+				"ib0":    "0000:81:00.0",
+			}
+
+			managed := func() []string {
+				result := []string{}
+				for devName, pciAddr := range devices {
+					if nc.isDeviceManaged(devName, pciAddr) {
+						result = append(result, devName)
+					}
+				}
+				return result
+			}
+
+			It("should filter discovered devices by include and exclude lists", func() {
+				testCases := []struct {
+					name     string
+					include  []string
+					exclude  []string
+					expected []string
+				}{
+					{
+						name:     "no filters manages all devices",
+						expected: []string{"eth0", "eth1", "enp3s0", "ib0"},
+					},
+					{
+						name:     "include only by name glob and PCI address",
+						include:  []string{"eth*", "0000:81:00.0"},
+						expected: []string{"eth0", "eth1", "ib0"},
+					},
+					{
+						name:     "exclude only by name and PCI glob",
+						exclude:  []string{"ib0", "0000:08:00.*"},
+						expected: []string{"enp3s0"},
+					},
+					{
+						name:     "exclude wins over include",
+						include:  []string{"eth*", "enp3s0"},
+						exclude:  []string{"0000:08:00.1"},
+						expected: []string{"eth0", "enp3s0"},
+					},
+				}
+
+				for _, tc := range testCases {
+					By(tc.name)
+					nc.include = tc.include
+					nc.exclude = tc.exclude
+					Expect(managed()).To(ConsistOf(tc.expected))
+				}
+			})
+
+			It("should skip excluded devices during discovery", func() {
+				nc.exclude = []string{"eth0"}
+				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth0/device/vendor").Return([]byte("0x15b3"), nil).Once()
+				sriovnetMock.On("GetPciFromNetDevice", "eth0").Return("0000:08:00.0", nil).Once()
+
+				discovered, err := nc.discoverMellanoxDevices(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(discovered).To(BeEmpty())
+				Expect(nc.mellanoxDevices).To(BeEmpty())
+			})
+		})
+
+		Context("getEswitchSettings", func() {
+			It("should parse mode, inline-mode and encap-mode", func() {
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/0000:08:00.0").
+					Return("pci/0000:08:00.0: mode switchdev inline-mode none encap-mode basic\n", "", nil).Once()
+
+				settings, err := nc.getEswitchSettings(context.Background(), "0000:08:00.0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(settings).To(Equal(eswitchSettings{Mode: "switchdev", InlineMode: "none", EncapMode: "basic"}))
+			})
+
+			It("should leave inline-mode and encap-mode empty when not reported", func() {
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/0000:08:00.0").
+					Return("pci/0000:08:00.0: mode legacy\n", "", nil).Once()
+
+				settings, err := nc.getEswitchSettings(context.Background(), "0000:08:00.0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(settings).To(Equal(eswitchSettings{Mode: "legacy"}))
+			})
+
+			It("should fail when command fails", func() {
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/0000:08:00.0").
+					Return("", "error", fmt.Errorf("devlink failed")).Once()
+
+				_, err := nc.getEswitchSettings(context.Background(), "0000:08:00.0")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("setEswitchInlineAndEncapMode", func() {
+			It("should set both inline-mode and encap-mode", func() {
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "set", "pci/0000:08:00.0",
+					"inline-mode", "none", "encap-mode", "basic").Return("", "", nil).Once()
+
+				err := nc.setEswitchInlineAndEncapMode(context.Background(), "0000:08:00.0", "none", "basic")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should only set the values that were read", func() {
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "set", "pci/0000:08:00.0",
+					"encap-mode", "basic").Return("", "", nil).Once()
+
+				err := nc.setEswitchInlineAndEncapMode(context.Background(), "0000:08:00.0", "", "basic")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should not run devlink when no values were read", func() {
+				err := nc.setEswitchInlineAndEncapMode(context.Background(), "0000:08:00.0", "", "")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
 		Context("createVFs", func() {
 			It("should succeed", func() {
 				osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("4"), os.FileMode(0o644)).Return(nil).Once()
+				osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("4\n"), nil).Once()
 
-				err := nc.createVFs("0000:08:00.0", 4)
+				err := nc.createVFs(context.Background(), "0000:08:00.0", 4)
 				Expect(err).NotTo(HaveOccurred())
 			})
 
 			It("should fail when WriteFile fails", func() {
 				osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("4"), os.FileMode(0o644)).Return(fmt.Errorf("write failed")).Once()
 
-				err := nc.createVFs("0000:08:00.0", 4)
+				err := nc.createVFs(context.Background(), "0000:08:00.0", 4)
 				Expect(err).To(HaveOccurred())
 			})
+
+			It("should succeed after one retry when the PF is busy", func() {
+				nc.sriovNumVfsRetries = 3
+				nc.sriovNumVfsRetryDelay = time.Millisecond
+				osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("4"), os.FileMode(0o644)).Return(syscall.EBUSY).Once()
+				osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("4"), os.FileMode(0o644)).Return(nil).Once()
+				osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("4"), nil).Once()
+
+				err := nc.createVFs(context.Background(), "0000:08:00.0", 4)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should fail with the last read value when sriov_numvfs never converges", func() {
+				nc.sriovNumVfsSettleTimeout = 300 * time.Millisecond
+				osMock.On("WriteFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs", []byte("4"), os.FileMode(0o644)).Return(nil).Once()
+				osMock.On("ReadFile", "/sys/bus/pci/devices/0000:08:00.0/sriov_numvfs").Return([]byte("0"), nil)
+
+				err := nc.createVFs(context.Background(), "0000:08:00.0", 4)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("did not converge to 4"))
+				Expect(err.Error()).To(ContainSubstring("last value: 0"))
+			})
+		})
+
+		Context("collectVFInfo", func() {
+			It("should mark the device as partial when VF details can't be collected", func() {
+				osMock.On("ReadDir", "/sys/class/net/eth0/device/virtfn0/net/").Return([]os.DirEntry{}, nil).Once()
+				osMock.On("ReadDir", "/sys/class/net/eth0/device/virtfn1/net/").Return(nil, fmt.Errorf("not found")).Once()
+				device := &MellanoxDevice{PfNumVfs: 2}
+
+				nc.collectVFInfo(context.Background(), "eth0", device)
+				Expect(device.VFs).To(BeEmpty())
+				Expect(device.PartialVFs).To(BeTrue())
+				Expect(missingVFIndexes(device)).To(Equal([]int{0, 1}))
+			})
+
+			It("should do nothing when the device has no VFs", func() {
+				device := &MellanoxDevice{}
+
+				nc.collectVFInfo(context.Background(), "eth0", device)
+				Expect(device.PartialVFs).To(BeFalse())
+			})
+		})
+
+		Context("missingVFIndexes", func() {
+			It("should return the VF indexes without saved details", func() {
+				device := &MellanoxDevice{PfNumVfs: 4, VFs: []VF{{VFIndex: 0}, {VFIndex: 2}}}
+				Expect(missingVFIndexes(device)).To(Equal([]int{1, 3}))
+			})
+
+			It("should return no indexes when all VFs were saved", func() {
+				device := &MellanoxDevice{PfNumVfs: 2, VFs: []VF{{VFIndex: 0}, {VFIndex: 1}}}
+				Expect(missingVFIndexes(device)).To(BeEmpty())
+			})
 		})
 
 		Context("isMellanoxDeviceByInterface", func() {
@@ -390,106 +1067,460 @@ var _ = Describe("Netconfig", func() {
 			It("should return number of VFs", func() {
 				osMock.On("ReadFile", "/sys/class/net/eth0/device/sriov_numvfs").Return([]byte("4"), nil).Once()
 
-				result := nc.getPfNumVfsFromSysfs("eth0")
+				result := nc.getPfNumVfsFromSysfs(context.Background(), "eth0")
 				Expect(result).To(Equal(4))
 			})
 
 			It("should return 0 when ReadFile fails", func() {
 				osMock.On("ReadFile", "/sys/class/net/eth0/device/sriov_numvfs").Return(nil, fmt.Errorf("read failed")).Once()
 
-				result := nc.getPfNumVfsFromSysfs("eth0")
+				result := nc.getPfNumVfsFromSysfs(context.Background(), "eth0")
 				Expect(result).To(Equal(0))
 			})
+
+			Context("with a fake sysfs root", func() {
+				var deviceDir string
+
+				BeforeEach(func() {
+					sysfsRoot := GinkgoT().TempDir()
+					deviceDir = filepath.Join(sysfsRoot, "class", "net", "eth0", "device")
+					Expect(os.MkdirAll(deviceDir, 0o755)).To(Succeed())
+
+					nc = New(cmdMock, wrappers.NewOS(), hostMock, sriovnetMock, netlinkMockPkg.NewLib(GinkgoT()),
+						config.Config{SysfsRoot: sysfsRoot}).(*netconfig)
+				})
+
+				It("should read sriov_numvfs", func() {
+					Expect(os.WriteFile(filepath.Join(deviceDir, "sriov_numvfs"), []byte("2\n"), 0o644)).To(Succeed())
+					Expect(os.Symlink("../0000:08:00.2", filepath.Join(deviceDir, "virtfn0"))).To(Succeed())
+
+					Expect(nc.getPfNumVfsFromSysfs(context.Background(), "eth0")).To(Equal(2))
+				})
+
+				It("should fall back to counting virtfn entries when sriov_numvfs reports 0", func() {
+					Expect(os.WriteFile(filepath.Join(deviceDir, "sriov_numvfs"), []byte("0\n"), 0o644)).To(Succeed())
+					Expect(os.Symlink("../0000:08:00.2", filepath.Join(deviceDir, "virtfn0"))).To(Succeed())
+					Expect(os.Symlink("../0000:08:00.3", filepath.Join(deviceDir, "virtfn1"))).To(Succeed())
+					Expect(os.Symlink("../0000:08:00.4", filepath.Join(deviceDir, "virtfn2"))).To(Succeed())
+
+					var logs []string
+					ctx := logr.NewContext(context.Background(), funcr.New(func(_, args string) {
+						logs = append(logs, args)
+					}, funcr.Options{}))
+
+					Expect(nc.getPfNumVfsFromSysfs(ctx, "eth0")).To(Equal(3))
+					Expect(logs).To(ContainElement(ContainSubstring("sriov_numvfs reports 0 VFs")))
+				})
+
+				It("should return 0 when there are no VFs", func() {
+					Expect(os.WriteFile(filepath.Join(deviceDir, "sriov_numvfs"), []byte("0\n"), 0o644)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(deviceDir, "sriov_totalvfs"), []byte("8\n"), 0o644)).To(Succeed())
+
+					Expect(nc.getPfNumVfsFromSysfs(context.Background(), "eth0")).To(Equal(0))
+				})
+			})
 		})
 
-		Context("restructureGUID", func() {
-			It("should restructure valid GUID", func() {
-				result := nc.restructureGUID("0c42a1030016054c")
-				Expect(result).To(Equal("0c42:a103:0016:054c"))
+		Context("IP addresses", func() {
+			var (
+				netlinkMock *netlinkMockPkg.Lib
+				link        *mockLink
+			)
+
+			// mustAddr returns a static address, as reported by the kernel for the addresses without a lifetime
+			mustAddr := func(cidr string) netlink.Addr {
+				addr, err := netlink.ParseAddr(cidr)
+				Expect(err).NotTo(HaveOccurred())
+				addr.Flags = netlinkPkg.AddrFlagPermanent
+				addr.ValidLft = netlinkPkg.InfiniteLifetime
+				return *addr
+			}
+
+			BeforeEach(func() {
+				netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
+				nc.netlinkLib = netlinkMock
+				link = &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0", Flags: net.FlagUp, MTU: 1500}}
+			})
+
+			It("should round-trip static IPv4 and IPv6 addresses", func() {
+				netlinkMock.On("AddrList", link, netlinkPkg.FamilyAll).Return([]netlink.Addr{
+					mustAddr("192.168.1.10/24"),
+					mustAddr("2001:db8::10/64"),
+					mustAddr("fe80::1/64"),
+				}, nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth0/device/sriov_numvfs").Return([]byte("0"), nil).Once()
+				osMock.On("ReadDir", "/sys/class/net/eth0/device/").Return([]os.DirEntry{}, nil).Once()
+
+				device := nc.collectDeviceInfo(context.Background(), "eth0", "0000:08:00.0", link)
+				Expect(device.IPAddrs).To(Equal([]string{"192.168.1.10/24", "2001:db8::10/64"}))
+
+				// After the reload only the kernel-managed link-local address is present
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("AddrList", link, netlinkPkg.FamilyAll).Return([]netlink.Addr{mustAddr("fe80::1/64")}, nil).Once()
+				netlinkMock.On("AddrAdd", link, mock.MatchedBy(func(addr *netlink.Addr) bool {
+					return addr.IPNet.String() == "192.168.1.10/24"
+				})).Return(nil).Once()
+				netlinkMock.On("AddrAdd", link, mock.MatchedBy(func(addr *netlink.Addr) bool {
+					return addr.IPNet.String() == "2001:db8::10/64"
+				})).Return(nil).Once()
+
+				err := nc.restoreDeviceIPAddrs(context.Background(), "eth0", device.IPAddrs)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should skip the DHCP and temporary SLAAC addresses", func() {
+				dhcpAddr, err := netlink.ParseAddr("192.168.1.20/24")
+				Expect(err).NotTo(HaveOccurred())
+				dhcpAddr.ValidLft = 3600
+				slaacAddr, err := netlink.ParseAddr("2001:db8::abcd/64")
+				Expect(err).NotTo(HaveOccurred())
+				slaacAddr.Flags = syscall.IFA_F_TEMPORARY
+				slaacAddr.ValidLft = 86400
+				netlinkMock.On("AddrList", link, netlinkPkg.FamilyAll).Return([]netlink.Addr{
+					mustAddr("192.168.1.10/24"),
+					*dhcpAddr,
+					*slaacAddr,
+				}, nil).Once()
+
+				ipAddrs, err := nc.getDeviceIPAddrs(link)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ipAddrs).To(Equal([]string{"192.168.1.10/24"}))
+			})
+
+			It("should not add addresses that are already present", func() {
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("AddrList", link, netlinkPkg.FamilyAll).Return([]netlink.Addr{mustAddr("192.168.1.10/24")}, nil).Once()
+				netlinkMock.On("AddrAdd", link, mock.MatchedBy(func(addr *netlink.Addr) bool {
+					return addr.IPNet.String() == "2001:db8::10/64"
+				})).Return(nil).Once()
+
+				err := nc.restoreDeviceIPAddrs(context.Background(), "eth0", []string{"192.168.1.10/24", "2001:db8::10/64"})
+				Expect(err).NotTo(HaveOccurred())
 			})
 
-			It("should return original for short GUID", func() {
-				result := nc.restructureGUID("0c42a103")
-				Expect(result).To(Equal("0c42a103"))
+			It("should return error when AddrAdd fails", func() {
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("AddrList", link, netlinkPkg.FamilyAll).Return([]netlink.Addr{}, nil).Once()
+				netlinkMock.On("AddrAdd", link, mock.Anything).Return(fmt.Errorf("permission denied")).Once()
+
+				err := nc.restoreDeviceIPAddrs(context.Background(), "eth0", []string{"192.168.1.10/24"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to add IP address 192.168.1.10/24"))
 			})
+		})
+
+		Context("with a custom sysfs root", func() {
+			It("should read device attributes below the configured root", func() {
+				sysfsRoot := GinkgoT().TempDir()
+				deviceDir := filepath.Join(sysfsRoot, "class", "net", "eth0", "device")
+				Expect(os.MkdirAll(deviceDir, 0o755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(deviceDir, "vendor"), []byte("0x15b3\n"), 0o644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(deviceDir, "sriov_numvfs"), []byte("8\n"), 0o644)).To(Succeed())
+
+				nc = New(cmdMock, wrappers.NewOS(), hostMock, sriovnetMock, netlinkMockPkg.NewLib(GinkgoT()),
+					config.Config{SysfsRoot: sysfsRoot}).(*netconfig)
 
-			It("should return empty for empty GUID", func() {
-				result := nc.restructureGUID("")
-				Expect(result).To(Equal(""))
+				Expect(nc.isMellanoxDeviceByInterface("eth0")).To(BeTrue())
+				Expect(nc.getPfNumVfsFromSysfs(context.Background(), "eth0")).To(Equal(8))
+			})
+		})
+
+		Context("restructureGUID", func() {
+			It("should normalize GUIDs to colon-separated bytes", func() {
+				testCases := []struct {
+					input    string
+					expected string
+				}{
+					{"0c42a1030016054c", "0c:42:a1:03:00:16:05:4c"},
+					{"0C42A1030016054C", "0c:42:a1:03:00:16:05:4c"},
+					{" 0c42a1030016054c\n", "0c:42:a1:03:00:16:05:4c"},
+					{"0c:42:a1:03:00:16:05:4c", "0c:42:a1:03:00:16:05:4c"},
+					{"0c-42-a1-03-00-16-05-4c", "0c:42:a1:03:00:16:05:4c"},
+					{"0c42:a103:0016:054c", "0c:42:a1:03:00:16:05:4c"},
+					{"0c42-a103-0016-054c", "0c:42:a1:03:00:16:05:4c"},
+					{"00:00:00:00:00:00:00:00", "00:00:00:00:00:00:00:00"},
+				}
+
+				for _, tc := range testCases {
+					result, err := nc.restructureGUID(tc.input)
+					Expect(err).NotTo(HaveOccurred(), "input: %q", tc.input)
+					Expect(result).To(Equal(tc.expected), "input: %q", tc.input)
+				}
+			})
+
+			It("should return error for malformed GUIDs", func() {
+				malformed := []string{
+					"",
+					"-",
+					"0c42a103",
+					"0c42a1030016054c00",
+					"0c:42:a1:03:00:16:05",
+					"0g42a1030016054c",
+					"0c.42.a1.03.00.16.05.4c",
+					"0x42a1030016054c",
+					"+c42a1030016054c",
+				}
+
+				for _, input := range malformed {
+					result, err := nc.restructureGUID(input)
+					Expect(err).To(HaveOccurred(), "input: %q", input)
+					Expect(result).To(BeEmpty(), "input: %q", input)
+				}
 			})
 		})
 
 		Context("setIBGUIDs", func() {
+			var (
+				netlinkMock *netlinkMockPkg.Lib
+				link        *mockLink
+			)
+
+			BeforeEach(func() {
+				netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
+				nc.netlinkLib = netlinkMock
+				link = &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+			})
+
 			It("should skip invalid all-zero GUID", func() {
 				// Test that invalid GUID (00:00:00:00:00:00:00:00) is skipped
-				// and no ip link commands are called
+				// and neither netlink nor ip link commands are called
 				err := nc.setIBGUIDs(context.Background(), "eth0", 0, "00:00:00:00:00:00:00:00")
 				Expect(err).NotTo(HaveOccurred())
 
-				// Verify no commands were called
+				// Verify no commands were called
+				cmdMock.AssertNotCalled(GinkgoT(), "RunCommand")
+			})
+
+			It("should set valid GUID successfully with netlink", func() {
+				validGUID := "0c:42:a1:03:00:16:05:4c"
+				hwGUID, _ := net.ParseMAC(validGUID)
+
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("LinkSetVfPortGUID", link, 0, hwGUID).Return(nil).Once()
+				netlinkMock.On("LinkSetVfNodeGUID", link, 0, hwGUID).Return(nil).Once()
+
+				err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
+				Expect(err).NotTo(HaveOccurred())
+
+				// The ip command is not needed
+				cmdMock.AssertNotCalled(GinkgoT(), "RunCommand")
+			})
+
+			It("should return error when the link is not found", func() {
+				netlinkMock.On("LinkByName", "eth0").Return(nil, fmt.Errorf("link not found")).Once()
+
+				err := nc.setIBGUIDs(context.Background(), "eth0", 0, "0c:42:a1:03:00:16:05:4c")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to get link eth0"))
+			})
+
+			It("should return error when setting the port GUID fails", func() {
+				validGUID := "0c:42:a1:03:00:16:05:4c"
+				hwGUID, _ := net.ParseMAC(validGUID)
+
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("LinkSetVfPortGUID", link, 0, hwGUID).Return(fmt.Errorf("operation not supported")).Once()
+
+				err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to set port GUID"))
+			})
+
+			It("should return error when setting the node GUID fails", func() {
+				validGUID := "0c:42:a1:03:00:16:05:4c"
+				hwGUID, _ := net.ParseMAC(validGUID)
+
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("LinkSetVfPortGUID", link, 0, hwGUID).Return(nil).Once()
+				netlinkMock.On("LinkSetVfNodeGUID", link, 0, hwGUID).Return(fmt.Errorf("operation not supported")).Once()
+
+				err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to set node GUID"))
+			})
+
+			It("should normalize dash-separated GUID before setting it", func() {
+				hwGUID, _ := net.ParseMAC("0c:42:a1:03:00:16:05:4c")
+
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("LinkSetVfPortGUID", link, 0, hwGUID).Return(nil).Once()
+				netlinkMock.On("LinkSetVfNodeGUID", link, 0, hwGUID).Return(nil).Once()
+
+				err := nc.setIBGUIDs(context.Background(), "eth0", 0, "0C-42-A1-03-00-16-05-4C")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should return error for malformed GUID without running commands", func() {
+				err := nc.setIBGUIDs(context.Background(), "eth0", 0, "0c:42:a1:03")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid GUID"))
+
+				cmdMock.AssertNotCalled(GinkgoT(), "RunCommand")
+			})
+
+			It("should handle different VF indices correctly", func() {
+				validGUID := "0c:42:a1:03:00:16:05:4d"
+				hwGUID, _ := net.ParseMAC(validGUID)
+				vfIndex := 3
+
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("LinkSetVfPortGUID", link, vfIndex, hwGUID).Return(nil).Once()
+				netlinkMock.On("LinkSetVfNodeGUID", link, vfIndex, hwGUID).Return(nil).Once()
+
+				err := nc.setIBGUIDs(context.Background(), "eth0", vfIndex, validGUID)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			Context("with PreferIPCommand", func() {
+				BeforeEach(func() {
+					nc.preferIPCommand = true
+				})
+
+				It("should set valid GUID successfully with the ip command", func() {
+					validGUID := "0c:42:a1:03:00:16:05:4c"
+
+					cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "port_guid", validGUID).
+						Return("", "", nil).Once()
+					cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "node_guid", validGUID).
+						Return("", "", nil).Once()
+
+					err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
+					Expect(err).NotTo(HaveOccurred())
+
+					// netlink is not used
+					netlinkMock.AssertNotCalled(GinkgoT(), "LinkByName", mock.Anything)
+				})
+
+				It("should return error when node_guid command fails", func() {
+					validGUID := "0c:42:a1:03:00:16:05:4c"
+
+					cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "port_guid", validGUID).
+						Return("", "", nil).Once()
+					cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "node_guid", validGUID).
+						Return("", "error setting node_guid", fmt.Errorf("command failed")).Once()
+
+					err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("failed to set node GUID"))
+					Expect(err).NotTo(MatchError(ErrIPCommandNotFound))
+				})
+
+				It("should return a command not found error when ip is missing", func() {
+					validGUID := "0c:42:a1:03:00:16:05:4c"
+
+					cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "port_guid", validGUID).
+						Return("", "", &cmd.ErrCommandNotFound{Command: "ip", Err: exec.ErrNotFound}).Once()
+
+					err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
+					Expect(err).To(MatchError(ErrIPCommandNotFound))
+					Expect(err.Error()).To(ContainSubstring("failed to set port GUID"))
+					Expect(err.Error()).To(ContainSubstring("install iproute2"))
+					var notFoundErr *cmd.ErrCommandNotFound
+					Expect(errors.As(err, &notFoundErr)).To(BeTrue())
+				})
+			})
+		})
+
+		Context("setVFAdminMAC", func() {
+			var (
+				netlinkMock *netlinkMockPkg.Lib
+				link        *mockLink
+				vf          VF
+			)
+
+			BeforeEach(func() {
+				netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
+				nc.netlinkLib = netlinkMock
+				link = &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+				vf = VF{VFIndex: 2, AdminMAC: "aa:bb:cc:dd:ee:01"}
+			})
+
+			It("should set the VF admin MAC through the PF with netlink", func() {
+				mac, _ := net.ParseMAC("aa:bb:cc:dd:ee:01")
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("LinkSetVfHardwareAddr", link, 2, mac).Return(nil).Once()
+
+				Expect(nc.setVFAdminMAC(context.Background(), "eth0", vf)).To(Succeed())
 				cmdMock.AssertNotCalled(GinkgoT(), "RunCommand")
 			})
 
-			It("should set valid GUID successfully", func() {
-				validGUID := "0c:42:a1:03:00:16:05:4c"
+			It("should return error when netlink fails to set the VF admin MAC", func() {
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once()
+				netlinkMock.On("LinkSetVfHardwareAddr", link, 2, mock.Anything).Return(fmt.Errorf("operation not supported")).Once()
 
-				// Mock successful port_guid command
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "port_guid", validGUID).
-					Return("", "", nil).Once()
+				err := nc.setVFAdminMAC(context.Background(), "eth0", vf)
+				Expect(err).To(MatchError(ContainSubstring("failed to set VF admin MAC")))
+			})
 
-				// Mock successful node_guid command
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "node_guid", validGUID).
+			It("should set the VF admin MAC with the ip command with PreferIPCommand", func() {
+				nc.preferIPCommand = true
+				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "eth0", "vf", "2", "mac", "aa:bb:cc:dd:ee:01").
 					Return("", "", nil).Once()
 
-				err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
-				Expect(err).NotTo(HaveOccurred())
-
-				// Verify both commands were called
-				cmdMock.AssertExpectations(GinkgoT())
+				Expect(nc.setVFAdminMAC(context.Background(), "eth0", vf)).To(Succeed())
+				netlinkMock.AssertNotCalled(GinkgoT(), "LinkByName", mock.Anything)
 			})
+		})
 
-			It("should return error when port_guid command fails", func() {
-				validGUID := "0c:42:a1:03:00:16:05:4c"
+		Context("detectPFGUIDChange", func() {
+			const (
+				ibDevicePath = "/sys/class/net/ib0/device/infiniband"
+				savedGUID    = "0c:42:a1:03:00:16:05:4c"
+			)
 
-				// Mock failed port_guid command
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "port_guid", validGUID).
-					Return("", "error setting port_guid", fmt.Errorf("command failed")).Once()
+			var (
+				logs   []string
+				logCtx context.Context
+			)
 
-				err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to set port GUID"))
+			expectNodeGUID := func(guid string) {
+				osMock.On("ReadDir", ibDevicePath).Return([]os.DirEntry{&mockDirEntry{name: "mlx5_0"}}, nil).Once()
+				osMock.On("ReadFile", ibDevicePath+"/mlx5_0/node_guid").Return([]byte(guid+"\n"), nil).Once()
+			}
+
+			BeforeEach(func() {
+				logs = nil
+				logCtx = logr.NewContext(context.Background(), funcr.New(func(_, args string) {
+					logs = append(logs, args)
+				}, funcr.Options{}))
 			})
 
-			It("should return error when node_guid command fails", func() {
-				validGUID := "0c:42:a1:03:00:16:05:4c"
+			It("should report and warn when the GUID of an IB PF changed across the reload", func() {
+				netlinkMock := netlinkMockPkg.NewLib(GinkgoT())
+				nc.netlinkLib = netlinkMock
+				link := &mockLink{attrs: &netlink.LinkAttrs{Name: "ib0", Flags: net.FlagUp, MTU: 2044}}
+				netlinkMock.On("AddrList", link, netlinkPkg.FamilyAll).Return([]netlink.Addr{}, nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/ib0/device/sriov_numvfs").Return([]byte("0"), nil).Once()
+				osMock.On("ReadDir", "/sys/class/net/ib0/device/").Return([]os.DirEntry{}, nil).Once()
+				expectNodeGUID("0c42:a103:0016:054c")
 
-				// Mock successful port_guid command
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "port_guid", validGUID).
-					Return("", "", nil).Once()
+				device := nc.collectDeviceInfo(context.Background(), "ib0", "0000:08:00.0", link)
+				Expect(device.DevType).To(Equal(devTypeIB))
+				Expect(device.GUID).To(Equal(savedGUID))
 
-				// Mock failed node_guid command
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "0", "node_guid", validGUID).
-					Return("", "error setting node_guid", fmt.Errorf("command failed")).Once()
+				// The reload brought the PF back with another GUID
+				expectNodeGUID("0c42:a103:0016:0000")
 
-				err := nc.setIBGUIDs(context.Background(), "eth0", 0, validGUID)
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("failed to set node GUID"))
+				Expect(nc.detectPFGUIDChange(logCtx, "ib0", device)).To(BeTrue())
+				Expect(logs).To(ContainElement(ContainSubstring("[WARN] PF GUID changed across the driver reload")))
+				cmdMock.AssertNotCalled(GinkgoT(), "RunCommand")
 			})
 
-			It("should handle different VF indices correctly", func() {
-				validGUID := "0c:42:a1:03:00:16:05:4d"
-				vfIndex := 3
+			It("should not report an unchanged GUID", func() {
+				expectNodeGUID("0c42:a103:0016:054c")
 
-				// Mock successful commands for VF index 3
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "3", "port_guid", validGUID).
-					Return("", "", nil).Once()
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "eth0", "vf", "3", "node_guid", validGUID).
-					Return("", "", nil).Once()
+				Expect(nc.detectPFGUIDChange(logCtx, "ib0", &MellanoxDevice{DevType: devTypeIB, GUID: savedGUID})).To(BeFalse())
+				Expect(logs).NotTo(ContainElement(ContainSubstring("[WARN]")))
+			})
 
-				err := nc.setIBGUIDs(context.Background(), "eth0", vfIndex, validGUID)
-				Expect(err).NotTo(HaveOccurred())
+			It("should skip Ethernet PFs", func() {
+				Expect(nc.detectPFGUIDChange(logCtx, "eth0", &MellanoxDevice{DevType: devTypeEth, GUID: "-"})).To(BeFalse())
+				osMock.AssertNotCalled(GinkgoT(), "ReadDir", mock.Anything)
+			})
 
-				cmdMock.AssertExpectations(GinkgoT())
+			It("should skip IB PFs without a saved GUID", func() {
+				Expect(nc.detectPFGUIDChange(logCtx, "ib0", &MellanoxDevice{DevType: devTypeIB, GUID: "-"})).To(BeFalse())
+				osMock.AssertNotCalled(GinkgoT(), "ReadDir", mock.Anything)
 			})
 		})
 	})
@@ -511,7 +1542,7 @@ var _ = Describe("Netconfig", func() {
 			hostMock = hostMockPkg.NewInterface(GinkgoT())
 			sriovnetMock = sriovnetMockPkg.NewLib(GinkgoT())
 			netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
-			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, 4).(*netconfig)
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{BindDelaySec: 4}).(*netconfig)
 			ctx = context.Background()
 		})
 
@@ -564,7 +1595,9 @@ var _ = Describe("Netconfig", func() {
 				}
 
 				// Mock VF configuration
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "eth3", "vf", "0", "mac", "00:00:00:00:00:00").Return("", "", nil).Once()
+				pfLink := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth3"}}
+				netlinkMock.On("LinkByName", "eth3").Return(pfLink, nil).Once()
+				netlinkMock.On("LinkSetVfHardwareAddr", pfLink, 0, net.HardwareAddr{0, 0, 0, 0, 0, 0}).Return(nil).Once()
 
 				// Mock VF unbinding
 				osMock.On("WriteFile", "/sys/bus/pci/drivers/mlx5_core/unbind", []byte("0000:08:01.0"), os.FileMode(0o644)).Return(nil).Once()
@@ -572,27 +1605,42 @@ var _ = Describe("Netconfig", func() {
 				// Mock Readlink for driver check
 				osMock.On("Readlink", "/sys/bus/pci/devices/0000:08:01.0/driver").Return("../../../../bus/pci/drivers/mlx5_core", nil).Once()
 
-				// Mock getCurrentDeviceName for VF
-				osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:01.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth10"}}, nil).Once()
-
-				// Mock netlink call for VF state restoration
-				mockLink := &mockLink{
-					attrs: &netlink.LinkAttrs{
-						Name:  "eth10",
-						Flags: net.FlagUp,
-						MTU:   1500,
-					},
-				}
-				netlinkMock.On("LinkByName", "eth10").Return(mockLink, nil).Once()
-				netlinkMock.On("LinkSetHardwareAddr", mockLink, mock.AnythingOfType("net.HardwareAddr")).Return(nil).Once()
-
-				err := nc.restoreVFConfigurations(ctx, "eth3", device, eswitchModeSwitchdev)
+				// The VF netdev is not looked up, the hardware MAC is set after the rebind
+				_, err := nc.restoreVFConfigurations(ctx, "eth3", device, eswitchModeSwitchdev)
 				Expect(err).NotTo(HaveOccurred())
 
 				// Verify VF was configured and unbound, but not rebound
 				cmdMock.AssertExpectations(GinkgoT())
 			})
 
+			It("should set the VF hardware MAC after the rebind in switchdev mode", func() {
+				nc.bindDelaySec = 0
+				device := &MellanoxDevice{
+					PCIAddr:     "0000:08:00.1",
+					DevType:     devTypeEth,
+					EswitchMode: eswitchModeSwitchdev,
+					PfNumVfs:    1,
+					VFs: []VF{
+						{VFIndex: 0, VFPCIAddr: "0000:08:01.0", VFName: "eth10", AdminState: adminStateUp, MACAddress: "2a:c1:0b:f4:b5:3e", AdminMAC: "2a:c1:0b:f4:b5:3e", MTU: 9000, GUID: "-"},
+					},
+				}
+				vfLink := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth10"}}
+				hwAddr, _ := net.ParseMAC("2a:c1:0b:f4:b5:3e")
+
+				osMock.On("Readlink", "/sys/bus/pci/devices/0000:08:01.0/driver").Return("", fmt.Errorf("not bound")).Once()
+				osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:01.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth10"}}, nil)
+				netlinkMock.On("LinkByName", "eth10").Return(vfLink, nil)
+				mock.InOrder(
+					osMock.On("WriteFile", "/sys/bus/pci/drivers/mlx5_core/bind", []byte("0000:08:01.0"), os.FileMode(0o644)).Return(nil).Once(),
+					netlinkMock.On("LinkSetHardwareAddr", vfLink, hwAddr).Return(nil).Once(),
+					netlinkMock.On("LinkSetMTU", vfLink, 9000).Return(nil).Once(),
+					netlinkMock.On("LinkSetUp", vfLink).Return(nil).Once(),
+				)
+
+				err := nc.rebindVFsInSwitchdevMode(ctx, device)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
 			It("should rebind VFs for legacy mode", func() {
 				device := &MellanoxDevice{
 					PCIAddr:     "0000:08:00.0",
@@ -608,7 +1656,9 @@ var _ = Describe("Netconfig", func() {
 				}
 
 				// Mock VF configuration
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "eth2", "vf", "0", "mac", "aa:bb:cc:dd:ee:01").Return("", "", nil).Maybe()
+				pfLink := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth2"}}
+				netlinkMock.On("LinkByName", "eth2").Return(pfLink, nil).Maybe()
+				netlinkMock.On("LinkSetVfHardwareAddr", pfLink, 0, mock.AnythingOfType("net.HardwareAddr")).Return(nil).Maybe()
 
 				// Mock VF unbinding and rebinding
 				osMock.On("WriteFile", "/sys/bus/pci/drivers/mlx5_core/unbind", []byte("0000:08:00.2"), os.FileMode(0o644)).Return(nil).Maybe()
@@ -637,12 +1687,19 @@ var _ = Describe("Netconfig", func() {
 				netlinkMock.On("LinkSetMTU", mockLink, 1500).Return(nil).Maybe()
 				netlinkMock.On("LinkSetUp", mockLink).Return(nil).Maybe()
 
-				err := nc.restoreVFConfigurations(ctx, "eth2", device, eswitchModeLegacy)
+				_, err := nc.restoreVFConfigurations(ctx, "eth2", device, eswitchModeLegacy)
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
 
 		Context("restoreRepresentors with two-phase rename", func() {
+			// expectRename mocks the netlink rename of a representor
+			expectRename := func(currentName, newName string) *mock.Call {
+				link := &mockLink{attrs: &netlink.LinkAttrs{Name: currentName}}
+				netlinkMock.On("LinkByName", currentName).Return(link, nil).Once()
+				return netlinkMock.On("LinkSetName", link, newName).Once()
+			}
+
 			It("should use two-phase rename to avoid name collisions", func() {
 				// This test verifies the two-phase rename mechanism prevents name collisions
 				// when interfaces are swapped after driver reload
@@ -689,7 +1746,7 @@ var _ = Describe("Netconfig", func() {
 
 				// Phase 1: Rename rep1 -> t00abp1v0 (temporary name with switch hash)
 				// Switch ID "00000000000000ab" -> last 4 chars = "00ab"
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "rep1", "name", "t00abp1v0").Return("", "", nil).Once()
+				expectRename("rep1", "t00abp1v0").Return(nil)
 
 				// For VF 1 - find current representor (currently named "rep0")
 				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{
@@ -703,11 +1760,11 @@ var _ = Describe("Netconfig", func() {
 				osMock.On("ReadFile", "/sys/class/net/rep0/phys_port_name").Return([]byte("pf1vf1"), nil).Once()
 
 				// Phase 1: Rename rep0 -> t00abp1v1 (temporary name with switch hash)
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "rep0", "name", "t00abp1v1").Return("", "", nil).Once()
+				expectRename("rep0", "t00abp1v1").Return(nil)
 
 				// Phase 2: Rename from temporary to final names
 				// Rename t00abp1v0 -> eth_rep0
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "t00abp1v0", "name", "eth_rep0").Return("", "", nil).Once()
+				expectRename("t00abp1v0", "eth_rep0").Return(nil)
 
 				// Set MTU for eth_rep0 (LinkByName called once for MTU)
 				mockLink0 := &mockLink{
@@ -725,7 +1782,7 @@ var _ = Describe("Netconfig", func() {
 				netlinkMock.On("LinkSetUp", mockLink0).Return(nil).Once()
 
 				// Rename t00abp1v1 -> eth_rep1
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "t00abp1v1", "name", "eth_rep1").Return("", "", nil).Once()
+				expectRename("t00abp1v1", "eth_rep1").Return(nil)
 
 				// Set MTU for eth_rep1 (LinkByName called once for MTU)
 				mockLink1 := &mockLink{
@@ -742,7 +1799,7 @@ var _ = Describe("Netconfig", func() {
 				netlinkMock.On("LinkByName", "eth_rep1").Return(mockLink1, nil).Once()
 				netlinkMock.On("LinkSetDown", mockLink1).Return(nil).Once()
 
-				err := nc.restoreRepresentors(ctx, "eth5", device)
+				err := nc.restoreRepresentors(ctx, "eth5", device, true)
 				Expect(err).NotTo(HaveOccurred())
 
 				// Verify all expectations were met
@@ -782,14 +1839,13 @@ var _ = Describe("Netconfig", func() {
 				osMock.On("ReadFile", "/sys/class/net/rep0/phys_port_name").Return([]byte("pf1vf0"), nil).Once()
 
 				// Phase 1: Rename fails
-				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "rep0", "name", "t00abp1v0").
-					Return("", "device busy", fmt.Errorf("rename failed")).Once()
+				expectRename("rep0", "t00abp1v0").Return(fmt.Errorf("rename failed"))
 
 				// Phase 2 should not execute since Phase 1 failed (no renameOps added)
 				// No additional mock expectations needed - Phase 2 won't run
 
 				// The function should continue despite the error
-				err := nc.restoreRepresentors(ctx, "eth5", device)
+				err := nc.restoreRepresentors(ctx, "eth5", device, true)
 				Expect(err).NotTo(HaveOccurred())
 
 				cmdMock.AssertExpectations(GinkgoT())
@@ -858,7 +1914,7 @@ var _ = Describe("Netconfig", func() {
 					}
 
 					// Phase 1: Rename to temp name
-					cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", currentName, "name", tempName).Return("", "", nil).Once()
+					expectRename(currentName, tempName).Return(nil)
 				}
 
 				// Mock Phase 2: Rename from temp to final names and set configs
@@ -866,7 +1922,7 @@ var _ = Describe("Netconfig", func() {
 					tempName := fmt.Sprintf("t00abp2v%d", i)
 					finalName := fmt.Sprintf("final_rep%d", i)
 
-					cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", tempName, "name", finalName).Return("", "", nil).Once()
+					expectRename(tempName, finalName).Return(nil)
 
 					mockLink := &mockLink{
 						attrs: &netlink.LinkAttrs{
@@ -882,7 +1938,7 @@ var _ = Describe("Netconfig", func() {
 					netlinkMock.On("LinkSetUp", mockLink).Return(nil).Once()
 				}
 
-				err := nc.restoreRepresentors(ctx, "eth5", device)
+				err := nc.restoreRepresentors(ctx, "eth5", device, true)
 				Expect(err).NotTo(HaveOccurred())
 
 				cmdMock.AssertExpectations(GinkgoT())
@@ -917,12 +1973,641 @@ var _ = Describe("Netconfig", func() {
 				}, nil).Once()
 
 				// Should continue without error
-				err := nc.restoreRepresentors(ctx, "eth5", device)
+				err := nc.restoreRepresentors(ctx, "eth5", device, true)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should re-identify shuffled representors and restore them in PhysPortNum/VFID order", func() {
+				switchID := "00000000000000ab"
+				device := &MellanoxDevice{
+					PCIAddr:     "0000:08:00.0",
+					DevType:     devTypeEth,
+					EswitchMode: eswitchModeSwitchdev,
+					PfNumVfs:    3,
+					// Saved in arbitrary order
+					Representors: []Representor{
+						{PhysSwitchID: switchID, PhysPortNum: "1", VFID: "10", Name: "eth_rep10", AdminState: adminStateUp, MTU: 9000},
+						{PhysSwitchID: switchID, PhysPortNum: "1", VFID: "2", Name: "eth_rep2", AdminState: adminStateDown, MTU: 1500},
+						{PhysSwitchID: switchID, PhysPortNum: "1", VFID: "0", Name: "eth_rep0", AdminState: adminStateUp, MTU: 1500},
+					},
+				}
+
+				// Fake /sys/class/net after reload: the representor names got shuffled
+				physPortNames := map[string]string{
+					"eth5":      "p1",
+					"eth_rep0":  "pf1vf2",
+					"eth_rep2":  "pf1vf10",
+					"eth_rep10": "pf1vf0",
+				}
+				osMock.EXPECT().ReadDir("/sys/class/net/").RunAndReturn(func(string) ([]os.DirEntry, error) {
+					names := make([]string, 0, len(physPortNames))
+					for name := range physPortNames {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					entries := make([]os.DirEntry, 0, len(names))
+					for _, name := range names {
+						entries = append(entries, &mockDirEntry{name: name})
+					}
+					return entries, nil
+				})
+				osMock.EXPECT().ReadFile(mock.MatchedBy(func(path string) bool {
+					return strings.HasSuffix(path, "/phys_switch_id")
+				})).Return([]byte(switchID), nil)
+				osMock.EXPECT().ReadFile(mock.MatchedBy(func(path string) bool {
+					return strings.HasSuffix(path, "/phys_port_name")
+				})).RunAndReturn(func(path string) ([]byte, error) {
+					portName, ok := physPortNames[filepath.Base(filepath.Dir(path))]
+					if !ok {
+						return nil, os.ErrNotExist
+					}
+					return []byte(portName), nil
+				})
+
+				var renames []string
+				netlinkMock.EXPECT().LinkSetName(mock.Anything, mock.Anything).RunAndReturn(func(link netlinkPkg.Link, to string) error {
+					from := link.Attrs().Name
+					renames = append(renames, from+"->"+to)
+					physPortNames[to] = physPortNames[from]
+					delete(physPortNames, from)
+					return nil
+				})
+
+				var applied []string
+				netlinkMock.EXPECT().LinkByName(mock.Anything).RunAndReturn(func(name string) (netlinkPkg.Link, error) {
+					return &mockLink{attrs: &netlink.LinkAttrs{Name: name}}, nil
+				})
+				netlinkMock.EXPECT().LinkSetMTU(mock.Anything, mock.Anything).RunAndReturn(func(link netlinkPkg.Link, mtu int) error {
+					applied = append(applied, fmt.Sprintf("%s mtu %d", link.Attrs().Name, mtu))
+					return nil
+				})
+				netlinkMock.EXPECT().LinkSetUp(mock.Anything).RunAndReturn(func(link netlinkPkg.Link) error {
+					applied = append(applied, link.Attrs().Name+" up")
+					return nil
+				})
+				netlinkMock.EXPECT().LinkSetDown(mock.Anything).RunAndReturn(func(link netlinkPkg.Link) error {
+					applied = append(applied, link.Attrs().Name+" down")
+					return nil
+				})
+
+				err := nc.restoreRepresentors(ctx, "eth5", device, true)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(renames).To(Equal([]string{
+					"eth_rep10->t00abp1v0",
+					"eth_rep0->t00abp1v2",
+					"eth_rep2->t00abp1v10",
+					"t00abp1v0->eth_rep0",
+					"t00abp1v2->eth_rep2",
+					"t00abp1v10->eth_rep10",
+				}))
+				Expect(physPortNames).To(Equal(map[string]string{
+					"eth5":      "p1",
+					"eth_rep0":  "pf1vf0",
+					"eth_rep2":  "pf1vf2",
+					"eth_rep10": "pf1vf10",
+				}))
+				Expect(applied).To(Equal([]string{
+					"eth_rep0 mtu 1500", "eth_rep0 up",
+					"eth_rep2 mtu 1500", "eth_rep2 down",
+					"eth_rep10 mtu 9000", "eth_rep10 up",
+				}))
+			})
+
+			It("should sort representors numerically by PhysPortNum and VFID", func() {
+				sorted := sortRepresentors([]Representor{
+					{PhysPortNum: "2", VFID: "1"},
+					{PhysPortNum: "1", VFID: "10"},
+					{PhysPortNum: "1", VFID: "9"},
+					{PhysPortNum: "10", VFID: "0"},
+				})
+				order := make([]string, 0, len(sorted))
+				for _, rep := range sorted {
+					order = append(order, rep.PhysPortNum+"/"+rep.VFID)
+				}
+				Expect(order).To(Equal([]string{"1/9", "1/10", "2/1", "10/0"}))
+			})
+
+			It("should re-identify the representor by port and VF with the new naming scheme", func() {
+				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{
+					&mockDirEntry{name: "eth5_0"}, &mockDirEntry{name: "eth5pf1vf0"},
+				}, nil)
+				osMock.On("ReadFile", "/sys/class/net/eth5_0/phys_switch_id").Return([]byte("00000000000000ab"), nil)
+				osMock.On("ReadFile", "/sys/class/net/eth5_0/phys_port_name").Return([]byte("0"), nil)
+				osMock.On("ReadFile", "/sys/class/net/eth5pf1vf0/phys_switch_id").Return([]byte("00000000000000ab"), nil)
+				osMock.On("ReadFile", "/sys/class/net/eth5pf1vf0/phys_port_name").Return([]byte("pf1vf0"), nil)
+
+				name, err := nc.findCurrentRepresentor(ctx, "00000000000000ab", "1", "0", true)
 				Expect(err).NotTo(HaveOccurred())
+				Expect(name).To(Equal("eth5pf1vf0"))
+			})
+
+			It("should not match a VF index only phys_port_name with the new naming scheme", func() {
+				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{&mockDirEntry{name: "eth5_0"}}, nil)
+				osMock.On("ReadFile", "/sys/class/net/eth5_0/phys_switch_id").Return([]byte("00000000000000ab"), nil)
+				osMock.On("ReadFile", "/sys/class/net/eth5_0/phys_port_name").Return([]byte("0"), nil)
+
+				_, err := nc.findCurrentRepresentor(ctx, "00000000000000ab", "1", "0", true)
+				Expect(err).To(MatchError("representor not found for VF ID 0"))
+			})
+
+			It("should restore representors by VF index with the old naming scheme", func() {
+				device := &MellanoxDevice{
+					PCIAddr:     "0000:08:00.0",
+					DevType:     devTypeEth,
+					EswitchMode: eswitchModeSwitchdev,
+					PfNumVfs:    2,
+					Representors: []Representor{
+						{PhysSwitchID: "00000000000000ab", VFID: "0", Name: "eth_rep0", AdminState: adminStateUp, MTU: 1500},
+					},
+				}
+
+				// The PF has no port number with the old naming scheme
+				osMock.On("ReadFile", "/sys/class/net/eth5/phys_switch_id").Return([]byte("00000000000000ab"), nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth5/phys_port_name").Return(nil, fmt.Errorf("operation not supported")).Once()
+				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{
+					&mockDirEntry{name: "eth5_1"}, &mockDirEntry{name: "eth5_0"},
+				}, nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth5_1/phys_switch_id").Return([]byte("00000000000000ab"), nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth5_1/phys_port_name").Return([]byte("1"), nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth5_0/phys_switch_id").Return([]byte("00000000000000ab"), nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth5_0/phys_port_name").Return([]byte("0"), nil).Once()
+
+				expectRename("eth5_0", "t00abpv0").Return(nil)
+				expectRename("t00abpv0", "eth_rep0").Return(nil)
+				link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth_rep0"}}
+				netlinkMock.On("LinkByName", "eth_rep0").Return(link, nil).Twice()
+				netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+				netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+
+				Expect(nc.restoreRepresentors(ctx, "eth5", device, false)).To(Succeed())
+			})
+
+			It("should rename the representors with the ip command when PreferIPCommand is set", func() {
+				nc.preferIPCommand = true
+				device := &MellanoxDevice{
+					PCIAddr:     "0000:08:00.0",
+					DevType:     devTypeEth,
+					EswitchMode: eswitchModeSwitchdev,
+					PfNumVfs:    1,
+					Representors: []Representor{
+						{PhysSwitchID: "00000000000000ab", VFID: "0", Name: "eth_rep0", AdminState: adminStateUp, MTU: 1500},
+					},
+				}
+
+				osMock.On("ReadFile", "/sys/class/net/eth5/phys_switch_id").Return([]byte("00000000000000ab"), nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth5/phys_port_name").Return(nil, fmt.Errorf("operation not supported")).Once()
+				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{&mockDirEntry{name: "eth5_0"}}, nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth5_0/phys_switch_id").Return([]byte("00000000000000ab"), nil).Once()
+				osMock.On("ReadFile", "/sys/class/net/eth5_0/phys_port_name").Return([]byte("0"), nil).Once()
+
+				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "eth5_0", "name", "t00abpv0").Return("", "", nil).Once()
+				cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "t00abpv0", "name", "eth_rep0").Return("", "", nil).Once()
+				link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth_rep0"}}
+				netlinkMock.On("LinkByName", "eth_rep0").Return(link, nil).Twice()
+				netlinkMock.On("LinkSetMTU", link, 1500).Return(nil).Once()
+				netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+
+				Expect(nc.restoreRepresentors(ctx, "eth5", device, false)).To(Succeed())
+				netlinkMock.AssertNotCalled(GinkgoT(), "LinkSetName", mock.Anything, mock.Anything)
 			})
 		})
 	})
 
+	Context("Summary", func() {
+		var nc *netconfig
+
+		BeforeEach(func() {
+			nc = New(cmdMockPkg.NewInterface(GinkgoT()), osMockPkg.NewOSWrapper(GinkgoT()), hostMockPkg.NewInterface(GinkgoT()),
+				sriovnetMockPkg.NewLib(GinkgoT()), netlinkMockPkg.NewLib(GinkgoT()), config.Config{BindDelaySec: 4}).(*netconfig)
+		})
+
+		It("should return an empty summary when no devices were saved", func() {
+			summary := nc.Summary()
+			Expect(summary.PFCount).To(Equal(0))
+			Expect(summary.VFCount).To(Equal(0))
+			Expect(summary.DevTypes).To(BeEmpty())
+			Expect(summary.PCIAddrs).To(BeEmpty())
+		})
+
+		It("should aggregate a mixed eth/ib device set", func() {
+			nc.mellanoxDevices["eth0"] = &MellanoxDevice{
+				PCIAddr: "0000:03:00.0", DevType: devTypeEth, EswitchMode: eswitchModeSwitchdev, PfNumVfs: 8,
+			}
+			nc.mellanoxDevices["eth1"] = &MellanoxDevice{
+				PCIAddr: "0000:03:00.1", DevType: devTypeEth, EswitchMode: eswitchModeLegacy, PfNumVfs: 0,
+			}
+			nc.mellanoxDevices["ib0"] = &MellanoxDevice{
+				PCIAddr: "0000:81:00.0", DevType: devTypeIB, EswitchMode: eswitchModeLegacy, PfNumVfs: 4,
+			}
+
+			summary := nc.Summary()
+			Expect(summary.PFCount).To(Equal(3))
+			Expect(summary.VFCount).To(Equal(12))
+			Expect(summary.DevTypes).To(Equal(map[string]int{devTypeEth: 2, devTypeIB: 1}))
+			Expect(summary.NumVFs).To(Equal(map[string]int{"eth0": 8, "eth1": 0, "ib0": 4}))
+			Expect(summary.EswitchModes).To(Equal(map[string]string{
+				"eth0": eswitchModeSwitchdev, "eth1": eswitchModeLegacy, "ib0": eswitchModeLegacy,
+			}))
+			Expect(summary.PCIAddrs).To(Equal(map[string]string{
+				"eth0": "0000:03:00.0", "eth1": "0000:03:00.1", "ib0": "0000:81:00.0",
+			}))
+		})
+	})
+
+	Context("waitForLink", func() {
+		var (
+			nc          *netconfig
+			netlinkMock *netlinkMockPkg.Lib
+		)
+
+		BeforeEach(func() {
+			netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
+			nc = New(cmdMockPkg.NewInterface(GinkgoT()), osMockPkg.NewOSWrapper(GinkgoT()), hostMockPkg.NewInterface(GinkgoT()),
+				sriovnetMockPkg.NewLib(GinkgoT()), netlinkMock, config.Config{NetlinkWaitTimeout: time.Second}).(*netconfig)
+		})
+
+		It("should retry until the link is registered after the driver reload", func() {
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+			mock.InOrder(
+				netlinkMock.On("LinkByName", "eth0").Return(nil, fmt.Errorf("Link not found")).Once(),
+				netlinkMock.On("LinkByName", "eth0").Return(link, nil).Once(),
+			)
+			netlinkMock.On("LinkSetMTU", link, 9000).Return(nil).Once()
+
+			Expect(nc.setDeviceMTU(context.Background(), "eth0", 9000)).To(Succeed())
+		})
+
+		It("should fail with a clear error when the link is never registered", func() {
+			netlinkMock.On("LinkByName", "eth0").Return(nil, fmt.Errorf("Link not found"))
+
+			_, err := nc.waitForLink(context.Background(), "eth0", 250*time.Millisecond)
+			Expect(err).To(MatchError(ContainSubstring("not registered within 250ms: Link not found")))
+			Expect(len(netlinkMock.Calls)).To(BeNumerically(">", 1))
+		})
+
+		It("should try once without a timeout", func() {
+			netlinkMock.On("LinkByName", "eth0").Return(nil, fmt.Errorf("Link not found")).Once()
+
+			_, err := nc.waitForLink(context.Background(), "eth0", 0)
+			Expect(err).To(MatchError("Link not found"))
+		})
+
+		It("should stop polling when the context deadline is hit", func() {
+			netlinkMock.On("LinkByName", "eth0").Return(nil, fmt.Errorf("Link not found"))
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			_, err := nc.waitForLink(ctx, "eth0", time.Minute)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(err).To(MatchError(ContainSubstring("link eth0 not registered")))
+		})
+	})
+
+	Context("waitForVFNetdev", func() {
+		var (
+			nc     *netconfig
+			osMock *osMockPkg.OSWrapper
+		)
+
+		BeforeEach(func() {
+			osMock = osMockPkg.NewOSWrapper(GinkgoT())
+			nc = New(cmdMockPkg.NewInterface(GinkgoT()), osMock, hostMockPkg.NewInterface(GinkgoT()),
+				sriovnetMockPkg.NewLib(GinkgoT()), netlinkMockPkg.NewLib(GinkgoT()),
+				config.Config{NetconfigPollInterval: 10 * time.Millisecond}).(*netconfig)
+		})
+
+		It("should retry until the VF netdev is registered", func() {
+			mock.InOrder(
+				osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.2/net").Return(nil, os.ErrNotExist).Once(),
+				osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.2/net").Return(
+					[]os.DirEntry{&mockDirEntry{name: "eth0v0"}}, nil).Once(),
+			)
+
+			name, err := nc.waitForVFNetdev(context.Background(), "0000:08:00.2", time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(Equal("eth0v0"))
+		})
+
+		It("should fail naming the VF when its netdev never appears", func() {
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.2/net").Return(nil, os.ErrNotExist)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			_, err := nc.waitForVFNetdev(ctx, "0000:08:00.2", time.Minute)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(err).To(MatchError(ContainSubstring("VF 0000:08:00.2 netdev not registered")))
+		})
+	})
+
+	Context("LAG", func() {
+		var (
+			nc           *netconfig
+			cmdMock      *cmdMockPkg.Interface
+			osMock       *osMockPkg.OSWrapper
+			hostMock     *hostMockPkg.Interface
+			sriovnetMock *sriovnetMockPkg.Lib
+			netlinkMock  *netlinkMockPkg.Lib
+			ctx          context.Context
+			bond         *mockLink
+		)
+
+		BeforeEach(func() {
+			cmdMock = cmdMockPkg.NewInterface(GinkgoT())
+			osMock = osMockPkg.NewOSWrapper(GinkgoT())
+			hostMock = hostMockPkg.NewInterface(GinkgoT())
+			sriovnetMock = sriovnetMockPkg.NewLib(GinkgoT())
+			netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{
+				NetlinkWaitTimeout:    time.Second,
+				NetconfigPollInterval: 10 * time.Millisecond,
+			}).(*netconfig)
+			ctx = context.Background()
+			bond = &mockLink{attrs: &netlink.LinkAttrs{Name: "bond0", Index: 10}}
+		})
+
+		// expectLAGMemberSave mocks the discovery of a legacy PF without VFs enslaved to bond0
+		expectLAGMemberSave := func(devName, pciAddr string) {
+			link := &mockLink{attrs: &netlink.LinkAttrs{Name: devName, Flags: net.FlagUp, MTU: 9000, MasterIndex: 10}}
+			osMock.On("ReadFile", "/sys/class/net/"+devName+"/device/vendor").Return([]byte("0x15b3"), nil).Once()
+			sriovnetMock.On("GetPciFromNetDevice", devName).Return(pciAddr, nil).Once()
+			netlinkMock.On("LinkByName", devName).Return(link, nil).Once()
+			netlinkMock.On("AddrList", link, netlinkPkg.FamilyAll).Return([]netlink.Addr{}, nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "eswitch", "show", "pci/"+pciAddr).
+				Return("pci/"+pciAddr+": mode legacy", "", nil).Once()
+			osMock.On("ReadFile", "/sys/class/net/"+devName+"/device/sriov_numvfs").Return([]byte("0"), nil).Once()
+			osMock.On("ReadDir", "/sys/class/net/"+devName+"/device/").Return([]os.DirEntry{}, nil).Once()
+			osMock.On("Readlink", "/sys/class/net/"+devName+"/master").Return("../../../virtual/net/bond0", nil).Once()
+			osMock.On("Stat", "/sys/class/net/bond0/bonding").Return(nil, nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "param", "show", "pci/"+pciAddr,
+				"name", "lag_port_select_mode").Return("pci/"+pciAddr+":\n  name lag_port_select_mode type driver-specific\n"+
+				"    values:\n      cmode runtime value hash\n", "", nil).Once()
+		}
+
+		// expectPFRestore mocks the restore of the admin state and MTU of a LAG member PF without VFs
+		expectPFRestore := func(devName, pciAddr string, link *mockLink, adminState string, mtu int) {
+			osMock.On("ReadDir", "/sys/bus/pci/devices/"+pciAddr+"/net").Return([]os.DirEntry{&mockDirEntry{name: devName}}, nil).Once()
+			netlinkMock.On("LinkByName", devName).Return(link, nil).Twice()
+			if adminState == adminStateUp {
+				netlinkMock.On("LinkSetUp", link).Return(nil).Once()
+			} else {
+				netlinkMock.On("LinkSetDown", link).Return(nil).Once()
+			}
+			netlinkMock.On("LinkSetMTU", link, mtu).Return(nil).Once()
+		}
+
+		lagMember := func(pciAddr string) *MellanoxDevice {
+			return &MellanoxDevice{
+				PCIAddr:           pciAddr,
+				DevType:           devTypeEth,
+				EswitchMode:       eswitchModeLegacy,
+				LAGMaster:         "bond0",
+				LAGPortSelectMode: "hash",
+			}
+		}
+
+		It("should save the LAG of two PFs and re-enslave both to the bond after the reload", func() {
+			hostMock.On("LsMod", mock.Anything).Return(map[string]host.LoadedModule{
+				"mlx5_core": {Name: "mlx5_core", RefCount: 1, UsedBy: []string{}},
+			}, nil).Once()
+			osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{
+				&mockDirEntry{name: "bond0"}, &mockDirEntry{name: "eth0"}, &mockDirEntry{name: "eth1"},
+			}, nil).Once()
+			osMock.On("ReadFile", "/sys/class/net/bond0/device/vendor").Return(nil, os.ErrNotExist).Once()
+			expectLAGMemberSave("eth0", "0000:08:00.0")
+			expectLAGMemberSave("eth1", "0000:08:00.1")
+
+			Expect(nc.Save(ctx)).To(Succeed())
+			Expect(nc.mellanoxDevices).To(HaveLen(2))
+			Expect(nc.mellanoxDevices["eth0"].LAGMaster).To(Equal("bond0"))
+			Expect(nc.mellanoxDevices["eth0"].LAGPortSelectMode).To(Equal("hash"))
+			Expect(nc.mellanoxDevices["eth1"].LAGMaster).To(Equal("bond0"))
+			Expect(nc.mellanoxDevices["eth1"].LAGPortSelectMode).To(Equal("hash"))
+
+			// The members come back from the reload without a master
+			eth0 := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+			eth1 := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth1"}}
+			expectPFRestore("eth0", "0000:08:00.0", eth0, adminStateUp, 9000)
+			expectPFRestore("eth1", "0000:08:00.1", eth1, adminStateUp, 9000)
+			netlinkMock.On("LinkByName", "bond0").Return(bond, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.1/net").Return([]os.DirEntry{&mockDirEntry{name: "eth1"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(eth0, nil).Once()
+			netlinkMock.On("LinkByName", "eth1").Return(eth1, nil).Once()
+			mock.InOrder(
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "param", "set", "pci/0000:08:00.0",
+					"name", "lag_port_select_mode", "value", "hash", "cmode", "runtime").Return("", "", nil).Once(),
+				cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "param", "set", "pci/0000:08:00.1",
+					"name", "lag_port_select_mode", "value", "hash", "cmode", "runtime").Return("", "", nil).Once(),
+				netlinkMock.On("LinkSetDown", eth0).Return(nil).Once(),
+				netlinkMock.On("LinkSetMaster", eth0, bond).Return(nil).Once(),
+				netlinkMock.On("LinkSetDown", eth1).Return(nil).Once(),
+				netlinkMock.On("LinkSetMaster", eth1, bond).Return(nil).Once(),
+			)
+
+			Expect(nc.Restore(ctx)).To(Succeed())
+		})
+
+		It("should not enslave any member before the netdevs of both members are registered", func() {
+			nc.mellanoxDevices["eth0"] = lagMember("0000:08:00.0")
+			nc.mellanoxDevices["eth1"] = lagMember("0000:08:00.1")
+			eth0 := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+			eth1 := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth1"}}
+			expectPFRestore("eth0", "0000:08:00.0", eth0, adminStateDown, 0)
+			expectPFRestore("eth1", "0000:08:00.1", eth1, adminStateDown, 0)
+
+			var events []string
+			record := func(event string) func(mock.Arguments) {
+				return func(mock.Arguments) { events = append(events, event) }
+			}
+			netlinkMock.On("LinkByName", "bond0").Return(bond, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.1/net").Return(nil, os.ErrNotExist).
+				Run(record("eth1 missing")).Twice()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.1/net").Return([]os.DirEntry{&mockDirEntry{name: "eth1"}}, nil).
+				Run(record("eth1 registered")).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(eth0, nil).Once()
+			netlinkMock.On("LinkByName", "eth1").Return(eth1, nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "param", "set", mock.Anything,
+				"name", "lag_port_select_mode", "value", "hash", "cmode", "runtime").Return("", "", nil).Twice()
+			netlinkMock.On("LinkSetDown", mock.Anything).Return(nil).Twice()
+			netlinkMock.On("LinkSetMaster", eth0, bond).Return(nil).Run(record("eth0 enslaved")).Once()
+			netlinkMock.On("LinkSetMaster", eth1, bond).Return(nil).Run(record("eth1 enslaved")).Once()
+
+			Expect(nc.Restore(ctx)).To(Succeed())
+			Expect(events).To(Equal([]string{"eth1 missing", "eth1 missing", "eth1 registered", "eth0 enslaved", "eth1 enslaved"}))
+		})
+
+		It("should not form a partial LAG when a member never comes back", func() {
+			nc.netlinkWaitTimeout = 50 * time.Millisecond
+			nc.mellanoxDevices["eth0"] = lagMember("0000:08:00.0")
+			nc.mellanoxDevices["eth1"] = lagMember("0000:08:00.1")
+			// eth1 fails its own restore too
+			expectPFRestore("eth0", "0000:08:00.0", &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}, adminStateDown, 0)
+
+			netlinkMock.On("LinkByName", "bond0").Return(bond, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.1/net").Return(nil, os.ErrNotExist)
+			netlinkMock.On("LinkByName", "eth0").Return(&mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}, nil).Once()
+
+			Expect(nc.Restore(ctx)).To(Succeed())
+			netlinkMock.AssertNotCalled(GinkgoT(), "LinkSetMaster", mock.Anything, mock.Anything)
+		})
+
+		It("should not save a bridge or an OVS master as a LAG", func() {
+			for _, master := range []string{"ovs-system", "br0"} {
+				osMock.On("Readlink", "/sys/class/net/eth0/master").Return("../../../virtual/net/"+master, nil).Once()
+				osMock.On("Stat", "/sys/class/net/"+master+"/bonding").Return(nil, os.ErrNotExist).Once()
+
+				device := &MellanoxDevice{}
+				nc.collectLAGInfo(ctx, "eth0", "0000:08:00.0", device)
+				Expect(device.LAGMaster).To(BeEmpty())
+			}
+			cmdMock.AssertNotCalled(GinkgoT(), "RunCommand", mock.Anything, "devlink", "dev", "param", "show",
+				"pci/0000:08:00.0", "name", "lag_port_select_mode")
+		})
+
+		It("should restore the admin state of a member and count the LAG as failed when the enslave fails", func() {
+			nc.strictRestore = true
+			nc.mellanoxDevices["eth0"] = lagMember("0000:08:00.0")
+			nc.mellanoxDevices["eth0"].AdminState = adminStateUp
+			eth0 := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+			expectPFRestore("eth0", "0000:08:00.0", eth0, adminStateUp, 0)
+
+			netlinkMock.On("LinkByName", "bond0").Return(bond, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(eth0, nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "param", "set", "pci/0000:08:00.0",
+				"name", "lag_port_select_mode", "value", "hash", "cmode", "runtime").Return("", "", nil).Once()
+			mock.InOrder(
+				netlinkMock.On("LinkSetDown", eth0).Return(nil).Once(),
+				netlinkMock.On("LinkSetMaster", eth0, bond).Return(errors.New("device or resource busy")).Once(),
+				netlinkMock.On("LinkSetUp", eth0).Return(nil).Once(),
+			)
+
+			Expect(nc.Restore(ctx)).To(MatchError("SRIOV configuration partially restored: 0 of 1 devices and 0 of 0 VFs failed" +
+				", 1 of 1 LAGs failed (failed LAGs: bond0)"))
+		})
+
+		It("should skip the members that are still enslaved to the bond", func() {
+			nc.mellanoxDevices["eth0"] = lagMember("0000:08:00.0")
+			nc.mellanoxDevices["eth1"] = lagMember("0000:08:00.1")
+			eth1 := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth1"}}
+			expectPFRestore("eth0", "0000:08:00.0", &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0", MasterIndex: 10}}, adminStateDown, 0)
+			expectPFRestore("eth1", "0000:08:00.1", eth1, adminStateDown, 0)
+
+			netlinkMock.On("LinkByName", "bond0").Return(bond, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.1/net").Return([]os.DirEntry{&mockDirEntry{name: "eth1"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(&mockLink{attrs: &netlink.LinkAttrs{Name: "eth0", MasterIndex: 10}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth1").Return(eth1, nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "param", "set", "pci/0000:08:00.1",
+				"name", "lag_port_select_mode", "value", "hash", "cmode", "runtime").Return("", "", nil).Once()
+			netlinkMock.On("LinkSetDown", eth1).Return(nil).Once()
+			netlinkMock.On("LinkSetMaster", eth1, bond).Return(nil).Once()
+
+			Expect(nc.Restore(ctx)).To(Succeed())
+		})
+
+		It("should enslave the members with the ip command with PreferIPCommand", func() {
+			nc.preferIPCommand = true
+			nc.mellanoxDevices["eth0"] = lagMember("0000:08:00.0")
+			eth0 := &mockLink{attrs: &netlink.LinkAttrs{Name: "eth0"}}
+			expectPFRestore("eth0", "0000:08:00.0", eth0, adminStateDown, 0)
+
+			netlinkMock.On("LinkByName", "bond0").Return(bond, nil).Once()
+			osMock.On("ReadDir", "/sys/bus/pci/devices/0000:08:00.0/net").Return([]os.DirEntry{&mockDirEntry{name: "eth0"}}, nil).Once()
+			netlinkMock.On("LinkByName", "eth0").Return(eth0, nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "devlink", "dev", "param", "set", "pci/0000:08:00.0",
+				"name", "lag_port_select_mode", "value", "hash", "cmode", "runtime").Return("", "", nil).Once()
+			netlinkMock.On("LinkSetDown", eth0).Return(nil).Once()
+			cmdMock.On("RunCommand", mock.Anything, "ip", "link", "set", "dev", "eth0", "master", "bond0").Return("", "", nil).Once()
+
+			Expect(nc.Restore(ctx)).To(Succeed())
+			netlinkMock.AssertNotCalled(GinkgoT(), "LinkSetMaster", mock.Anything, mock.Anything)
+		})
+	})
+
+	Context("ExportJSON", func() {
+		var (
+			nc     *netconfig
+			osMock *osMockPkg.OSWrapper
+			ctx    context.Context
+		)
+
+		newNetconfig := func(cfg config.Config) *netconfig {
+			return New(cmdMockPkg.NewInterface(GinkgoT()), osMock, hostMockPkg.NewInterface(GinkgoT()),
+				sriovnetMockPkg.NewLib(GinkgoT()), netlinkMockPkg.NewLib(GinkgoT()), cfg).(*netconfig)
+		}
+
+		// switchdevDevice returns a device whose VFs and representors are in the given order
+		switchdevDevice := func(vfs []VF, representors []Representor) *MellanoxDevice {
+			return &MellanoxDevice{
+				PCIAddr: "0000:03:00.0", DevType: devTypeEth, AdminState: adminStateUp, MTU: 9000, GUID: "-",
+				EswitchMode: eswitchModeSwitchdev, IPAddrs: []string{"192.168.1.10/24"}, PfNumVfs: 2,
+				VFs: vfs, Representors: representors,
+			}
+		}
+
+		vf0 := VF{VFIndex: 0, VFPCIAddr: "0000:03:00.2", VFName: "eth2", AdminState: adminStateUp,
+			MACAddress: "aa:bb:cc:dd:ee:01", AdminMAC: "aa:bb:cc:dd:ee:01", MTU: 1500, GUID: "-"}
+		vf1 := VF{VFIndex: 1, VFPCIAddr: "0000:03:00.3", VFName: "eth3", AdminState: adminStateDown,
+			MACAddress: "aa:bb:cc:dd:ee:02", AdminMAC: "aa:bb:cc:dd:ee:02", MTU: 1500, GUID: "-"}
+		rep0 := Representor{PhysSwitchID: "00000000000000ab", PhysPortNum: "0", VFID: "0", Name: "eth_rep0",
+			AdminState: adminStateUp, MTU: 1500}
+		rep1 := Representor{PhysSwitchID: "00000000000000ab", PhysPortNum: "0", VFID: "1", Name: "eth_rep1",
+			AdminState: adminStateDown, MTU: 1500}
+
+		BeforeEach(func() {
+			osMock = osMockPkg.NewOSWrapper(GinkgoT())
+			nc = newNetconfig(config.Config{})
+			ctx = context.Background()
+		})
+
+		It("should export the same JSON for the same devices regardless of the discovery order", func() {
+			nc.mellanoxDevices["eth0"] = switchdevDevice([]VF{vf0, vf1}, []Representor{rep0, rep1})
+			nc.mellanoxDevices["ib0"] = &MellanoxDevice{PCIAddr: "0000:81:00.0", DevType: devTypeIB, GUID: "-"}
+			other := newNetconfig(config.Config{})
+			other.mellanoxDevices["ib0"] = &MellanoxDevice{PCIAddr: "0000:81:00.0", DevType: devTypeIB, GUID: "-"}
+			other.mellanoxDevices["eth0"] = switchdevDevice([]VF{vf1, vf0}, []Representor{rep1, rep0})
+
+			first, err := nc.ExportJSON(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := nc.ExportJSON(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			reordered, err := other.ExportJSON(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(second).To(Equal(first))
+			Expect(reordered).To(Equal(first))
+			// The saved state is not reordered by the export
+			Expect(other.mellanoxDevices["eth0"].VFs).To(Equal([]VF{vf1, vf0}))
+		})
+
+		It("should include the VF and representor details", func() {
+			nc.mellanoxDevices["eth0"] = switchdevDevice([]VF{vf1, vf0}, []Representor{rep1, rep0})
+
+			data, err := nc.ExportJSON(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			var exported map[string]MellanoxDevice
+			Expect(json.Unmarshal(data, &exported)).To(Succeed())
+			Expect(exported).To(Equal(map[string]MellanoxDevice{
+				"eth0": *switchdevDevice([]VF{vf0, vf1}, []Representor{rep0, rep1}),
+			}))
+		})
+
+		It("should write the export to NetconfigExportPath", func() {
+			nc = newNetconfig(config.Config{NetconfigExportPath: "/run/netconfig.json"})
+			nc.mellanoxDevices["eth0"] = switchdevDevice([]VF{vf0}, nil)
+			expected, err := nc.ExportJSON(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			osMock.On("WriteFile", "/run/netconfig.json", expected, os.FileMode(0o644)).Return(nil).Once()
+
+			Expect(nc.writeExport(ctx)).To(Succeed())
+		})
+	})
+
 	Context("DevicesUseNewNamingScheme", func() {
 		var (
 			nc           *netconfig
@@ -940,7 +2625,7 @@ var _ = Describe("Netconfig", func() {
 			hostMock = hostMockPkg.NewInterface(GinkgoT())
 			sriovnetMock = sriovnetMockPkg.NewLib(GinkgoT())
 			netlinkMock = netlinkMockPkg.NewLib(GinkgoT())
-			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, 4).(*netconfig)
+			nc = New(cmdMock, osMock, hostMock, sriovnetMock, netlinkMock, config.Config{BindDelaySec: 4}).(*netconfig)
 			ctx = context.Background()
 		})
 		It("should return true when device uses new naming scheme (np suffix)", func() {
@@ -1091,6 +2776,42 @@ var _ = Describe("Netconfig", func() {
 				Expect(result).To(Equal(tc.expected), "NetNamePath: %s should return %v", tc.netNamePath, tc.expected)
 			}
 		})
+
+		Context("representorsUseNewNamingScheme", func() {
+			switchdevDevice := func() *MellanoxDevice {
+				return &MellanoxDevice{
+					EswitchMode:  eswitchModeSwitchdev,
+					Representors: []Representor{{PhysSwitchID: "00000000000000ab", VFID: "0", Name: "eth_rep0"}},
+				}
+			}
+
+			It("should detect the naming scheme once for all the switchdev devices", func() {
+				nc.mellanoxDevices["eth0"] = switchdevDevice()
+				nc.mellanoxDevices["eth1"] = switchdevDevice()
+				osMock.On("ReadDir", "/sys/class/net/").Return([]os.DirEntry{}, nil).Once()
+
+				Expect(nc.representorsUseNewNamingScheme(ctx)).To(BeFalse())
+			})
+
+			It("should skip the detection when no device has representors to restore", func() {
+				nc.mellanoxDevices["eth0"] = &MellanoxDevice{EswitchMode: eswitchModeLegacy}
+
+				Expect(nc.representorsUseNewNamingScheme(ctx)).To(BeTrue())
+				osMock.AssertNotCalled(GinkgoT(), "ReadDir", mock.Anything)
+			})
+
+			It("should warn and assume the new naming scheme when the detection fails", func() {
+				var logs []string
+				logCtx := logr.NewContext(ctx, funcr.New(func(_, args string) {
+					logs = append(logs, args)
+				}, funcr.Options{}))
+				nc.mellanoxDevices["eth0"] = switchdevDevice()
+				osMock.On("ReadDir", "/sys/class/net/").Return(nil, fmt.Errorf("permission denied")).Once()
+
+				Expect(nc.representorsUseNewNamingScheme(logCtx)).To(BeTrue())
+				Expect(logs).To(ContainElement(ContainSubstring("[WARN] Failed to detect the naming scheme")))
+			})
+		})
 	})
 })
 
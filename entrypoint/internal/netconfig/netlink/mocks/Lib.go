@@ -24,6 +24,112 @@ func (_m *Lib) EXPECT() *Lib_Expecter {
 	return &Lib_Expecter{mock: &_m.Mock}
 }
 
+// AddrAdd provides a mock function with given fields: link, addr
+func (_m *Lib) AddrAdd(link netlink.Link, addr *vishvanandanetlink.Addr) error {
+	ret := _m.Called(link, addr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddrAdd")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, *vishvanandanetlink.Addr) error); ok {
+		r0 = rf(link, addr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Lib_AddrAdd_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddrAdd'
+type Lib_AddrAdd_Call struct {
+	*mock.Call
+}
+
+// AddrAdd is a helper method to define mock.On call
+//   - link netlink.Link
+//   - addr *vishvanandanetlink.Addr
+func (_e *Lib_Expecter) AddrAdd(link interface{}, addr interface{}) *Lib_AddrAdd_Call {
+	return &Lib_AddrAdd_Call{Call: _e.mock.On("AddrAdd", link, addr)}
+}
+
+func (_c *Lib_AddrAdd_Call) Run(run func(link netlink.Link, addr *vishvanandanetlink.Addr)) *Lib_AddrAdd_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(netlink.Link), args[1].(*vishvanandanetlink.Addr))
+	})
+	return _c
+}
+
+func (_c *Lib_AddrAdd_Call) Return(_a0 error) *Lib_AddrAdd_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Lib_AddrAdd_Call) RunAndReturn(run func(netlink.Link, *vishvanandanetlink.Addr) error) *Lib_AddrAdd_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddrList provides a mock function with given fields: link, family
+func (_m *Lib) AddrList(link netlink.Link, family int) ([]vishvanandanetlink.Addr, error) {
+	ret := _m.Called(link, family)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddrList")
+	}
+
+	var r0 []vishvanandanetlink.Addr
+	var r1 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) ([]vishvanandanetlink.Addr, error)); ok {
+		return rf(link, family)
+	}
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) []vishvanandanetlink.Addr); ok {
+		r0 = rf(link, family)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]vishvanandanetlink.Addr)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(netlink.Link, int) error); ok {
+		r1 = rf(link, family)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Lib_AddrList_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddrList'
+type Lib_AddrList_Call struct {
+	*mock.Call
+}
+
+// AddrList is a helper method to define mock.On call
+//   - link netlink.Link
+//   - family int
+func (_e *Lib_Expecter) AddrList(link interface{}, family interface{}) *Lib_AddrList_Call {
+	return &Lib_AddrList_Call{Call: _e.mock.On("AddrList", link, family)}
+}
+
+func (_c *Lib_AddrList_Call) Run(run func(link netlink.Link, family int)) *Lib_AddrList_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(netlink.Link), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Lib_AddrList_Call) Return(_a0 []vishvanandanetlink.Addr, _a1 error) *Lib_AddrList_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Lib_AddrList_Call) RunAndReturn(run func(netlink.Link, int) ([]vishvanandanetlink.Addr, error)) *Lib_AddrList_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetLink provides a mock function with given fields: link
 func (_m *Lib) GetLink(link netlink.Link) vishvanandanetlink.Link {
 	ret := _m.Called(link)
@@ -270,6 +376,100 @@ func (_c *Lib_LinkSetMTU_Call) RunAndReturn(run func(netlink.Link, int) error) *
 	return _c
 }
 
+// LinkSetMaster provides a mock function with given fields: link, master
+func (_m *Lib) LinkSetMaster(link netlink.Link, master netlink.Link) error {
+	ret := _m.Called(link, master)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LinkSetMaster")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, netlink.Link) error); ok {
+		r0 = rf(link, master)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Lib_LinkSetMaster_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LinkSetMaster'
+type Lib_LinkSetMaster_Call struct {
+	*mock.Call
+}
+
+// LinkSetMaster is a helper method to define mock.On call
+//   - link netlink.Link
+//   - master netlink.Link
+func (_e *Lib_Expecter) LinkSetMaster(link interface{}, master interface{}) *Lib_LinkSetMaster_Call {
+	return &Lib_LinkSetMaster_Call{Call: _e.mock.On("LinkSetMaster", link, master)}
+}
+
+func (_c *Lib_LinkSetMaster_Call) Run(run func(link netlink.Link, master netlink.Link)) *Lib_LinkSetMaster_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(netlink.Link), args[1].(netlink.Link))
+	})
+	return _c
+}
+
+func (_c *Lib_LinkSetMaster_Call) Return(_a0 error) *Lib_LinkSetMaster_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Lib_LinkSetMaster_Call) RunAndReturn(run func(netlink.Link, netlink.Link) error) *Lib_LinkSetMaster_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LinkSetName provides a mock function with given fields: link, name
+func (_m *Lib) LinkSetName(link netlink.Link, name string) error {
+	ret := _m.Called(link, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LinkSetName")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, string) error); ok {
+		r0 = rf(link, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Lib_LinkSetName_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LinkSetName'
+type Lib_LinkSetName_Call struct {
+	*mock.Call
+}
+
+// LinkSetName is a helper method to define mock.On call
+//   - link netlink.Link
+//   - name string
+func (_e *Lib_Expecter) LinkSetName(link interface{}, name interface{}) *Lib_LinkSetName_Call {
+	return &Lib_LinkSetName_Call{Call: _e.mock.On("LinkSetName", link, name)}
+}
+
+func (_c *Lib_LinkSetName_Call) Run(run func(link netlink.Link, name string)) *Lib_LinkSetName_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(netlink.Link), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Lib_LinkSetName_Call) Return(_a0 error) *Lib_LinkSetName_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Lib_LinkSetName_Call) RunAndReturn(run func(netlink.Link, string) error) *Lib_LinkSetName_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // LinkSetUp provides a mock function with given fields: link
 func (_m *Lib) LinkSetUp(link netlink.Link) error {
 	ret := _m.Called(link)
@@ -316,6 +516,150 @@ func (_c *Lib_LinkSetUp_Call) RunAndReturn(run func(netlink.Link) error) *Lib_Li
 	return _c
 }
 
+// LinkSetVfHardwareAddr provides a mock function with given fields: link, vf, hwaddr
+func (_m *Lib) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
+	ret := _m.Called(link, vf, hwaddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LinkSetVfHardwareAddr")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, net.HardwareAddr) error); ok {
+		r0 = rf(link, vf, hwaddr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Lib_LinkSetVfHardwareAddr_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LinkSetVfHardwareAddr'
+type Lib_LinkSetVfHardwareAddr_Call struct {
+	*mock.Call
+}
+
+// LinkSetVfHardwareAddr is a helper method to define mock.On call
+//   - link netlink.Link
+//   - vf int
+//   - hwaddr net.HardwareAddr
+func (_e *Lib_Expecter) LinkSetVfHardwareAddr(link interface{}, vf interface{}, hwaddr interface{}) *Lib_LinkSetVfHardwareAddr_Call {
+	return &Lib_LinkSetVfHardwareAddr_Call{Call: _e.mock.On("LinkSetVfHardwareAddr", link, vf, hwaddr)}
+}
+
+func (_c *Lib_LinkSetVfHardwareAddr_Call) Run(run func(link netlink.Link, vf int, hwaddr net.HardwareAddr)) *Lib_LinkSetVfHardwareAddr_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(netlink.Link), args[1].(int), args[2].(net.HardwareAddr))
+	})
+	return _c
+}
+
+func (_c *Lib_LinkSetVfHardwareAddr_Call) Return(_a0 error) *Lib_LinkSetVfHardwareAddr_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Lib_LinkSetVfHardwareAddr_Call) RunAndReturn(run func(netlink.Link, int, net.HardwareAddr) error) *Lib_LinkSetVfHardwareAddr_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LinkSetVfNodeGUID provides a mock function with given fields: link, vf, nodeguid
+func (_m *Lib) LinkSetVfNodeGUID(link netlink.Link, vf int, nodeguid net.HardwareAddr) error {
+	ret := _m.Called(link, vf, nodeguid)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LinkSetVfNodeGUID")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, net.HardwareAddr) error); ok {
+		r0 = rf(link, vf, nodeguid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Lib_LinkSetVfNodeGUID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LinkSetVfNodeGUID'
+type Lib_LinkSetVfNodeGUID_Call struct {
+	*mock.Call
+}
+
+// LinkSetVfNodeGUID is a helper method to define mock.On call
+//   - link netlink.Link
+//   - vf int
+//   - nodeguid net.HardwareAddr
+func (_e *Lib_Expecter) LinkSetVfNodeGUID(link interface{}, vf interface{}, nodeguid interface{}) *Lib_LinkSetVfNodeGUID_Call {
+	return &Lib_LinkSetVfNodeGUID_Call{Call: _e.mock.On("LinkSetVfNodeGUID", link, vf, nodeguid)}
+}
+
+func (_c *Lib_LinkSetVfNodeGUID_Call) Run(run func(link netlink.Link, vf int, nodeguid net.HardwareAddr)) *Lib_LinkSetVfNodeGUID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(netlink.Link), args[1].(int), args[2].(net.HardwareAddr))
+	})
+	return _c
+}
+
+func (_c *Lib_LinkSetVfNodeGUID_Call) Return(_a0 error) *Lib_LinkSetVfNodeGUID_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Lib_LinkSetVfNodeGUID_Call) RunAndReturn(run func(netlink.Link, int, net.HardwareAddr) error) *Lib_LinkSetVfNodeGUID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LinkSetVfPortGUID provides a mock function with given fields: link, vf, portguid
+func (_m *Lib) LinkSetVfPortGUID(link netlink.Link, vf int, portguid net.HardwareAddr) error {
+	ret := _m.Called(link, vf, portguid)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LinkSetVfPortGUID")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, net.HardwareAddr) error); ok {
+		r0 = rf(link, vf, portguid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Lib_LinkSetVfPortGUID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LinkSetVfPortGUID'
+type Lib_LinkSetVfPortGUID_Call struct {
+	*mock.Call
+}
+
+// LinkSetVfPortGUID is a helper method to define mock.On call
+//   - link netlink.Link
+//   - vf int
+//   - portguid net.HardwareAddr
+func (_e *Lib_Expecter) LinkSetVfPortGUID(link interface{}, vf interface{}, portguid interface{}) *Lib_LinkSetVfPortGUID_Call {
+	return &Lib_LinkSetVfPortGUID_Call{Call: _e.mock.On("LinkSetVfPortGUID", link, vf, portguid)}
+}
+
+func (_c *Lib_LinkSetVfPortGUID_Call) Run(run func(link netlink.Link, vf int, portguid net.HardwareAddr)) *Lib_LinkSetVfPortGUID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(netlink.Link), args[1].(int), args[2].(net.HardwareAddr))
+	})
+	return _c
+}
+
+func (_c *Lib_LinkSetVfPortGUID_Call) Return(_a0 error) *Lib_LinkSetVfPortGUID_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Lib_LinkSetVfPortGUID_Call) RunAndReturn(run func(netlink.Link, int, net.HardwareAddr) error) *Lib_LinkSetVfPortGUID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewLib creates a new instance of Lib. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewLib(t interface {
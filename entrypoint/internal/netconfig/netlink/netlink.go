@@ -17,7 +17,9 @@
 package netlink
 
 import (
+	"math"
 	"net"
+	"syscall"
 
 	"github.com/vishvananda/netlink"
 )
@@ -30,6 +32,19 @@ type Link interface {
 	netlink.Link
 }
 
+// Addr is an IP address assigned to a link
+type Addr = netlink.Addr
+
+// FamilyAll selects both IPv4 and IPv6 addresses in AddrList
+const FamilyAll = netlink.FAMILY_ALL
+
+// AddrFlagPermanent is set on the addresses configured without a lifetime, the addresses
+// leased by DHCP or autoconfigured by SLAAC don't have it
+const AddrFlagPermanent = syscall.IFA_F_PERMANENT
+
+// InfiniteLifetime is the ValidLft of an address that never expires
+const InfiniteLifetime = math.MaxUint32
+
 type Lib interface {
 	// LinkByName finds a link by name and returns a pointer to the object.
 	LinkByName(name string) (Link, error)
@@ -42,8 +57,29 @@ type Lib interface {
 	// LinkSetMTU sets the mtu of the link device.
 	// Equivalent to: `ip link set $link mtu $mtu`
 	LinkSetMTU(link Link, mtu int) error
+	// LinkSetMaster sets the master of the link device.
+	// Equivalent to: `ip link set $link master $master`
+	LinkSetMaster(link Link, master Link) error
+	// LinkSetName sets the name of the link device.
+	// Equivalent to: `ip link set $link name $name`
+	LinkSetName(link Link, name string) error
 	// LinkSetHardwareAddr sets the hardware address of a link.
 	LinkSetHardwareAddr(link Link, hwaddr net.HardwareAddr) error
+	// LinkSetVfHardwareAddr sets the hardware address of a VF of the link device.
+	// Equivalent to: `ip link set $link vf $vf mac $hwaddr`
+	LinkSetVfHardwareAddr(link Link, vf int, hwaddr net.HardwareAddr) error
+	// LinkSetVfNodeGUID sets the node GUID of a VF of the link device.
+	// Equivalent to: `ip link set $link vf $vf node_guid $nodeguid`
+	LinkSetVfNodeGUID(link Link, vf int, nodeguid net.HardwareAddr) error
+	// LinkSetVfPortGUID sets the port GUID of a VF of the link device.
+	// Equivalent to: `ip link set $link vf $vf port_guid $portguid`
+	LinkSetVfPortGUID(link Link, vf int, portguid net.HardwareAddr) error
+	// AddrList gets a list of IP addresses assigned to the link.
+	// Equivalent to: `ip addr show dev $link`
+	AddrList(link Link, family int) ([]netlink.Addr, error)
+	// AddrAdd adds an IP address to the link.
+	// Equivalent to: `ip addr add $addr dev $link`
+	AddrAdd(link Link, addr *netlink.Addr) error
 	// GetLink returns the underlying netlink.Link from a Link interface
 	GetLink(link Link) netlink.Link
 }
@@ -73,11 +109,53 @@ func (w *libWrapper) LinkSetMTU(link Link, mtu int) error {
 	return netlink.LinkSetMTU(link, mtu)
 }
 
+// LinkSetMaster sets the master of the link device.
+// Equivalent to: `ip link set $link master $master`
+func (w *libWrapper) LinkSetMaster(link Link, master Link) error {
+	return netlink.LinkSetMaster(link, master)
+}
+
+// LinkSetName sets the name of the link device.
+// Equivalent to: `ip link set $link name $name`
+func (w *libWrapper) LinkSetName(link Link, name string) error {
+	return netlink.LinkSetName(link, name)
+}
+
 // LinkSetHardwareAddr sets the hardware address of a link.
 func (w *libWrapper) LinkSetHardwareAddr(link Link, hwaddr net.HardwareAddr) error {
 	return netlink.LinkSetHardwareAddr(link, hwaddr)
 }
 
+// LinkSetVfHardwareAddr sets the hardware address of a VF of the link device.
+// Equivalent to: `ip link set $link vf $vf mac $hwaddr`
+func (w *libWrapper) LinkSetVfHardwareAddr(link Link, vf int, hwaddr net.HardwareAddr) error {
+	return netlink.LinkSetVfHardwareAddr(link, vf, hwaddr)
+}
+
+// LinkSetVfNodeGUID sets the node GUID of a VF of the link device.
+// Equivalent to: `ip link set $link vf $vf node_guid $nodeguid`
+func (w *libWrapper) LinkSetVfNodeGUID(link Link, vf int, nodeguid net.HardwareAddr) error {
+	return netlink.LinkSetVfNodeGUID(link, vf, nodeguid)
+}
+
+// LinkSetVfPortGUID sets the port GUID of a VF of the link device.
+// Equivalent to: `ip link set $link vf $vf port_guid $portguid`
+func (w *libWrapper) LinkSetVfPortGUID(link Link, vf int, portguid net.HardwareAddr) error {
+	return netlink.LinkSetVfPortGUID(link, vf, portguid)
+}
+
+// AddrList gets a list of IP addresses assigned to the link.
+// Equivalent to: `ip addr show dev $link`
+func (w *libWrapper) AddrList(link Link, family int) ([]netlink.Addr, error) {
+	return netlink.AddrList(link, family)
+}
+
+// AddrAdd adds an IP address to the link.
+// Equivalent to: `ip addr add $addr dev $link`
+func (w *libWrapper) AddrAdd(link Link, addr *netlink.Addr) error {
+	return netlink.AddrAdd(link, addr)
+}
+
 // GetLink returns the underlying netlink.Link from a Link interface
 func (w *libWrapper) GetLink(link Link) netlink.Link {
 	return link
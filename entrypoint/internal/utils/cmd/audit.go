@@ -0,0 +1,153 @@
+/*
+ Copyright 2025, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers"
+)
+
+// ErrCommandNotAllowed is returned by RunCommand when the command binary is not in the allow-list.
+type ErrCommandNotAllowed struct {
+	Command string
+}
+
+func (e *ErrCommandNotAllowed) Error() string {
+	return fmt.Sprintf("command %q is not in the command allow-list", e.Command)
+}
+
+// AuditRecord is a single line of the command audit log
+type AuditRecord struct {
+	Time       string   `json:"time"`
+	Argv       []string `json:"argv"`
+	ExitCode   int      `json:"exitCode"` // -1 if the command didn't run
+	DurationMs int64    `json:"durationMs"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// NewAudited wraps c to append an AuditRecord per command to auditLogFile and to refuse to run
+// binaries that are not in allowList. Each feature is disabled when its argument is empty;
+// c is returned as is when both are disabled.
+func NewAudited(c Interface, osWrapper wrappers.OSWrapper, auditLogFile string, allowList []string) Interface {
+	if auditLogFile == "" && len(allowList) == 0 {
+		return c
+	}
+	a := &auditedCmd{
+		cmd:          c,
+		os:           osWrapper,
+		auditLogFile: auditLogFile,
+	}
+	if len(allowList) > 0 {
+		a.allowList = make(map[string]struct{}, len(allowList))
+		for _, binary := range allowList {
+			a.allowList[filepath.Base(binary)] = struct{}{}
+		}
+	}
+	return a
+}
+
+type auditedCmd struct {
+	cmd          Interface
+	os           wrappers.OSWrapper
+	auditLogFile string
+	allowList    map[string]struct{} // nil means all commands are allowed
+
+	// serializes writes to the audit log, commands may run concurrently
+	mu sync.Mutex
+}
+
+// RunCommand checks the allow-list, runs the command and records it in the audit log.
+func (a *auditedCmd) RunCommand(ctx context.Context, command string, args ...string) (string, string, error) {
+	return a.RunCommandWithEnv(ctx, nil, command, args...)
+}
+
+// RunCommandWithEnv checks the allow-list, runs the command with env and records it in the audit log.
+func (a *auditedCmd) RunCommandWithEnv(ctx context.Context, env map[string]string, command string, args ...string) (string, string, error) {
+	start := time.Now()
+	if a.allowList != nil {
+		if _, ok := a.allowList[filepath.Base(command)]; !ok {
+			err := &ErrCommandNotAllowed{Command: command}
+			a.audit(ctx, start, command, args, err)
+			return "", "", err
+		}
+	}
+	stdout, stderr, err := a.cmd.RunCommandWithEnv(ctx, env, command, args...)
+	a.audit(ctx, start, command, args, err)
+	return stdout, stderr, err
+}
+
+// NotFound checks if the error is "command not found" error.
+func (a *auditedCmd) NotFound(err error) bool {
+	return a.cmd.NotFound(err)
+}
+
+// audit appends a record for the command to the audit log, failures are only logged
+func (a *auditedCmd) audit(ctx context.Context, start time.Time, command string, args []string, runErr error) {
+	if a.auditLogFile == "" {
+		return
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	record := AuditRecord{
+		Time:       start.UTC().Format(time.RFC3339Nano),
+		Argv:       append([]string{command}, args...),
+		ExitCode:   exitCode(runErr),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if runErr != nil {
+		record.Error = runErr.Error()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.V(1).Info("failed to marshal audit record", "error", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := a.os.OpenFile(a.auditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Info("[WARN] failed to open command audit log", "path", a.auditLogFile, "error", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Info("[WARN] failed to write command audit log", "path", a.auditLogFile, "error", err)
+	}
+}
+
+// exitCode returns the exit code of a command based on the error returned by RunCommand
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var failedErr *ErrCommandFailed
+	if errors.As(err, &failedErr) {
+		return failedErr.ExitCode
+	}
+	return -1
+}
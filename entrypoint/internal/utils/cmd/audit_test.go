@@ -0,0 +1,134 @@
+/*
+ Copyright 2025, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers"
+)
+
+var _ = Describe("Audited Cmd", func() {
+	var (
+		ctx          context.Context
+		tempDir      string
+		auditLogFile string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		tempDir = GinkgoT().TempDir()
+		auditLogFile = filepath.Join(tempDir, "audit.log")
+	})
+
+	readAuditRecords := func() []AuditRecord {
+		data, err := os.ReadFile(auditLogFile)
+		Expect(err).NotTo(HaveOccurred())
+		var records []AuditRecord
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var record AuditRecord
+			Expect(json.Unmarshal([]byte(line), &record)).To(Succeed())
+			records = append(records, record)
+		}
+		return records
+	}
+
+	It("should return the wrapped implementation when auditing and allow-list are disabled", func() {
+		inner := New()
+		Expect(NewAudited(inner, wrappers.NewOS(), "", nil)).To(BeIdenticalTo(inner))
+	})
+
+	It("should append an audit line per executed command", func() {
+		c := NewAudited(New(), wrappers.NewOS(), auditLogFile, nil)
+
+		stdout, _, err := c.RunCommand(ctx, "sh", "-c", "echo hello")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(Equal("hello\n"))
+		_, _, err = c.RunCommand(ctx, "sh", "-c", "exit 3")
+		Expect(err).To(HaveOccurred())
+
+		records := readAuditRecords()
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].Argv).To(Equal([]string{"sh", "-c", "echo hello"}))
+		Expect(records[0].ExitCode).To(Equal(0))
+		Expect(records[0].Error).To(BeEmpty())
+		Expect(records[0].Time).NotTo(BeEmpty())
+		Expect(records[0].DurationMs).To(BeNumerically(">=", 0))
+		Expect(records[1].Argv).To(Equal([]string{"sh", "-c", "exit 3"}))
+		Expect(records[1].ExitCode).To(Equal(3))
+		Expect(records[1].Error).NotTo(BeEmpty())
+	})
+
+	It("should reject a binary that is not in the allow-list", func() {
+		c := NewAudited(New(), wrappers.NewOS(), auditLogFile, []string{"sh", "/usr/bin/modprobe"})
+		marker := filepath.Join(tempDir, "marker")
+
+		_, _, err := c.RunCommand(ctx, "touch", marker)
+		Expect(err).To(HaveOccurred())
+		var notAllowedErr *ErrCommandNotAllowed
+		Expect(errors.As(err, &notAllowedErr)).To(BeTrue())
+		Expect(notAllowedErr.Command).To(Equal("touch"))
+		_, statErr := os.Stat(marker)
+		Expect(os.IsNotExist(statErr)).To(BeTrue(), "disallowed command must not run")
+
+		// Allowed binaries still run, matched by base name
+		_, _, err = c.RunCommand(ctx, "/bin/sh", "-c", "true")
+		Expect(err).NotTo(HaveOccurred())
+
+		records := readAuditRecords()
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].Argv).To(Equal([]string{"touch", marker}))
+		Expect(records[0].ExitCode).To(Equal(-1))
+		Expect(records[0].Error).To(ContainSubstring("allow-list"))
+		Expect(records[1].ExitCode).To(Equal(0))
+	})
+
+	It("should enforce the allow-list without an audit log", func() {
+		c := NewAudited(New(), wrappers.NewOS(), "", []string{"sh"})
+
+		_, _, err := c.RunCommand(ctx, "true")
+		Expect(err).To(MatchError(ContainSubstring(`command "true" is not in the command allow-list`)))
+		Expect(c.NotFound(err)).To(BeFalse())
+		_, err = os.Stat(auditLogFile)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should build the audited command helper from the config", func() {
+		c := NewFromConfig(config.Config{
+			CommandEnv:       map[string]string{"AUDIT_TEST": "from-config"},
+			AuditLogFile:     auditLogFile,
+			CommandAllowList: []string{"sh"},
+		}, wrappers.NewOS())
+
+		stdout, _, err := c.RunCommand(ctx, "sh", "-c", "echo $AUDIT_TEST")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(Equal("from-config\n"))
+		_, _, err = c.RunCommand(ctx, "true")
+		Expect(err).To(MatchError(ContainSubstring(`command "true" is not in the command allow-list`)))
+
+		Expect(readAuditRecords()).To(HaveLen(2))
+	})
+})
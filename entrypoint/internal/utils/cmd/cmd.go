@@ -19,12 +19,19 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"syscall"
 
 	"github.com/go-logr/logr"
+
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers"
 )
 
 // New initialize default implementation of the cmd.Interface.
@@ -32,15 +39,116 @@ func New() Interface {
 	return &cmd{}
 }
 
+// NewWithOutputLimit initialize default implementation of the cmd.Interface which keeps at most
+// maxOutputBytes of the stdout and of the stderr of a command, zero keeps the whole output.
+func NewWithOutputLimit(maxOutputBytes int) Interface {
+	return &cmd{maxOutputBytes: maxOutputBytes}
+}
+
+// NewWithEnv initialize default implementation of the cmd.Interface like NewWithOutputLimit,
+// env is merged into the environment of every command, e.g. LC_ALL=C or MAKEFLAGS=-j8.
+func NewWithEnv(maxOutputBytes int, env map[string]string) Interface {
+	return &cmd{maxOutputBytes: maxOutputBytes, env: env}
+}
+
+// NewFromConfig initialize the cmd.Interface used by the entrypoint: the commands get the
+// MaxCommandOutputBytes limit and the CommandEnv environment, and are audited with
+// AuditLogFile and CommandAllowList.
+func NewFromConfig(cfg config.Config, osWrapper wrappers.OSWrapper) Interface {
+	return NewAudited(NewWithEnv(cfg.MaxCommandOutputBytes, cfg.CommandEnv), osWrapper, cfg.AuditLogFile, cfg.CommandAllowList)
+}
+
 // Interface is the interface exposed by the cmd package.
 type Interface interface {
 	// RunCommand runs a command.
 	RunCommand(ctx context.Context, command string, args ...string) (string, string, error)
+	// RunCommandWithEnv runs a command with env merged into its environment, env takes
+	// precedence over the environment configured for all commands.
+	RunCommandWithEnv(ctx context.Context, env map[string]string, command string, args ...string) (string, string, error)
 	// NotFound checks if the error is "command not found" error.
 	NotFound(err error) bool
 }
 
-type cmd struct{}
+type cmd struct {
+	maxOutputBytes int
+	// env is merged into the environment of every command
+	env map[string]string
+}
+
+// limitedBuffer keeps the first limit bytes written to it and counts the rest,
+// the writes never fail so the command still runs to completion
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.buf.Write(p)
+	}
+	keep := min(len(p), max(b.limit-b.buf.Len(), 0))
+	b.buf.Write(p[:keep])
+	b.truncated += len(p) - keep
+	return len(p), nil
+}
+
+// String returns the kept output followed by a marker when some of it was dropped
+func (b *limitedBuffer) String() string {
+	if b.truncated == 0 {
+		return b.buf.String()
+	}
+	return fmt.Sprintf("%s\n[truncated %d bytes]", b.buf.String(), b.truncated)
+}
+
+// ErrCommandNotFound is returned by RunCommand when the command binary can't be found or executed.
+type ErrCommandNotFound struct {
+	Command string
+	Err     error
+}
+
+func (e *ErrCommandNotFound) Error() string {
+	return fmt.Sprintf("command %q not found: %v", e.Command, e.Err)
+}
+
+func (e *ErrCommandNotFound) Unwrap() error {
+	return e.Err
+}
+
+// ErrCommandFailed is returned by RunCommand when the command ran and exited with a non-zero code.
+type ErrCommandFailed struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *ErrCommandFailed) Error() string {
+	msg := fmt.Sprintf("command %q failed with exit code %d", e.Command, e.ExitCode)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *ErrCommandFailed) Unwrap() error {
+	return e.Err
+}
+
+// wrapRunError converts an error returned by exec.Cmd.Run to ErrCommandNotFound or ErrCommandFailed
+func wrapRunError(command, stderr string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ErrCommandFailed{Command: command, ExitCode: exitErr.ExitCode(), Stderr: stderr, Err: err}
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return &ErrCommandNotFound{Command: command, Err: err}
+	}
+	return err
+}
 
 // formatCommandOutput formats command output for logging, making carriage returns visible
 func formatCommandOutput(output string) string {
@@ -53,9 +161,36 @@ func formatCommandOutput(output string) string {
 
 // RunCommand is the default implementation of the cmd.Interface.
 func (c *cmd) RunCommand(ctx context.Context, command string, args ...string) (string, string, error) {
+	return c.RunCommandWithEnv(ctx, nil, command, args...)
+}
+
+// commandEnv returns the environment of a command: the process environment followed by the
+// configured env and the per-call env, exec.Cmd uses the last value of a duplicate key.
+// nil is returned when there is nothing to merge, the command then inherits the process environment.
+func (c *cmd) commandEnv(env map[string]string) []string {
+	if len(c.env) == 0 && len(env) == 0 {
+		return nil
+	}
+	result := os.Environ()
+	for _, vars := range []map[string]string{c.env, env} {
+		keys := make([]string, 0, len(vars))
+		for key := range vars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result = append(result, key+"="+vars[key])
+		}
+	}
+	return result
+}
+
+// RunCommandWithEnv is the default implementation of the cmd.Interface.
+func (c *cmd) RunCommandWithEnv(ctx context.Context, env map[string]string, command string, args ...string) (string, string, error) {
 	log := logr.FromContextOrDiscard(ctx)
-	log.V(1).Info("RunCommand()", "command", command, "args", args)
-	var stdout, stderr bytes.Buffer
+	log.V(1).Info("RunCommand()", "command", command, "args", args, "env", env)
+	stdout := limitedBuffer{limit: c.maxOutputBytes}
+	stderr := limitedBuffer{limit: c.maxOutputBytes}
 
 	cmd := exec.CommandContext(ctx, command, args...)
 	// Ensure child process is killed when context is canceled
@@ -67,6 +202,7 @@ func (c *cmd) RunCommand(ctx context.Context, command string, args ...string) (s
 	}
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	cmd.Env = c.commandEnv(env)
 
 	err := cmd.Run()
 
@@ -84,12 +220,17 @@ func (c *cmd) RunCommand(ctx context.Context, command string, args ...string) (s
 	}
 
 	log.V(1).Info(logMessage)
-	return stdout.String(), stderr.String(), err
+	return stdout.String(), stderr.String(), wrapRunError(command, stderr.String(), err)
 }
 
 // NotFound is the default implementation of the cmd.Interface.
 func (c *cmd) NotFound(err error) bool {
-	if exitErr, ok := err.(*exec.ExitError); ok {
+	var notFoundErr *ErrCommandNotFound
+	if errors.As(err, &notFoundErr) {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
 		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() == 127 {
 			return true
 		}
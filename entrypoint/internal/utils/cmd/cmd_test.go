@@ -0,0 +1,129 @@
+/*
+ Copyright 2025, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cmd", func() {
+	var (
+		c   Interface
+		ctx context.Context
+	)
+
+	BeforeEach(func() {
+		c = New()
+		ctx = context.Background()
+	})
+
+	Context("RunCommand", func() {
+		It("should return stdout on success", func() {
+			stdout, _, err := c.RunCommand(ctx, "sh", "-c", "echo hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(Equal("hello\n"))
+		})
+
+		It("should return ErrCommandNotFound when the binary does not exist", func() {
+			_, _, err := c.RunCommand(ctx, "nonexistent-binary-for-cmd-test")
+			Expect(err).To(HaveOccurred())
+
+			var notFoundErr *ErrCommandNotFound
+			Expect(errors.As(err, &notFoundErr)).To(BeTrue())
+			Expect(notFoundErr.Command).To(Equal("nonexistent-binary-for-cmd-test"))
+			Expect(c.NotFound(err)).To(BeTrue())
+
+			var failedErr *ErrCommandFailed
+			Expect(errors.As(err, &failedErr)).To(BeFalse())
+		})
+
+		It("should return ErrCommandFailed with exit code and stderr when the command fails", func() {
+			_, stderr, err := c.RunCommand(ctx, "sh", "-c", "echo boom >&2; exit 3")
+			Expect(err).To(HaveOccurred())
+			Expect(stderr).To(Equal("boom\n"))
+
+			var failedErr *ErrCommandFailed
+			Expect(errors.As(err, &failedErr)).To(BeTrue())
+			Expect(failedErr.Command).To(Equal("sh"))
+			Expect(failedErr.ExitCode).To(Equal(3))
+			Expect(failedErr.Stderr).To(Equal("boom\n"))
+			Expect(err.Error()).To(Equal(`command "sh" failed with exit code 3: boom`))
+			Expect(c.NotFound(err)).To(BeFalse())
+
+			var notFoundErr *ErrCommandNotFound
+			Expect(errors.As(err, &notFoundErr)).To(BeFalse())
+		})
+
+		It("should report exit code 127 from a shell as not found", func() {
+			_, _, err := c.RunCommand(ctx, "sh", "-c", "nonexistent-binary-for-cmd-test")
+			Expect(err).To(HaveOccurred())
+			Expect(c.NotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("with an output limit", func() {
+		BeforeEach(func() {
+			c = NewWithOutputLimit(8)
+		})
+
+		It("should truncate the output over the limit with a marker", func() {
+			stdout, stderr, err := c.RunCommand(ctx, "sh", "-c", "printf 0123456789abcdef; printf 0123456789 >&2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(Equal("01234567\n[truncated 8 bytes]"))
+			Expect(stderr).To(Equal("01234567\n[truncated 2 bytes]"))
+		})
+
+		It("should return the whole output under the limit", func() {
+			stdout, stderr, err := c.RunCommand(ctx, "sh", "-c", "echo hello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(Equal("hello\n"))
+			Expect(stderr).To(BeEmpty())
+		})
+
+		It("should let the command complete when its output is truncated", func() {
+			_, _, err := c.RunCommand(ctx, "sh", "-c", "seq 1 100000; exit 3")
+
+			var failedErr *ErrCommandFailed
+			Expect(errors.As(err, &failedErr)).To(BeTrue())
+			Expect(failedErr.ExitCode).To(Equal(3))
+		})
+	})
+
+	Context("with a command environment", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv("CMD_TEST_INHERITED", "inherited")
+			c = NewWithEnv(0, map[string]string{"LC_ALL": "C", "MAKEFLAGS": "-j8"})
+		})
+
+		It("should pass the configured env along with the process environment", func() {
+			stdout, _, err := c.RunCommand(ctx, "sh", "-c", `echo "$LC_ALL $MAKEFLAGS $CMD_TEST_INHERITED"`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(Equal("C -j8 inherited\n"))
+		})
+
+		It("should let the per-call env take precedence", func() {
+			stdout, _, err := c.RunCommandWithEnv(ctx, map[string]string{"MAKEFLAGS": "-j1", "CMD_TEST_INHERITED": "overridden"},
+				"sh", "-c", `echo "$LC_ALL $MAKEFLAGS $CMD_TEST_INHERITED"`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout).To(Equal("C -j1 overridden\n"))
+		})
+	})
+})
@@ -146,6 +146,86 @@ func (_c *Interface_RunCommand_Call) RunAndReturn(run func(context.Context, stri
 	return _c
 }
 
+// RunCommandWithEnv provides a mock function with given fields: ctx, env, command, args
+func (_m *Interface) RunCommandWithEnv(ctx context.Context, env map[string]string, command string, args ...string) (string, string, error) {
+	_va := make([]interface{}, len(args))
+	for _i := range args {
+		_va[_i] = args[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, env, command)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RunCommandWithEnv")
+	}
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]string, string, ...string) (string, string, error)); ok {
+		return rf(ctx, env, command, args...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]string, string, ...string) string); ok {
+		r0 = rf(ctx, env, command, args...)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, map[string]string, string, ...string) string); ok {
+		r1 = rf(ctx, env, command, args...)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, map[string]string, string, ...string) error); ok {
+		r2 = rf(ctx, env, command, args...)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Interface_RunCommandWithEnv_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RunCommandWithEnv'
+type Interface_RunCommandWithEnv_Call struct {
+	*mock.Call
+}
+
+// RunCommandWithEnv is a helper method to define mock.On call
+//   - ctx context.Context
+//   - env map[string]string
+//   - command string
+//   - args ...string
+func (_e *Interface_Expecter) RunCommandWithEnv(ctx interface{}, env interface{}, command interface{}, args ...interface{}) *Interface_RunCommandWithEnv_Call {
+	return &Interface_RunCommandWithEnv_Call{Call: _e.mock.On("RunCommandWithEnv",
+		append([]interface{}{ctx, env, command}, args...)...)}
+}
+
+func (_c *Interface_RunCommandWithEnv_Call) Run(run func(ctx context.Context, env map[string]string, command string, args ...string)) *Interface_RunCommandWithEnv_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(context.Context), args[1].(map[string]string), args[2].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Interface_RunCommandWithEnv_Call) Return(_a0 string, _a1 string, _a2 error) *Interface_RunCommandWithEnv_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Interface_RunCommandWithEnv_Call) RunAndReturn(run func(context.Context, map[string]string, string, ...string) (string, string, error)) *Interface_RunCommandWithEnv_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewInterface creates a new instance of Interface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewInterface(t interface {
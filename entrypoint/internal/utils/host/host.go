@@ -18,11 +18,13 @@ package host
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/constants"
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd"
@@ -67,6 +69,11 @@ type Interface interface {
 	// GetRedHatVersionInfo parses RedHat version information from /host/etc/os-release
 	// and returns version details. Should only be called for RedHat-based distributions.
 	GetRedHatVersionInfo(ctx context.Context) (*RedhatVersionInfo, error)
+	// GetInstalledOfedVersion returns the OFED version reported by ofed_info -s,
+	// or an empty string if ofed_info is not installed.
+	GetInstalledOfedVersion(ctx context.Context) (string, error)
+	// GetFreeDiskSpace returns the bytes available to unprivileged users on the filesystem path is on.
+	GetFreeDiskSpace(ctx context.Context, path string) (uint64, error)
 }
 
 type host struct {
@@ -141,17 +148,17 @@ func (h *host) GetDebugInfo(ctx context.Context) (string, error) {
 	}
 
 	// Get kernel information
-	stdout, stderr, err := h.cmd.RunCommand(ctx, "uname", "-a")
+	stdout, _, err := h.cmd.RunCommand(ctx, "uname", "-a")
 	if err != nil {
-		fmt.Fprintf(&debugInfo, "[uname -a]: Error executing uname -a: %v (stderr: %s)\n", err, stderr)
+		fmt.Fprintf(&debugInfo, "[uname -a]: Error executing uname -a: %v\n", err)
 	} else {
 		fmt.Fprintf(&debugInfo, "[uname -a]: %s\n", stdout)
 	}
 
 	// Get memory information
-	stdout, stderr, err = h.cmd.RunCommand(ctx, "free", "-m")
+	stdout, _, err = h.cmd.RunCommand(ctx, "free", "-m")
 	if err != nil {
-		fmt.Fprintf(&debugInfo, "[free -m]: Error executing free -m: %v (stderr: %s)\n", err, stderr)
+		fmt.Fprintf(&debugInfo, "[free -m]: Error executing free -m: %v\n", err)
 	} else {
 		fmt.Fprintf(&debugInfo, "[free -m]: %s\n", stdout)
 	}
@@ -163,6 +170,8 @@ func (h *host) GetDebugInfo(ctx context.Context) (string, error) {
 type LoadedModule struct {
 	// Name of the kernel module.
 	Name string
+	// Size of the module in bytes.
+	Size int
 	// RefCount amount of refs to the module.
 	RefCount int
 	// UsedBy contains names of the modules that depends on this module.
@@ -172,9 +181,9 @@ type LoadedModule struct {
 // LsMod is the default implementation of the host.Interface.
 func (h *host) LsMod(ctx context.Context) (map[string]LoadedModule, error) {
 	// Execute lsmod command
-	stdout, stderr, err := h.cmd.RunCommand(ctx, "lsmod")
+	stdout, _, err := h.cmd.RunCommand(ctx, "lsmod")
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute lsmod command: %w, stderr: %s", err, stderr)
+		return nil, fmt.Errorf("failed to execute lsmod command: %w", err)
 	}
 
 	// Parse the output
@@ -201,6 +210,13 @@ func (h *host) LsMod(ctx context.Context) (map[string]LoadedModule, error) {
 		moduleName := fields[0]
 		refCountStr := fields[2]
 
+		// Parse module size
+		size, err := strconv.Atoi(fields[1])
+		if err != nil {
+			// If we can't parse the size, set it to 0
+			size = 0
+		}
+
 		// Parse reference count
 		refCount, err := strconv.Atoi(refCountStr)
 		if err != nil {
@@ -227,6 +243,7 @@ func (h *host) LsMod(ctx context.Context) (map[string]LoadedModule, error) {
 
 		modules[moduleName] = LoadedModule{
 			Name:     moduleName,
+			Size:     size,
 			RefCount: refCount,
 			UsedBy:   usedBy,
 		}
@@ -238,9 +255,9 @@ func (h *host) LsMod(ctx context.Context) (map[string]LoadedModule, error) {
 // RmMod is the default implementation of the host.Interface.
 func (h *host) RmMod(ctx context.Context, module string) error {
 	// Execute rmmod command to unload the kernel module
-	_, stderr, err := h.cmd.RunCommand(ctx, "rmmod", module)
+	_, _, err := h.cmd.RunCommand(ctx, "rmmod", module)
 	if err != nil {
-		return fmt.Errorf("failed to unload kernel module %s: %w, stderr: %s", module, err, stderr)
+		return fmt.Errorf("failed to unload kernel module %s: %w", module, err)
 	}
 	return nil
 }
@@ -350,3 +367,30 @@ func (h *host) GetKernelVersion(ctx context.Context) (string, error) {
 	// Trim whitespace and return
 	return strings.TrimSpace(stdout), nil
 }
+
+// ofedVersionRegex matches the version in the ofed_info -s output, e.g. MLNX_OFED_LINUX-24.10-1.1.4.0:
+var ofedVersionRegex = regexp.MustCompile(`\d+\.\d+[\w.-]*`)
+
+// GetInstalledOfedVersion is the default implementation of the host.Interface.
+func (h *host) GetInstalledOfedVersion(ctx context.Context) (string, error) {
+	stdout, _, err := h.cmd.RunCommand(ctx, "ofed_info", "-s")
+	if err != nil {
+		var notFoundErr *cmd.ErrCommandNotFound
+		if errors.As(err, &notFoundErr) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get installed OFED version: %w", err)
+	}
+
+	return ofedVersionRegex.FindString(strings.TrimSuffix(strings.TrimSpace(stdout), ":")), nil
+}
+
+// GetFreeDiskSpace is the default implementation of the host.Interface.
+func (h *host) GetFreeDiskSpace(_ context.Context, path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := h.os.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to get filesystem statistics of %s: %w", path, err)
+	}
+	// Bavail excludes the blocks reserved for root
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
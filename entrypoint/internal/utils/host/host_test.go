@@ -19,12 +19,17 @@ package host
 import (
 	"context"
 	"errors"
+	"os/exec"
+	"strings"
+	"syscall"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/Mellanox/doca-driver-build/entrypoint/internal/constants"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd"
 	cmd_mocks "github.com/Mellanox/doca-driver-build/entrypoint/internal/utils/cmd/mocks"
 	wrappers_mocks "github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers/mocks"
 )
@@ -98,7 +103,7 @@ Swap:          2048           0        2048`
 			debugInfo, err := h.GetDebugInfo(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(debugInfo).To(ContainSubstring("[os-release]: " + osReleaseContent))
-			Expect(debugInfo).To(ContainSubstring("[uname -a]: Error executing uname -a: assert.AnError general error for testing (stderr: command not found)"))
+			Expect(debugInfo).To(ContainSubstring("[uname -a]: Error executing uname -a: assert.AnError general error for testing"))
 			Expect(debugInfo).To(ContainSubstring("[free -m]: " + freeOutput))
 		})
 
@@ -116,7 +121,7 @@ ID=ubuntu`
 			Expect(err).ToNot(HaveOccurred())
 			Expect(debugInfo).To(ContainSubstring("[os-release]: " + osReleaseContent))
 			Expect(debugInfo).To(ContainSubstring("[uname -a]: " + unameOutput))
-			Expect(debugInfo).To(ContainSubstring("[free -m]: Error executing free -m: assert.AnError general error for testing (stderr: permission denied)"))
+			Expect(debugInfo).To(ContainSubstring("[free -m]: Error executing free -m: assert.AnError general error for testing"))
 		})
 
 		It("should handle all operations failing gracefully", func() {
@@ -127,8 +132,8 @@ ID=ubuntu`
 			debugInfo, err := h.GetDebugInfo(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(debugInfo).To(ContainSubstring("[os-release]: Error reading /etc/os-release: assert.AnError general error for testing"))
-			Expect(debugInfo).To(ContainSubstring("[uname -a]: Error executing uname -a: assert.AnError general error for testing (stderr: command not found)"))
-			Expect(debugInfo).To(ContainSubstring("[free -m]: Error executing free -m: assert.AnError general error for testing (stderr: permission denied)"))
+			Expect(debugInfo).To(ContainSubstring("[uname -a]: Error executing uname -a: assert.AnError general error for testing"))
+			Expect(debugInfo).To(ContainSubstring("[free -m]: Error executing free -m: assert.AnError general error for testing"))
 		})
 
 		It("should handle empty outputs from commands", func() {
@@ -231,6 +236,7 @@ nvmet_rdma             67890   1`
 				mlx5Core, exists := result["mlx5_core"]
 				Expect(exists).To(BeTrue())
 				Expect(mlx5Core.Name).To(Equal("mlx5_core"))
+				Expect(mlx5Core.Size).To(Equal(1234567))
 				Expect(mlx5Core.RefCount).To(Equal(2))
 				Expect(mlx5Core.UsedBy).To(Equal([]string{"mlx5_ib", "mlx5_netdev"}))
 
@@ -245,6 +251,7 @@ nvmet_rdma             67890   1`
 				nvidiaPeermem, exists := result["nvidia_peermem"]
 				Expect(exists).To(BeTrue())
 				Expect(nvidiaPeermem.Name).To(Equal("nvidia_peermem"))
+				Expect(nvidiaPeermem.Size).To(Equal(45678))
 				Expect(nvidiaPeermem.RefCount).To(Equal(0))
 				Expect(nvidiaPeermem.UsedBy).To(BeEmpty())
 
@@ -256,6 +263,18 @@ nvmet_rdma             67890   1`
 				Expect(ibCore.UsedBy).To(Equal([]string{"mlx5_ib", "ib_isert", "ib_srpt"}))
 			})
 
+			It("should set size to 0 when it can't be parsed", func() {
+				lsmodOutput := `Module                  Size  Used by
+mlx5_core             invalid  0`
+
+				cmdMock.EXPECT().RunCommand(ctx, "lsmod").Return(lsmodOutput, "", nil)
+
+				result, err := h.LsMod(ctx)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result["mlx5_core"].Size).To(BeZero())
+			})
+
 			It("should handle empty lsmod output", func() {
 				lsmodOutput := `Module                  Size  Used by`
 
@@ -346,7 +365,7 @@ invalid_ref_count       12345   invalid dependency`
 
 		Context("when lsmod command fails", func() {
 			It("should return error when command execution fails", func() {
-				expectedError := errors.New("command not found")
+				expectedError := &cmd.ErrCommandFailed{Command: "lsmod", ExitCode: 127, Stderr: "lsmod: command not found"}
 				cmdMock.EXPECT().RunCommand(ctx, "lsmod").Return("", "lsmod: command not found", expectedError)
 
 				result, err := h.LsMod(ctx)
@@ -358,14 +377,15 @@ invalid_ref_count       12345   invalid dependency`
 			})
 
 			It("should return error with stderr information", func() {
-				expectedError := errors.New("permission denied")
 				stderr := "lsmod: permission denied"
+				expectedError := &cmd.ErrCommandFailed{Command: "lsmod", ExitCode: 1, Stderr: stderr}
 				cmdMock.EXPECT().RunCommand(ctx, "lsmod").Return("", stderr, expectedError)
 
 				result, err := h.LsMod(ctx)
 
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("stderr: lsmod: permission denied"))
+				Expect(err).To(MatchError(expectedError))
+				// stderr is reported once, by the command error
+				Expect(strings.Count(err.Error(), stderr)).To(Equal(1))
 				Expect(result).To(BeNil())
 			})
 		})
@@ -453,8 +473,8 @@ test_module             12345   1 "module with spaces"`
 		Context("when rmmod command fails", func() {
 			It("should return error when module is not loaded", func() {
 				moduleName := "nonexistent_module"
-				expectedError := errors.New("module not found")
 				stderr := "rmmod: ERROR: Module nonexistent_module is not currently loaded"
+				expectedError := &cmd.ErrCommandFailed{Command: "rmmod", ExitCode: 1, Stderr: stderr}
 
 				cmdMock.EXPECT().RunCommand(ctx, "rmmod", moduleName).Return("", stderr, expectedError)
 
@@ -462,13 +482,13 @@ test_module             12345   1 "module with spaces"`
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to unload kernel module nonexistent_module"))
-				Expect(err.Error()).To(ContainSubstring("stderr: rmmod: ERROR: Module nonexistent_module is not currently loaded"))
+				Expect(err.Error()).To(ContainSubstring("rmmod: ERROR: Module nonexistent_module is not currently loaded"))
 			})
 
 			It("should return error when module is in use", func() {
 				moduleName := "module_in_use"
-				expectedError := errors.New("module in use")
 				stderr := "rmmod: ERROR: Module module_in_use is in use"
+				expectedError := &cmd.ErrCommandFailed{Command: "rmmod", ExitCode: 1, Stderr: stderr}
 
 				cmdMock.EXPECT().RunCommand(ctx, "rmmod", moduleName).Return("", stderr, expectedError)
 
@@ -476,13 +496,13 @@ test_module             12345   1 "module with spaces"`
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to unload kernel module module_in_use"))
-				Expect(err.Error()).To(ContainSubstring("stderr: rmmod: ERROR: Module module_in_use is in use"))
+				Expect(err.Error()).To(ContainSubstring("rmmod: ERROR: Module module_in_use is in use"))
 			})
 
 			It("should return error when permission is denied", func() {
 				moduleName := "privileged_module"
-				expectedError := errors.New("permission denied")
 				stderr := "rmmod: ERROR: could not remove module privileged_module: Operation not permitted"
+				expectedError := &cmd.ErrCommandFailed{Command: "rmmod", ExitCode: 1, Stderr: stderr}
 
 				cmdMock.EXPECT().RunCommand(ctx, "rmmod", moduleName).Return("", stderr, expectedError)
 
@@ -490,21 +510,20 @@ test_module             12345   1 "module with spaces"`
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to unload kernel module privileged_module"))
-				Expect(err.Error()).To(ContainSubstring("stderr: rmmod: ERROR: could not remove module privileged_module: Operation not permitted"))
+				Expect(err.Error()).To(ContainSubstring("rmmod: ERROR: could not remove module privileged_module: Operation not permitted"))
 			})
 
 			It("should return error when rmmod command is not found", func() {
 				moduleName := "test_module"
-				expectedError := errors.New("command not found")
-				stderr := "rmmod: command not found"
+				expectedError := &cmd.ErrCommandNotFound{Command: "rmmod", Err: exec.ErrNotFound}
 
-				cmdMock.EXPECT().RunCommand(ctx, "rmmod", moduleName).Return("", stderr, expectedError)
+				cmdMock.EXPECT().RunCommand(ctx, "rmmod", moduleName).Return("", "", expectedError)
 
 				err := h.RmMod(ctx, moduleName)
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to unload kernel module test_module"))
-				Expect(err.Error()).To(ContainSubstring("stderr: rmmod: command not found"))
+				Expect(err.Error()).To(ContainSubstring(`command "rmmod" not found`))
 			})
 
 			It("should return error with empty stderr", func() {
@@ -517,7 +536,7 @@ test_module             12345   1 "module with spaces"`
 
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to unload kernel module test_module"))
-				Expect(err.Error()).To(ContainSubstring("stderr: "))
+				Expect(err.Error()).To(ContainSubstring("unknown error"))
 			})
 		})
 
@@ -1011,4 +1030,59 @@ ID="rhel"`
 			Expect(versionInfo2).To(BeNil())
 		})
 	})
+
+	Context("GetInstalledOfedVersion", func() {
+		It("should parse the ofed_info -s output", func() {
+			cmdMock.EXPECT().RunCommand(ctx, "ofed_info", "-s").Return("MLNX_OFED_LINUX-24.10-1.1.4.0:\n", "", nil)
+
+			version, err := h.GetInstalledOfedVersion(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("24.10-1.1.4.0"))
+		})
+
+		It("should parse the ofed_info -s output of DOCA-OFED", func() {
+			cmdMock.EXPECT().RunCommand(ctx, "ofed_info", "-s").Return("OFED-internal-25.01-0.6.0:\n", "", nil)
+
+			version, err := h.GetInstalledOfedVersion(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("25.01-0.6.0"))
+		})
+
+		It("should return an empty version when ofed_info is not installed", func() {
+			cmdMock.EXPECT().RunCommand(ctx, "ofed_info", "-s").
+				Return("", "", &cmd.ErrCommandNotFound{Command: "ofed_info", Err: exec.ErrNotFound})
+
+			version, err := h.GetInstalledOfedVersion(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(BeEmpty())
+		})
+
+		It("should return error when ofed_info fails", func() {
+			cmdMock.EXPECT().RunCommand(ctx, "ofed_info", "-s").Return("", "", errors.New("exit status 1"))
+
+			_, err := h.GetInstalledOfedVersion(ctx)
+			Expect(err).To(MatchError(ContainSubstring("failed to get installed OFED version")))
+		})
+	})
+	Context("GetFreeDiskSpace", func() {
+		It("should return the blocks available to unprivileged users in bytes", func() {
+			osMock.EXPECT().Statfs("/mnt/drivers-inventory", mock.Anything).RunAndReturn(func(_ string, buf *syscall.Statfs_t) error {
+				buf.Bsize = 4096
+				buf.Bfree = 2000
+				buf.Bavail = 1000
+				return nil
+			})
+
+			free, err := h.GetFreeDiskSpace(ctx, "/mnt/drivers-inventory")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(free).To(Equal(uint64(4096000)))
+		})
+
+		It("should return error when statfs fails", func() {
+			osMock.EXPECT().Statfs("/mnt/drivers-inventory", mock.Anything).Return(syscall.ENOENT)
+
+			_, err := h.GetFreeDiskSpace(ctx, "/mnt/drivers-inventory")
+			Expect(err).To(MatchError(syscall.ENOENT))
+		})
+	})
 })
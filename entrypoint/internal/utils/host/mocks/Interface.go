@@ -78,6 +78,119 @@ func (_c *Interface_GetDebugInfo_Call) RunAndReturn(run func(context.Context) (s
 	return _c
 }
 
+// GetFreeDiskSpace provides a mock function with given fields: ctx, path
+func (_m *Interface) GetFreeDiskSpace(ctx context.Context, path string) (uint64, error) {
+	ret := _m.Called(ctx, path)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFreeDiskSpace")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (uint64, error)); ok {
+		return rf(ctx, path)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) uint64); ok {
+		r0 = rf(ctx, path)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, path)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Interface_GetFreeDiskSpace_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFreeDiskSpace'
+type Interface_GetFreeDiskSpace_Call struct {
+	*mock.Call
+}
+
+// GetFreeDiskSpace is a helper method to define mock.On call
+//   - ctx context.Context
+//   - path string
+func (_e *Interface_Expecter) GetFreeDiskSpace(ctx interface{}, path interface{}) *Interface_GetFreeDiskSpace_Call {
+	return &Interface_GetFreeDiskSpace_Call{Call: _e.mock.On("GetFreeDiskSpace", ctx, path)}
+}
+
+func (_c *Interface_GetFreeDiskSpace_Call) Run(run func(ctx context.Context, path string)) *Interface_GetFreeDiskSpace_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Interface_GetFreeDiskSpace_Call) Return(_a0 uint64, _a1 error) *Interface_GetFreeDiskSpace_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Interface_GetFreeDiskSpace_Call) RunAndReturn(run func(context.Context, string) (uint64, error)) *Interface_GetFreeDiskSpace_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetInstalledOfedVersion provides a mock function with given fields: ctx
+func (_m *Interface) GetInstalledOfedVersion(ctx context.Context) (string, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInstalledOfedVersion")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (string, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) string); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Interface_GetInstalledOfedVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetInstalledOfedVersion'
+type Interface_GetInstalledOfedVersion_Call struct {
+	*mock.Call
+}
+
+// GetInstalledOfedVersion is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Interface_Expecter) GetInstalledOfedVersion(ctx interface{}) *Interface_GetInstalledOfedVersion_Call {
+	return &Interface_GetInstalledOfedVersion_Call{Call: _e.mock.On("GetInstalledOfedVersion", ctx)}
+}
+
+func (_c *Interface_GetInstalledOfedVersion_Call) Run(run func(ctx context.Context)) *Interface_GetInstalledOfedVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Interface_GetInstalledOfedVersion_Call) Return(_a0 string, _a1 error) *Interface_GetInstalledOfedVersion_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Interface_GetInstalledOfedVersion_Call) RunAndReturn(run func(context.Context) (string, error)) *Interface_GetInstalledOfedVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetKernelVersion provides a mock function with given fields: ctx
 func (_m *Interface) GetKernelVersion(ctx context.Context) (string, error) {
 	ret := _m.Called(ctx)
@@ -0,0 +1,230 @@
+/*
+ Copyright 2025, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers"
+)
+
+// Types of the events, they match the Kubernetes event types
+const (
+	EventTypeNormal  = "Normal"
+	EventTypeWarning = "Warning"
+)
+
+// Reasons of the events
+const (
+	ReasonBuildSucceeded = "DriverBuildSucceeded"
+	ReasonBuildFailed    = "DriverBuildFailed"
+	ReasonLoaded         = "DriverLoaded"
+	ReasonLoadFailed     = "DriverLoadFailed"
+	ReasonUnloaded       = "DriverUnloaded"
+	ReasonUnloadFailed   = "DriverUnloadFailed"
+)
+
+const (
+	// eventSourceComponent is reported as the source of the events
+	eventSourceComponent = "doca-driver"
+
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	requestTimeout    = 10 * time.Second
+)
+
+// New initialize default implementation of the notifier.Interface. Events are posted only with
+// KubeEventsEnabled when running in a cluster, the returned notifier is a no-op otherwise.
+func New(cfg config.Config, os wrappers.OSWrapper) Interface {
+	if !cfg.KubeEventsEnabled {
+		return &noop{}
+	}
+	return &kubeNotifier{
+		os:       os,
+		nodeName: cfg.NodeName,
+	}
+}
+
+// Interface is the interface exposed by the notifier package.
+type Interface interface {
+	// Notify reports a state change of the driver, a failure to deliver it is only logged.
+	Notify(ctx context.Context, eventType, reason, message string)
+}
+
+type noop struct{}
+
+// Notify is the no-op implementation of the notifier.Interface.
+func (n *noop) Notify(context.Context, string, string, string) {}
+
+type kubeNotifier struct {
+	os       wrappers.OSWrapper
+	nodeName string
+
+	setupOnce sync.Once
+	client    *http.Client
+	apiURL    string
+	token     string
+	namespace string
+}
+
+// Notify is the default implementation of the notifier.Interface.
+func (k *kubeNotifier) Notify(ctx context.Context, eventType, reason, message string) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	k.setupOnce.Do(func() {
+		if err := k.setup(); err != nil {
+			log.Info("[WARN] Kubernetes events are disabled", "reason", err.Error())
+		}
+	})
+	if k.client == nil {
+		return
+	}
+
+	if err := k.postEvent(ctx, eventType, reason, message); err != nil {
+		log.Info("[WARN] Failed to post Kubernetes event", "reason", reason, "error", err)
+		// Non-fatal error, continue
+		return
+	}
+	log.V(1).Info("Posted Kubernetes event", "type", eventType, "reason", reason)
+}
+
+// setup reads the in-cluster config of the pod
+func (k *kubeNotifier) setup() error {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return fmt.Errorf("not running in a Kubernetes cluster")
+	}
+	if k.nodeName == "" {
+		return fmt.Errorf("NODE_NAME is not set")
+	}
+
+	token, err := k.os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
+	namespace, err := k.os.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return fmt.Errorf("failed to read service account namespace: %w", err)
+	}
+	caCert, err := k.os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return fmt.Errorf("failed to read service account CA certificate: %w", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return fmt.Errorf("no valid CA certificate found in %s/ca.crt", serviceAccountDir)
+	}
+
+	k.apiURL = "https://" + net.JoinHostPort(host, port)
+	k.token = strings.TrimSpace(string(token))
+	k.namespace = strings.TrimSpace(string(namespace))
+	k.client = &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12},
+		},
+	}
+	return nil
+}
+
+// event is the subset of the core/v1 Event posted to the API server
+type event struct {
+	APIVersion     string          `json:"apiVersion"`
+	Kind           string          `json:"kind"`
+	Metadata       eventMetadata   `json:"metadata"`
+	InvolvedObject objectReference `json:"involvedObject"`
+	Reason         string          `json:"reason"`
+	Message        string          `json:"message"`
+	Type           string          `json:"type"`
+	Source         eventSource     `json:"source"`
+	FirstTimestamp string          `json:"firstTimestamp"`
+	LastTimestamp  string          `json:"lastTimestamp"`
+	Count          int             `json:"count"`
+}
+
+type eventMetadata struct {
+	GenerateName string `json:"generateName"`
+	Namespace    string `json:"namespace"`
+}
+
+type objectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	// UID of a Node involved object is its name, as set by the kubelet
+	UID string `json:"uid"`
+}
+
+type eventSource struct {
+	Component string `json:"component"`
+	Host      string `json:"host"`
+}
+
+// postEvent creates an Event for the node in the namespace of the pod
+func (k *kubeNotifier) postEvent(ctx context.Context, eventType, reason, message string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	body, err := json.Marshal(event{
+		APIVersion: "v1",
+		Kind:       "Event",
+		Metadata: eventMetadata{
+			GenerateName: k.nodeName + ".",
+			Namespace:    k.namespace,
+		},
+		InvolvedObject: objectReference{APIVersion: "v1", Kind: "Node", Name: k.nodeName, UID: k.nodeName},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         eventSource{Component: eventSourceComponent, Host: k.nodeName},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/namespaces/%s/events", k.apiURL, k.namespace)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+k.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post event: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+ Copyright 2025, NVIDIA CORPORATION & AFFILIATES
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Mellanox/doca-driver-build/entrypoint/internal/config"
+	osMockPkg "github.com/Mellanox/doca-driver-build/entrypoint/internal/wrappers/mocks"
+)
+
+var _ = Describe("Notifier", func() {
+	var (
+		osMock *osMockPkg.OSWrapper
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		osMock = osMockPkg.NewOSWrapper(GinkgoT())
+		ctx = context.Background()
+	})
+
+	It("should return a no-op notifier when Kubernetes events are disabled", func() {
+		n := New(config.Config{NodeName: "node1"}, osMock)
+		Expect(n).To(BeAssignableToTypeOf(&noop{}))
+
+		// No service account files are read
+		n.Notify(ctx, EventTypeNormal, ReasonLoaded, "driver loaded")
+	})
+
+	It("should not post events when not running in a cluster", func() {
+		GinkgoT().Setenv("KUBERNETES_SERVICE_HOST", "")
+		GinkgoT().Setenv("KUBERNETES_SERVICE_PORT", "")
+
+		n := New(config.Config{KubeEventsEnabled: true, NodeName: "node1"}, osMock)
+		n.Notify(ctx, EventTypeNormal, ReasonLoaded, "driver loaded")
+		n.Notify(ctx, EventTypeNormal, ReasonUnloaded, "inbox driver restored")
+	})
+
+	Context("in a cluster", func() {
+		var (
+			server   *httptest.Server
+			received []event
+			status   int
+		)
+
+		BeforeEach(func() {
+			received = nil
+			status = http.StatusCreated
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.URL.Path).To(Equal("/api/v1/namespaces/nvidia-network-operator/events"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer test-token"))
+
+				var ev event
+				Expect(json.NewDecoder(r.Body).Decode(&ev)).To(Succeed())
+				received = append(received, ev)
+				w.WriteHeader(status)
+			}))
+			DeferCleanup(server.Close)
+
+			serverURL, err := url.Parse(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			host, port, err := net.SplitHostPort(serverURL.Host)
+			Expect(err).NotTo(HaveOccurred())
+			GinkgoT().Setenv("KUBERNETES_SERVICE_HOST", host)
+			GinkgoT().Setenv("KUBERNETES_SERVICE_PORT", port)
+
+			caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+			osMock.EXPECT().ReadFile(serviceAccountDir+"/token").Return([]byte("test-token\n"), nil).Once()
+			osMock.EXPECT().ReadFile(serviceAccountDir+"/namespace").Return([]byte("nvidia-network-operator"), nil).Once()
+			osMock.EXPECT().ReadFile(serviceAccountDir+"/ca.crt").Return(caCert, nil).Once()
+		})
+
+		It("should post an event for the node", func() {
+			n := New(config.Config{KubeEventsEnabled: true, NodeName: "node1"}, osMock)
+			n.Notify(ctx, EventTypeNormal, ReasonBuildSucceeded, "driver build succeeded")
+			n.Notify(ctx, EventTypeWarning, ReasonLoadFailed, "driver load failed: test")
+
+			Expect(received).To(HaveLen(2))
+			Expect(received[0].Type).To(Equal(EventTypeNormal))
+			Expect(received[0].Reason).To(Equal(ReasonBuildSucceeded))
+			Expect(received[0].Message).To(Equal("driver build succeeded"))
+			Expect(received[0].Metadata.Namespace).To(Equal("nvidia-network-operator"))
+			Expect(received[0].InvolvedObject).To(Equal(objectReference{APIVersion: "v1", Kind: "Node", Name: "node1", UID: "node1"}))
+			Expect(received[0].Source).To(Equal(eventSource{Component: eventSourceComponent, Host: "node1"}))
+			Expect(received[1].Type).To(Equal(EventTypeWarning))
+			Expect(received[1].Reason).To(Equal(ReasonLoadFailed))
+		})
+
+		It("should continue when the API server rejects the event", func() {
+			status = http.StatusForbidden
+
+			n := New(config.Config{KubeEventsEnabled: true, NodeName: "node1"}, osMock)
+			n.Notify(ctx, EventTypeWarning, ReasonBuildFailed, "driver build failed: test")
+
+			Expect(received).To(HaveLen(1))
+		})
+	})
+})
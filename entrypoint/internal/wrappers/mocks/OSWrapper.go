@@ -6,6 +6,8 @@ import (
 	fs "io/fs"
 	os "os"
 
+	syscall "syscall"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -127,6 +129,66 @@ func (_c *OSWrapper_MkdirAll_Call) RunAndReturn(run func(string, fs.FileMode) er
 	return _c
 }
 
+// OpenFile provides a mock function with given fields: name, flag, perm
+func (_m *OSWrapper) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	ret := _m.Called(name, flag, perm)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OpenFile")
+	}
+
+	var r0 *os.File
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, os.FileMode) (*os.File, error)); ok {
+		return rf(name, flag, perm)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, os.FileMode) *os.File); ok {
+		r0 = rf(name, flag, perm)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*os.File)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, os.FileMode) error); ok {
+		r1 = rf(name, flag, perm)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OSWrapper_OpenFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OpenFile'
+type OSWrapper_OpenFile_Call struct {
+	*mock.Call
+}
+
+// OpenFile is a helper method to define mock.On call
+//   - name string
+//   - flag int
+//   - perm os.FileMode
+func (_e *OSWrapper_Expecter) OpenFile(name interface{}, flag interface{}, perm interface{}) *OSWrapper_OpenFile_Call {
+	return &OSWrapper_OpenFile_Call{Call: _e.mock.On("OpenFile", name, flag, perm)}
+}
+
+func (_c *OSWrapper_OpenFile_Call) Run(run func(name string, flag int, perm os.FileMode)) *OSWrapper_OpenFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int), args[2].(os.FileMode))
+	})
+	return _c
+}
+
+func (_c *OSWrapper_OpenFile_Call) Return(_a0 *os.File, _a1 error) *OSWrapper_OpenFile_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *OSWrapper_OpenFile_Call) RunAndReturn(run func(string, int, os.FileMode) (*os.File, error)) *OSWrapper_OpenFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ReadDir provides a mock function with given fields: name
 func (_m *OSWrapper) ReadDir(name string) ([]fs.DirEntry, error) {
 	ret := _m.Called(name)
@@ -345,6 +407,53 @@ func (_c *OSWrapper_RemoveAll_Call) RunAndReturn(run func(string) error) *OSWrap
 	return _c
 }
 
+// Rename provides a mock function with given fields: oldpath, newpath
+func (_m *OSWrapper) Rename(oldpath string, newpath string) error {
+	ret := _m.Called(oldpath, newpath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rename")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(oldpath, newpath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OSWrapper_Rename_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Rename'
+type OSWrapper_Rename_Call struct {
+	*mock.Call
+}
+
+// Rename is a helper method to define mock.On call
+//   - oldpath string
+//   - newpath string
+func (_e *OSWrapper_Expecter) Rename(oldpath interface{}, newpath interface{}) *OSWrapper_Rename_Call {
+	return &OSWrapper_Rename_Call{Call: _e.mock.On("Rename", oldpath, newpath)}
+}
+
+func (_c *OSWrapper_Rename_Call) Run(run func(oldpath string, newpath string)) *OSWrapper_Rename_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *OSWrapper_Rename_Call) Return(_a0 error) *OSWrapper_Rename_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *OSWrapper_Rename_Call) RunAndReturn(run func(string, string) error) *OSWrapper_Rename_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Stat provides a mock function with given fields: name
 func (_m *OSWrapper) Stat(name string) (fs.FileInfo, error) {
 	ret := _m.Called(name)
@@ -403,6 +512,53 @@ func (_c *OSWrapper_Stat_Call) RunAndReturn(run func(string) (fs.FileInfo, error
 	return _c
 }
 
+// Statfs provides a mock function with given fields: path, buf
+func (_m *OSWrapper) Statfs(path string, buf *syscall.Statfs_t) error {
+	ret := _m.Called(path, buf)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Statfs")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *syscall.Statfs_t) error); ok {
+		r0 = rf(path, buf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OSWrapper_Statfs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Statfs'
+type OSWrapper_Statfs_Call struct {
+	*mock.Call
+}
+
+// Statfs is a helper method to define mock.On call
+//   - path string
+//   - buf *syscall.Statfs_t
+func (_e *OSWrapper_Expecter) Statfs(path interface{}, buf interface{}) *OSWrapper_Statfs_Call {
+	return &OSWrapper_Statfs_Call{Call: _e.mock.On("Statfs", path, buf)}
+}
+
+func (_c *OSWrapper_Statfs_Call) Run(run func(path string, buf *syscall.Statfs_t)) *OSWrapper_Statfs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(*syscall.Statfs_t))
+	})
+	return _c
+}
+
+func (_c *OSWrapper_Statfs_Call) Return(_a0 error) *OSWrapper_Statfs_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *OSWrapper_Statfs_Call) RunAndReturn(run func(string, *syscall.Statfs_t) error) *OSWrapper_Statfs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // WriteFile provides a mock function with given fields: name, data, perm
 func (_m *OSWrapper) WriteFile(name string, data []byte, perm fs.FileMode) error {
 	ret := _m.Called(name, data, perm)
@@ -18,6 +18,7 @@ package wrappers
 
 import (
 	"os"
+	"syscall"
 )
 
 // OSWrapper is a wrapper for some functions from std os package
@@ -37,6 +38,8 @@ type OSWrapper interface {
 	// Stat returns a [FileInfo] describing the named file.
 	// If there is an error, it will be of type [*PathError].
 	Stat(name string) (os.FileInfo, error)
+	// Statfs returns statistics about the filesystem path is on.
+	Statfs(path string, buf *syscall.Statfs_t) error
 	// WriteFile writes data to the named file, creating it if necessary.
 	// If the file does not exist, WriteFile creates it with permissions perm (before umask);
 	// otherwise WriteFile truncates it before writing, without changing permissions.
@@ -65,6 +68,17 @@ type OSWrapper interface {
 	// Readlink returns the destination of the named symbolic link.
 	// If there is an error, it will be of type *PathError.
 	Readlink(name string) (string, error)
+	// Rename renames (moves) oldpath to newpath.
+	// If newpath already exists and is not a directory, Rename replaces it.
+	// If there is an error, it will be of type *LinkError.
+	Rename(oldpath, newpath string) error
+	// OpenFile is the generalized open call; most users will use Open
+	// or Create instead. It opens the named file with specified flag
+	// (O_RDONLY etc.). If the file does not exist, and the O_CREATE flag
+	// is passed, it is created with mode perm (before umask).
+	// If successful, methods on the returned File can be used for I/O.
+	// If there is an error, it will be of type *PathError.
+	OpenFile(name string, flag int, perm os.FileMode) (*os.File, error)
 }
 
 // NewOS returns a new instance of OSWrapper interface implementation
@@ -98,6 +112,11 @@ func (o *osWrapper) Stat(name string) (os.FileInfo, error) {
 	return os.Stat(name)
 }
 
+// Statfs returns statistics about the filesystem path is on.
+func (o *osWrapper) Statfs(path string, buf *syscall.Statfs_t) error {
+	return syscall.Statfs(path, buf)
+}
+
 // WriteFile writes data to the named file, creating it if necessary.
 // If the file does not exist, WriteFile creates it with permissions perm (before umask);
 // otherwise WriteFile truncates it before writing, without changing permissions.
@@ -140,3 +159,20 @@ func (o *osWrapper) MkdirAll(path string, perm os.FileMode) error {
 func (o *osWrapper) Readlink(name string) (string, error) {
 	return os.Readlink(name)
 }
+
+// Rename renames (moves) oldpath to newpath.
+// If newpath already exists and is not a directory, Rename replaces it.
+// If there is an error, it will be of type *LinkError.
+func (o *osWrapper) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// OpenFile is the generalized open call; most users will use Open
+// or Create instead. It opens the named file with specified flag
+// (O_RDONLY etc.). If the file does not exist, and the O_CREATE flag
+// is passed, it is created with mode perm (before umask).
+// If successful, methods on the returned File can be used for I/O.
+// If there is an error, it will be of type *PathError.
+func (o *osWrapper) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(name, flag, perm)
+}